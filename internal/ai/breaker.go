@@ -0,0 +1,316 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a provider's circuit breaker
+type BreakerState string
+
+const (
+	// BreakerClosed lets requests through normally
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen rejects requests until the cooldown has passed
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a trial request through after the cooldown
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// Defaults used when the circuit breaker is not configured
+const (
+	defaultFailureThreshold = 3
+	defaultBreakerCooldown  = 2 * time.Minute
+	defaultHealthWindow     = 20
+)
+
+// HealthSample is the outcome of a single provider call
+type HealthSample struct {
+	At      time.Time     `json:"at"`
+	Latency time.Duration `json:"latency"`
+	Success bool          `json:"success"`
+}
+
+// providerHealth is the persisted breaker state of one provider
+type providerHealth struct {
+	State               BreakerState   `json:"state"`
+	ConsecutiveFailures int            `json:"consecutive_failures"`
+	OpenedAt            time.Time      `json:"opened_at,omitempty"`
+	Samples             []HealthSample `json:"samples"`
+
+	// trial is set while a half-open trial request is in flight. It is not
+	// persisted: a trial left unresolved by an earlier run is simply retried.
+	trial bool
+}
+
+// ProviderStats summarizes a provider's recent health
+type ProviderStats struct {
+	State               BreakerState
+	Requests            int // Calls in the rolling window
+	Failures            int
+	ErrorRate           float64 // 0.0-1.0
+	AvgLatency          time.Duration
+	P95Latency          time.Duration
+	ConsecutiveFailures int
+	RetryAt             time.Time // When an open breaker moves to half-open
+}
+
+// HealthTracker keeps per-provider circuit breakers and rolling statistics.
+// State is persisted between runs so a provider that keeps failing is not
+// retried by every new command.
+type HealthTracker struct {
+	mu        sync.Mutex
+	cfg       BreakerConfig
+	providers map[string]*providerHealth
+	now       func() time.Time
+}
+
+// NewHealthTracker creates a tracker, loading any state saved by earlier runs
+func NewHealthTracker(cfg BreakerConfig) *HealthTracker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultFailureThreshold
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = defaultBreakerCooldown
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultHealthWindow
+	}
+
+	h := &HealthTracker{
+		cfg:       cfg,
+		providers: make(map[string]*providerHealth),
+		now:       time.Now,
+	}
+
+	if cfg.StateFile != "" {
+		if data, err := os.ReadFile(cfg.StateFile); err == nil {
+			// A corrupt state file only loses history, start fresh
+			json.Unmarshal(data, &h.providers)
+		}
+	}
+
+	return h
+}
+
+// Allow reports whether a request may be sent to the provider. An open
+// breaker moves to half-open once its cooldown has passed and lets a single
+// trial through; other callers are rejected until the trial is resolved by
+// RecordSuccess, RecordFailure or Release.
+func (h *HealthTracker) Allow(name string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p := h.get(name)
+	switch p.State {
+	case BreakerClosed:
+		return true
+	case BreakerOpen:
+		if h.now().Sub(p.OpenedAt) < h.cfg.Cooldown {
+			return false
+		}
+		p.State = BreakerHalfOpen
+	}
+
+	if p.trial {
+		return false
+	}
+	p.trial = true
+	return true
+}
+
+// Release gives up a half-open trial without an outcome, e.g. when the
+// request was cancelled or never sent, so the next caller can make it
+func (h *HealthTracker) Release(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.get(name).trial = false
+}
+
+// RecordSuccess records a successful call, closing the breaker. The state is
+// only saved when the success changes what the next run would decide: the
+// breaker closes, a failure streak ends, or the window still holds failures
+// whose error rate it dilutes. Calls to a healthy provider write nothing.
+func (h *HealthTracker) RecordSuccess(name string, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p := h.get(name)
+	changed := p.State != BreakerClosed || p.ConsecutiveFailures > 0 || hasFailures(p.Samples)
+
+	h.addSample(p, latency, true)
+	p.trial = false
+	p.ConsecutiveFailures = 0
+	p.State = BreakerClosed
+	p.OpenedAt = time.Time{}
+
+	if changed {
+		h.save()
+	}
+}
+
+// RecordFailure records a failed call. The breaker opens after the configured
+// number of consecutive failures, or immediately if a half-open trial fails.
+func (h *HealthTracker) RecordFailure(name string, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p := h.get(name)
+	h.addSample(p, latency, false)
+	p.trial = false
+	p.ConsecutiveFailures++
+
+	if p.State == BreakerHalfOpen || p.ConsecutiveFailures >= h.cfg.FailureThreshold {
+		p.State = BreakerOpen
+		p.OpenedAt = h.now()
+	}
+
+	h.save()
+}
+
+// Stats returns the rolling statistics for a provider
+func (h *HealthTracker) Stats(name string) ProviderStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p := h.get(name)
+	stats := ProviderStats{
+		State:               p.State,
+		Requests:            len(p.Samples),
+		ConsecutiveFailures: p.ConsecutiveFailures,
+	}
+
+	if p.State == BreakerOpen {
+		stats.RetryAt = p.OpenedAt.Add(h.cfg.Cooldown)
+	}
+
+	var latencies []time.Duration
+	var total time.Duration
+	for _, s := range p.Samples {
+		if !s.Success {
+			stats.Failures++
+			continue
+		}
+		latencies = append(latencies, s.Latency)
+		total += s.Latency
+	}
+
+	if stats.Requests > 0 {
+		stats.ErrorRate = float64(stats.Failures) / float64(stats.Requests)
+	}
+
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		stats.AvgLatency = total / time.Duration(len(latencies))
+		stats.P95Latency = latencies[(len(latencies)*95-1)/100]
+	}
+
+	return stats
+}
+
+// Order returns the chain reordered by health. Providers with an open
+// breaker move to the back, then those failing more than half of their
+// recent calls; otherwise the configured order is kept.
+func (h *HealthTracker) Order(chain []string) []string {
+	rank := make(map[string]int, len(chain))
+	for _, name := range chain {
+		stats := h.Stats(name)
+		switch {
+		case stats.State == BreakerOpen:
+			rank[name] = 2
+		case stats.ErrorRate > 0.5:
+			rank[name] = 1
+		default:
+			rank[name] = 0
+		}
+	}
+
+	ordered := append([]string(nil), chain...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return rank[ordered[i]] < rank[ordered[j]]
+	})
+
+	return ordered
+}
+
+func (h *HealthTracker) get(name string) *providerHealth {
+	p, ok := h.providers[name]
+	if !ok {
+		p = &providerHealth{State: BreakerClosed}
+		h.providers[name] = p
+	}
+	if p.State == "" {
+		p.State = BreakerClosed
+	}
+	return p
+}
+
+func hasFailures(samples []HealthSample) bool {
+	for _, s := range samples {
+		if !s.Success {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *HealthTracker) addSample(p *providerHealth, latency time.Duration, success bool) {
+	p.Samples = append(p.Samples, HealthSample{At: h.now(), Latency: latency, Success: success})
+	if len(p.Samples) > h.cfg.Window {
+		p.Samples = p.Samples[len(p.Samples)-h.cfg.Window:]
+	}
+}
+
+// save persists the state; failures are ignored since the state is advisory.
+// Each save writes its own temporary file and renames it into place, so
+// processes saving at the same time never interleave their writes.
+func (h *HealthTracker) save() {
+	if h.cfg.StateFile == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(h.providers, "", "  ")
+	if err != nil {
+		return
+	}
+
+	dir := filepath.Dir(h.cfg.StateFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(h.cfg.StateFile)+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), h.cfg.StateFile)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// breakerOpenError is returned when every provider was skipped
+func breakerOpenError(skipped []string, h *HealthTracker) error {
+	var soonest time.Time
+	for _, name := range skipped {
+		retryAt := h.Stats(name).RetryAt
+		if soonest.IsZero() || retryAt.Before(soonest) {
+			soonest = retryAt
+		}
+	}
+
+	wait := time.Until(soonest).Round(time.Second)
+	return fmt.Errorf("circuit breaker open for %v after repeated failures, retry in %s (or run: anaphase config check)", skipped, wait)
+}
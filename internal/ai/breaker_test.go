@@ -0,0 +1,186 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealthTrackerTransitions(t *testing.T) {
+	now := time.Now()
+	h := NewHealthTracker(BreakerConfig{FailureThreshold: 2, Cooldown: time.Minute})
+	h.now = func() time.Time { return now }
+
+	h.RecordFailure("gemini", time.Second)
+	if !h.Allow("gemini") {
+		t.Fatal("Expected breaker to stay closed below the threshold")
+	}
+
+	h.RecordFailure("gemini", time.Second)
+	if h.Allow("gemini") {
+		t.Fatal("Expected breaker to open at the threshold")
+	}
+	if h.Stats("gemini").State != BreakerOpen {
+		t.Errorf("Expected open state, got %s", h.Stats("gemini").State)
+	}
+
+	// After the cooldown a single trial is let through
+	now = now.Add(time.Minute)
+	if !h.Allow("gemini") {
+		t.Fatal("Expected half-open trial after cooldown")
+	}
+	if h.Stats("gemini").State != BreakerHalfOpen {
+		t.Errorf("Expected half-open state, got %s", h.Stats("gemini").State)
+	}
+	if h.Allow("gemini") {
+		t.Fatal("Expected other callers to wait for the trial")
+	}
+
+	// A failed trial reopens immediately
+	h.RecordFailure("gemini", time.Second)
+	if h.Allow("gemini") {
+		t.Fatal("Expected failed trial to reopen the breaker")
+	}
+
+	now = now.Add(time.Minute)
+	h.Allow("gemini")
+	h.RecordSuccess("gemini", 200*time.Millisecond)
+
+	stats := h.Stats("gemini")
+	if stats.State != BreakerClosed {
+		t.Errorf("Expected successful trial to close the breaker, got %s", stats.State)
+	}
+	if stats.Requests != 4 || stats.Failures != 3 {
+		t.Errorf("Expected 4 calls with 3 failures, got %d and %d", stats.Requests, stats.Failures)
+	}
+	if stats.AvgLatency != 200*time.Millisecond {
+		t.Errorf("Expected average latency of successful calls, got %s", stats.AvgLatency)
+	}
+}
+
+func TestHealthTrackerSingleHalfOpenTrial(t *testing.T) {
+	now := time.Now()
+	h := NewHealthTracker(BreakerConfig{FailureThreshold: 1, Cooldown: time.Minute})
+	h.now = func() time.Time { return now }
+
+	h.RecordFailure("groq", time.Second)
+	now = now.Add(time.Minute)
+
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if h.Allow("groq") {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := allowed.Load(); got != 1 {
+		t.Fatalf("Expected exactly one trial through a half-open breaker, got %d", got)
+	}
+
+	// A released trial, e.g. a cancelled request, lets the next caller try
+	h.Release("groq")
+	if !h.Allow("groq") {
+		t.Fatal("Expected a new trial after the previous one was released")
+	}
+	if h.Allow("groq") {
+		t.Fatal("Expected the new trial to block other callers")
+	}
+
+	h.RecordSuccess("groq", time.Second)
+	if !h.Allow("groq") || !h.Allow("groq") {
+		t.Error("Expected a closed breaker to let every caller through")
+	}
+}
+
+func TestHealthTrackerPersistsState(t *testing.T) {
+	cfg := BreakerConfig{FailureThreshold: 1, StateFile: filepath.Join(t.TempDir(), "health.json")}
+
+	NewHealthTracker(cfg).RecordFailure("groq", time.Second)
+
+	if NewHealthTracker(cfg).Allow("groq") {
+		t.Error("Expected open breaker to survive a restart")
+	}
+}
+
+func TestHealthTrackerSavesOnlyChanges(t *testing.T) {
+	dir := t.TempDir()
+	cfg := BreakerConfig{FailureThreshold: 2, StateFile: filepath.Join(dir, "health.json")}
+	h := NewHealthTracker(cfg)
+
+	// Successes on a healthy provider change nothing worth saving
+	h.RecordSuccess("groq", time.Second)
+	if _, err := os.Stat(cfg.StateFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no state file after a plain success, got %v", err)
+	}
+
+	// A failure is saved even below the threshold, and so is the success
+	// that ends the streak
+	h.RecordFailure("groq", time.Second)
+	if got := NewHealthTracker(cfg).Stats("groq").ConsecutiveFailures; got != 1 {
+		t.Errorf("Expected the failure streak to be saved, got %d", got)
+	}
+	h.RecordSuccess("groq", time.Second)
+	if got := NewHealthTracker(cfg).Stats("groq"); got.ConsecutiveFailures != 0 || got.Requests != 3 {
+		t.Errorf("Expected the ended streak and all samples to be saved, got %+v", got)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected only the state file to be left behind, got %d entries", len(entries))
+	}
+}
+
+func TestHealthTrackerOrder(t *testing.T) {
+	h := NewHealthTracker(BreakerConfig{FailureThreshold: 1})
+	h.RecordFailure("gemini", time.Second)
+	h.RecordSuccess("openai", time.Second)
+
+	got := h.Order([]string{"gemini", "groq", "openai"})
+	want := []string{"groq", "openai", "gemini"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected order %v, got %v", want, got)
+	}
+}
+
+func TestOrchestratorSkipsOpenBreaker(t *testing.T) {
+	failing := &fakeProvider{name: "primary", err: fmt.Errorf("unavailable")}
+	working := &fakeProvider{name: "backup", content: "hello"}
+
+	orchestrator := newTestOrchestrator(t, failing, 0)
+	orchestrator.providers["backup"] = working
+	orchestrator.fallbackChain = []string{"backup"}
+	orchestrator.health = NewHealthTracker(BreakerConfig{FailureThreshold: 1})
+
+	for i := 0; i < 3; i++ {
+		resp, err := orchestrator.Generate(context.Background(), &GenerateRequest{UserPrompt: "Hi"})
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if resp.Provider != "backup" {
+			t.Errorf("Expected backup provider, got %q", resp.Provider)
+		}
+	}
+
+	if failing.calls != 1 {
+		t.Errorf("Expected the failing provider to be called once before its breaker opened, got %d", failing.calls)
+	}
+
+	// With only the broken provider left, fail fast with a clear error
+	delete(orchestrator.providers, "backup")
+	_, err := orchestrator.Generate(context.Background(), &GenerateRequest{UserPrompt: "Hi"})
+	if err == nil || !strings.Contains(err.Error(), "circuit breaker open") {
+		t.Errorf("Expected circuit breaker error, got %v", err)
+	}
+}
@@ -1,20 +1,38 @@
 package ai
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
-// Cache provides response caching functionality
+// Cache provides response caching functionality. Entries are stored as one
+// JSON file each, in a subdirectory per provider; a file's modification time
+// doubles as its last access time for LRU eviction.
+//
+// File names are <request hash>-<model hash>.json, where the request hash
+// covers the prompts, parameters, response schema, metadata and prompt
+// version and the model hash covers the provider and model. This lets the
+// opt-in any-provider mode find responses for the same request from other
+// providers with a glob.
 type Cache struct {
-	directory string
-	ttl       time.Duration
-	enabled   bool
+	directory   string
+	ttl         time.Duration
+	maxSize     int64 // Bytes, 0 means unlimited
+	enabled     bool
+	anyProvider bool // Serve responses cached by any provider or model
+
+	mu        sync.Mutex
+	pruneOnce sync.Once
 }
 
 // CachedEntry represents a cached response
@@ -26,24 +44,92 @@ type CachedEntry struct {
 	PromptHash string            `json:"prompt_hash"`
 }
 
-// NewCache creates a new cache instance
-func NewCache(directory string, ttl time.Duration, enabled bool) *Cache {
+// CacheEntryInfo summarizes a cache file without its full contents
+type CacheEntryInfo struct {
+	Namespace  string // Provider subdirectory, empty for entries from older versions
+	Hash       string
+	Size       int64
+	LastAccess time.Time
+	CachedAt   time.Time
+	ExpiresAt  time.Time
+	Provider   string
+	Model      string
+	Corrupt    bool // File could not be parsed
+}
+
+// Expired reports whether the entry is past its expiry time
+func (e CacheEntryInfo) Expired() bool {
+	return e.Corrupt || time.Now().After(e.ExpiresAt)
+}
+
+// CacheStats describes the cache as a whole
+type CacheStats struct {
+	Directory string
+	Entries   int
+	Expired   int
+	TotalSize int64
+	MaxSize   int64
+	Oldest    time.Time
+	Newest    time.Time
+}
+
+// NewCache creates a new cache instance. maxSize is in bytes, 0 disables
+// the size limit.
+func NewCache(directory string, ttl time.Duration, maxSize int64, enabled bool) *Cache {
 	return &Cache{
 		directory: directory,
 		ttl:       ttl,
+		maxSize:   maxSize,
 		enabled:   enabled,
 	}
 }
 
-// Get retrieves cached response if available and not expired
-func (c *Cache) Get(req *GenerateRequest) (*GenerateResponse, bool) {
+// NewCacheFromConfig creates a cache from its configuration block
+func NewCacheFromConfig(cfg CacheConfig) (*Cache, error) {
+	maxSize, err := ParseSize(cfg.MaxSize)
+	if err != nil {
+		return nil, fmt.Errorf("cache max_size: %w", err)
+	}
+
+	cache := NewCache(cfg.Directory, cfg.TTL, maxSize, cfg.Enabled)
+	cache.anyProvider = cfg.AnyProvider
+	return cache, nil
+}
+
+// Directory returns the directory holding cache files
+func (c *Cache) Directory() string {
+	return c.directory
+}
+
+// Get retrieves a response cached for this provider and model, if available
+// and not expired. In any-provider mode, a response to the same request from
+// another provider or model is returned when there is no exact match.
+func (c *Cache) Get(provider, model string, req *GenerateRequest) (*GenerateResponse, bool) {
 	if !c.enabled {
 		return nil, false
 	}
 
-	hash := c.hashRequest(req)
-	cacheFile := filepath.Join(c.directory, hash+".json")
+	requestHash := hashRequest(req)
+	if resp, ok := c.load(c.path(namespaceFor(provider), cacheFileName(requestHash, provider, model))); ok {
+		return resp, true
+	}
 
+	if !c.anyProvider {
+		return nil, false
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(c.directory, "*", requestHash+"-*.json"))
+	for _, match := range matches {
+		if resp, ok := c.load(match); ok {
+			return resp, true
+		}
+	}
+
+	return nil, false
+}
+
+// load reads a cache file, removing it if it has expired
+func (c *Cache) load(cacheFile string) (*GenerateResponse, bool) {
 	// Check if cache file exists
 	data, err := os.ReadFile(cacheFile)
 	if err != nil {
@@ -52,7 +138,7 @@ func (c *Cache) Get(req *GenerateRequest) (*GenerateResponse, bool) {
 
 	// Parse cached entry
 	var entry CachedEntry
-	if err := json.Unmarshal(data, &entry); err != nil {
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Response == nil {
 		return nil, false
 	}
 
@@ -62,25 +148,30 @@ func (c *Cache) Get(req *GenerateRequest) (*GenerateResponse, bool) {
 		return nil, false
 	}
 
+	// Record the access for LRU eviction
+	now := time.Now()
+	os.Chtimes(cacheFile, now, now)
+
 	// Mark as cache hit
 	entry.Response.CacheHit = true
 
 	return entry.Response, true
 }
 
-// Set stores response in cache
-func (c *Cache) Set(req *GenerateRequest, resp *GenerateResponse) error {
+// Set stores a response in the provider's namespace
+func (c *Cache) Set(provider, model string, req *GenerateRequest, resp *GenerateResponse) error {
 	if !c.enabled {
 		return nil
 	}
 
 	// Ensure cache directory exists
-	if err := os.MkdirAll(c.directory, 0755); err != nil {
+	namespace := namespaceFor(provider)
+	if err := os.MkdirAll(filepath.Join(c.directory, namespace), 0755); err != nil {
 		return fmt.Errorf("create cache directory: %w", err)
 	}
 
-	hash := c.hashRequest(req)
-	cacheFile := filepath.Join(c.directory, hash+".json")
+	hash := hashRequest(req)
+	cacheFile := c.path(namespace, cacheFileName(hash, provider, model))
 
 	entry := CachedEntry{
 		Request:    req,
@@ -99,25 +190,380 @@ func (c *Cache) Set(req *GenerateRequest, resp *GenerateResponse) error {
 		return fmt.Errorf("write cache file: %w", err)
 	}
 
+	// Keep the cache within its size limit
+	if _, _, err := c.Evict(); err != nil {
+		return fmt.Errorf("evict cache entries: %w", err)
+	}
+
 	return nil
 }
 
 // Clear removes all cached entries
 func (c *Cache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	return os.RemoveAll(c.directory)
 }
 
-// hashRequest creates a deterministic hash of the request
-func (c *Cache) hashRequest(req *GenerateRequest) string {
+// Entries lists all cache files, most recently used first
+func (c *Cache) Entries() ([]CacheEntryInfo, error) {
+	return c.scan(true)
+}
+
+// scan lists cache files, most recently used first. Without parse only the
+// location, size and last access time are filled in.
+func (c *Cache) scan(parse bool) ([]CacheEntryInfo, error) {
+	dirs, err := os.ReadDir(c.directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read cache directory: %w", err)
+	}
+
+	// Entries written before namespacing live at the top level
+	namespaces := []string{""}
+	for _, dir := range dirs {
+		if dir.IsDir() {
+			namespaces = append(namespaces, dir.Name())
+		}
+	}
+
+	var entries []CacheEntryInfo
+	for _, namespace := range namespaces {
+		files, err := os.ReadDir(filepath.Join(c.directory, namespace))
+		if err != nil {
+			continue // Removed concurrently
+		}
+
+		for _, file := range files {
+			if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+				continue
+			}
+
+			info, err := file.Info()
+			if err != nil {
+				continue // Removed concurrently
+			}
+
+			entry := CacheEntryInfo{
+				Namespace:  namespace,
+				Hash:       strings.TrimSuffix(file.Name(), ".json"),
+				Size:       info.Size(),
+				LastAccess: info.ModTime(),
+			}
+
+			if parse {
+				c.describe(&entry)
+			}
+
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastAccess.After(entries[j].LastAccess)
+	})
+
+	return entries, nil
+}
+
+// describe fills in the details stored inside a cache file
+func (c *Cache) describe(entry *CacheEntryInfo) {
+	cached, err := c.read(entry.Namespace, entry.Hash)
+	if err != nil {
+		entry.Corrupt = true
+		return
+	}
+
+	entry.CachedAt = cached.CachedAt
+	entry.ExpiresAt = cached.ExpiresAt
+	if cached.Response != nil {
+		entry.Provider = cached.Response.Provider
+		entry.Model = cached.Response.Model
+	}
+}
+
+// Stats summarizes the cache contents
+func (c *Cache) Stats() (CacheStats, error) {
+	stats := CacheStats{Directory: c.directory, MaxSize: c.maxSize}
+
+	entries, err := c.Entries()
+	if err != nil {
+		return stats, err
+	}
+
+	for _, entry := range entries {
+		stats.Entries++
+		stats.TotalSize += entry.Size
+		if entry.Expired() {
+			stats.Expired++
+		}
+		if entry.Corrupt {
+			continue
+		}
+		if stats.Oldest.IsZero() || entry.CachedAt.Before(stats.Oldest) {
+			stats.Oldest = entry.CachedAt
+		}
+		if entry.CachedAt.After(stats.Newest) {
+			stats.Newest = entry.CachedAt
+		}
+	}
+
+	return stats, nil
+}
+
+// Show returns a single entry by hash. A unique prefix of the hash is enough.
+func (c *Cache) Show(hash string) (*CachedEntry, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []CacheEntryInfo
+	for _, entry := range entries {
+		if entry.Hash == hash {
+			matches = []CacheEntryInfo{entry}
+			break
+		}
+		if strings.HasPrefix(entry.Hash, hash) {
+			matches = append(matches, entry)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no cache entry matches %q", hash)
+	case 1:
+		return c.read(matches[0].Namespace, matches[0].Hash)
+	default:
+		return nil, fmt.Errorf("hash prefix %q is ambiguous (%d entries)", hash, len(matches))
+	}
+}
+
+// Prune removes expired and unreadable entries
+func (c *Cache) Prune() (removed int, freed int64, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.Entries()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, entry := range entries {
+		if !entry.Expired() {
+			continue
+		}
+		if err := os.Remove(c.path(entry.Namespace, entry.Hash)); err != nil && !os.IsNotExist(err) {
+			return removed, freed, fmt.Errorf("remove %s: %w", entry.Hash, err)
+		}
+		removed++
+		freed += entry.Size
+	}
+
+	return removed, freed, nil
+}
+
+// Evict removes least recently used entries until the cache fits within its
+// size limit
+func (c *Cache) Evict() (removed int, freed int64, err error) {
+	if c.maxSize <= 0 {
+		return 0, 0, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.scan(false)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+
+	// Entries are sorted most recently used first, so evict from the back
+	for i := len(entries) - 1; i >= 0 && total > c.maxSize; i-- {
+		entry := entries[i]
+		if err := os.Remove(c.path(entry.Namespace, entry.Hash)); err != nil && !os.IsNotExist(err) {
+			return removed, freed, fmt.Errorf("remove %s: %w", entry.Hash, err)
+		}
+		total -= entry.Size
+		removed++
+		freed += entry.Size
+	}
+
+	return removed, freed, nil
+}
+
+// PruneInBackground prunes expired entries and enforces the size limit in a
+// goroutine. It runs at most once per cache instance; errors are passed to
+// onError, which may be nil.
+func (c *Cache) PruneInBackground(onError func(error)) {
+	if !c.enabled {
+		return
+	}
+
+	c.pruneOnce.Do(func() {
+		go func() {
+			if _, _, err := c.Prune(); err != nil && onError != nil {
+				onError(err)
+			}
+			if _, _, err := c.Evict(); err != nil && onError != nil {
+				onError(err)
+			}
+		}()
+	})
+}
+
+// read loads a cache entry by namespace and full hash
+func (c *Cache) read(namespace, hash string) (*CachedEntry, error) {
+	data, err := os.ReadFile(c.path(namespace, hash))
+	if err != nil {
+		return nil, fmt.Errorf("read cache entry: %w", err)
+	}
+
+	var entry CachedEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("parse cache entry: %w", err)
+	}
+
+	return &entry, nil
+}
+
+func (c *Cache) path(namespace, hash string) string {
+	return filepath.Join(c.directory, namespace, hash+".json")
+}
+
+// hashRequest creates a deterministic, provider-independent hash of the request
+func hashRequest(req *GenerateRequest) string {
 	// Combine all request parameters into a single string
-	combined := fmt.Sprintf("%s|%s|%.2f|%d|%.2f",
+	combined := fmt.Sprintf("%s|%s|%.2f|%d|%.2f|v%s",
 		req.SystemPrompt,
 		req.UserPrompt,
 		req.Temperature,
 		req.MaxTokens,
 		req.TopP,
+		req.PromptVersion,
 	)
+	if req.ResponseSchema != nil {
+		// Hash the whole schema, not just its name, so a changed schema
+		// does not return responses shaped by the old one
+		schema, _ := json.Marshal(req.ResponseSchema)
+		combined += "|schema:" + req.ResponseSchema.Name + ":" + string(schema)
+	}
+	for _, m := range req.Messages {
+		combined += fmt.Sprintf("|%s:%q", m.Role, m.Content)
+	}
+
+	// Metadata changes the meaning of a request, so include it in key order
+	keys := make([]string, 0, len(req.Metadata))
+	for key := range req.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		combined += "|" + key + "=" + req.Metadata[key]
+	}
 
 	hash := sha256.Sum256([]byte(combined))
 	return hex.EncodeToString(hash[:])
 }
+
+// cacheFileName combines the request hash with a short provider/model hash
+func cacheFileName(requestHash, provider, model string) string {
+	variant := sha256.Sum256([]byte(provider + "|" + model))
+	return requestHash + "-" + hex.EncodeToString(variant[:6])
+}
+
+// namespaceFor maps a provider name to a safe directory name
+func namespaceFor(provider string) string {
+	namespace := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		default:
+			return '_'
+		}
+	}, provider)
+
+	if namespace == "" || strings.Trim(namespace, ".") == "" {
+		return "_"
+	}
+	return namespace
+}
+
+// ParseSize parses a human-readable size such as "100MB" or "512KB" into
+// bytes. Units are powers of 1024; an empty string means no limit.
+func ParseSize(s string) (int64, error) {
+	orig := s
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"G", 1 << 30},
+		{"M", 1 << 20},
+		{"K", 1 << 10},
+		{"B", 1},
+	}
+
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(s, unit.suffix) {
+			multiplier = unit.multiplier
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 100MB)", orig)
+	}
+
+	return int64(value * float64(multiplier)), nil
+}
+
+// FormatSize renders a byte count in the units accepted by ParseSize
+func FormatSize(bytes int64) string {
+	switch {
+	case bytes >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(bytes)/(1<<30))
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(bytes)/(1<<10))
+	default:
+		return fmt.Sprintf("%dB", bytes)
+	}
+}
+
+type responseCheckKey struct{}
+
+// WithResponseCheck makes generations with ctx cache only the responses
+// check accepts. A rejected response is still returned, so the caller can
+// send it back for repair, but it is not replayed from the cache by the next
+// run; cached responses check rejects are not served either.
+func WithResponseCheck(ctx context.Context, check func(*GenerateResponse) error) context.Context {
+	return context.WithValue(ctx, responseCheckKey{}, check)
+}
+
+// cacheable reports whether resp passes the check set by WithResponseCheck
+func cacheable(ctx context.Context, resp *GenerateResponse) bool {
+	check, _ := ctx.Value(responseCheckKey{}).(func(*GenerateResponse) error)
+	return check == nil || check(resp) == nil
+}
@@ -0,0 +1,172 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		wantErr  bool
+	}{
+		{"", 0, false},
+		{"100MB", 100 << 20, false},
+		{"512kb", 512 << 10, false},
+		{"1.5GB", 3 << 29, false},
+		{"2048", 2048, false},
+		{"10 M", 10 << 20, false},
+		{"lots", 0, true},
+		{"-1MB", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseSize(%q) = %d, want %d", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCache(dir, time.Hour, 1<<20, true)
+
+	requests := []*GenerateRequest{{UserPrompt: "first"}, {UserPrompt: "second"}, {UserPrompt: "third"}}
+	for i, req := range requests {
+		if err := cache.Set("test", "model", req, &GenerateResponse{Content: "reply", Provider: "test"}); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		// Spread access times so LRU order is deterministic
+		at := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(cache.path("test", cacheFileName(hashRequest(req), "test", "model")), at, at)
+	}
+
+	// Reading the oldest entry makes it the most recently used
+	if _, hit := cache.Get("test", "model", requests[0]); !hit {
+		t.Fatal("Expected cache hit for first request")
+	}
+
+	entries, err := cache.Entries()
+	if err != nil {
+		t.Fatalf("Entries failed: %v", err)
+	}
+
+	// Shrink the limit so only two entries fit
+	cache.maxSize = entries[0].Size + entries[1].Size
+	removed, _, err := cache.Evict()
+	if err != nil {
+		t.Fatalf("Evict failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected 1 evicted entry, got %d", removed)
+	}
+
+	if _, hit := cache.Get("test", "model", requests[1]); hit {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	if _, hit := cache.Get("test", "model", requests[0]); !hit {
+		t.Error("Expected the recently read entry to survive eviction")
+	}
+}
+
+func TestCachePruneAndShow(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCache(dir, -time.Minute, 0, true) // Entries expire immediately
+
+	req := &GenerateRequest{UserPrompt: "expired"}
+	if err := cache.Set("test", "model", req, &GenerateResponse{Content: "reply"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	os.WriteFile(dir+"/corrupt.json", []byte("{"), 0644)
+
+	hash := hashRequest(req)
+	entry, err := cache.Show(hash[:8])
+	if err != nil {
+		t.Fatalf("Show by prefix failed: %v", err)
+	}
+	if entry.PromptHash != hash {
+		t.Errorf("Expected hash %s, got %s", hash, entry.PromptHash)
+	}
+
+	removed, _, err := cache.Prune()
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("Expected expired and corrupt entries to be pruned, removed %d", removed)
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Entries != 0 {
+		t.Errorf("Expected empty cache, got %d entries", stats.Entries)
+	}
+}
+
+func TestCacheKeysArePerProviderAndModel(t *testing.T) {
+	dir := t.TempDir()
+	req := &GenerateRequest{UserPrompt: "order", PromptVersion: "1"}
+
+	cache, err := NewCacheFromConfig(CacheConfig{Enabled: true, Directory: dir, TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("NewCacheFromConfig failed: %v", err)
+	}
+	if err := cache.Set("groq", "llama-3.3-70b-versatile", req, &GenerateResponse{Content: "from groq", Provider: "groq"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "groq")); err != nil {
+		t.Errorf("Expected a groq namespace directory: %v", err)
+	}
+
+	if _, hit := cache.Get("groq", "llama-3.3-70b-versatile", req); !hit {
+		t.Error("Expected hit for the same provider and model")
+	}
+	if _, hit := cache.Get("gemini", "gemini-2.0-flash-exp", req); hit {
+		t.Error("Expected miss for a different provider")
+	}
+	if _, hit := cache.Get("groq", "mixtral-8x7b-32768", req); hit {
+		t.Error("Expected miss for a different model")
+	}
+
+	changed := []*GenerateRequest{
+		{UserPrompt: "order", PromptVersion: "2"},
+		{UserPrompt: "order", PromptVersion: "1", Metadata: map[string]string{"task": "refine"}},
+	}
+
+	// A schema changed under the same name must not reuse old responses
+	strict := false
+	schemaReq := &GenerateRequest{UserPrompt: "order", PromptVersion: "1", ResponseSchema: SchemaFor(DomainSpec{}, "domain_spec")}
+	if err := cache.Set("groq", "llama-3.3-70b-versatile", schemaReq, &GenerateResponse{Content: "{}", Provider: "groq"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if _, hit := cache.Get("groq", "llama-3.3-70b-versatile", &GenerateRequest{UserPrompt: "order", PromptVersion: "1", ResponseSchema: SchemaFor(DomainSpec{}, "domain_spec")}); !hit {
+		t.Error("Expected hit for the same schema")
+	}
+	changedSchema := SchemaFor(DomainSpec{}, "domain_spec")
+	changedSchema.AdditionalProperties = &strict
+	changed = append(changed, &GenerateRequest{UserPrompt: "order", PromptVersion: "1", ResponseSchema: changedSchema})
+	for _, other := range changed {
+		if _, hit := cache.Get("groq", "llama-3.3-70b-versatile", other); hit {
+			t.Errorf("Expected miss for changed request %+v", other)
+		}
+	}
+
+	// Opting in to any-provider lookups allows cross-provider reuse
+	anyCache, _ := NewCacheFromConfig(CacheConfig{Enabled: true, Directory: dir, TTL: time.Hour, AnyProvider: true})
+	resp, hit := anyCache.Get("gemini", "gemini-2.0-flash-exp", req)
+	if !hit || resp.Provider != "groq" {
+		t.Errorf("Expected any-provider hit from groq, got %v %+v", hit, resp)
+	}
+}
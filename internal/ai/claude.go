@@ -0,0 +1,415 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// claudeAPIVersion is the Anthropic API version sent with every request
+const claudeAPIVersion = "2023-06-01"
+
+// claudeDefaultMaxTokens is used when the request does not set MaxTokens,
+// since the Messages API requires an explicit limit
+const claudeDefaultMaxTokens = 4096
+
+// ClaudeProvider implements the Provider interface for Anthropic Messages API
+type ClaudeProvider struct {
+	apiKey  string
+	model   string
+	timeout time.Duration
+	retries int
+	baseURL string
+	stream  *http.Client // Shared by streaming requests, keeping their connections pooled
+}
+
+// claudePricing holds per-model-family pricing in USD per 1M tokens (input, output)
+var claudePricing = map[string][2]float64{
+	"claude-3-haiku":    {0.25, 1.25},
+	"claude-3-5-haiku":  {0.80, 4.00},
+	"claude-3-5-sonnet": {3.00, 15.00},
+	"claude-3-7-sonnet": {3.00, 15.00},
+	"claude-3-opus":     {15.00, 75.00},
+	"claude-sonnet-4":   {3.00, 15.00},
+	"claude-opus-4":     {15.00, 75.00},
+}
+
+// NewClaudeProvider creates a new Claude provider
+func NewClaudeProvider(apiKey, model, baseURL string, timeout time.Duration, retries int) *ClaudeProvider {
+	if model == "" {
+		model = "claude-3-5-sonnet-20241022" // Default to Claude 3.5 Sonnet
+	}
+
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com/v1"
+	}
+
+	return &ClaudeProvider{
+		apiKey:  apiKey,
+		model:   model,
+		timeout: timeout,
+		retries: retries,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		stream:  streamingClient(timeout),
+	}
+}
+
+func (c *ClaudeProvider) Name() string {
+	return "claude"
+}
+
+func (c *ClaudeProvider) Model() string {
+	return c.model
+}
+
+// claudeRequest represents a request to the Messages API
+type claudeRequest struct {
+	Model       string          `json:"model"`
+	System      string          `json:"system,omitempty"`
+	Messages    []claudeMessage `json:"messages"`
+	MaxTokens   int             `json:"max_tokens"`
+	Temperature float64         `json:"temperature"`
+	TopP        float64         `json:"top_p,omitempty"`
+	Stream      bool            `json:"stream,omitempty"`
+}
+
+type claudeMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// claudeResponse represents a response from the Messages API
+type claudeResponse struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Role    string `json:"role"`
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason   string `json:"stop_reason"`
+	StopSequence string `json:"stop_sequence"`
+	Usage        struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+type claudeErrorResponse struct {
+	Type  string `json:"type"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *ClaudeProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	startTime := time.Now()
+	claudeReq := c.buildRequest(req)
+
+	// Retry logic
+	var lastErr error
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(retryBackoff(attempt)):
+			}
+		}
+
+		resp, err := c.makeRequest(ctx, claudeReq)
+		if err == nil {
+			return c.parseResponse(resp, startTime), nil
+		}
+
+		// Leave waiting out rate limits to the orchestrator
+		if isRateLimited(err) {
+			return nil, err
+		}
+
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("claude request failed after %d retries: %w", c.retries, lastErr)
+}
+
+// claudeStreamEvent is a single server-sent event from the Messages API
+type claudeStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Model string `json:"model"`
+		Usage struct {
+			InputTokens int `json:"input_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// GenerateStream streams the message as server-sent events
+func (c *ClaudeProvider) GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan StreamChunk, error) {
+	startTime := time.Now()
+
+	claudeReq := c.buildRequest(req)
+	claudeReq.Stream = true
+
+	headers := map[string]string{
+		"x-api-key":         c.apiKey,
+		"anthropic-version": claudeAPIVersion,
+	}
+	httpResp, err := openStream(ctx, "claude", c.baseURL+"/messages", headers, claudeReq, c.stream, c.retries)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamChunk)
+	go c.readStream(ctx, httpResp.Body, out, startTime)
+
+	return out, nil
+}
+
+func (c *ClaudeProvider) readStream(ctx context.Context, body io.ReadCloser, out chan<- StreamChunk, startTime time.Time) {
+	defer close(out)
+	defer body.Close()
+
+	final := &GenerateResponse{Provider: "claude", Model: c.model}
+	var content strings.Builder
+	var stopReason string
+
+	scanner := scanLines(body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		var event claudeStreamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &event); err != nil {
+			sendChunk(ctx, out, StreamChunk{Err: fmt.Errorf("unmarshal stream event: %w", err)})
+			return
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message.Model != "" {
+				final.Model = event.Message.Model
+			}
+			final.TokensUsed.PromptTokens = event.Message.Usage.InputTokens
+		case "content_block_delta":
+			if event.Delta.Type != "text_delta" || event.Delta.Text == "" {
+				continue
+			}
+			content.WriteString(event.Delta.Text)
+			if !sendChunk(ctx, out, StreamChunk{Content: event.Delta.Text}) {
+				return
+			}
+		case "message_delta":
+			if event.Delta.StopReason != "" {
+				stopReason = event.Delta.StopReason
+			}
+			final.TokensUsed.CompletionTokens = event.Usage.OutputTokens
+		case "error":
+			sendChunk(ctx, out, StreamChunk{Err: fmt.Errorf("claude stream error: %s", event.Error.Message)})
+			return
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		sendChunk(ctx, out, StreamChunk{Err: fmt.Errorf("read stream: %w", err)})
+		return
+	}
+
+	final.Content = content.String()
+	final.Duration = time.Since(startTime)
+	final.FinishReason = claudeFinishReason(stopReason)
+	final.TokensUsed.TotalTokens = final.TokensUsed.PromptTokens + final.TokensUsed.CompletionTokens
+	final.Cost = c.calculateCost(final.TokensUsed.PromptTokens, final.TokensUsed.CompletionTokens)
+
+	sendChunk(ctx, out, StreamChunk{Done: true, Response: final})
+}
+
+func (c *ClaudeProvider) buildRequest(req *GenerateRequest) claudeRequest {
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = claudeDefaultMaxTokens
+	}
+
+	// System prompt is a top-level field, not a message
+	system, turns := splitSystem(req.Conversation())
+	messages := make([]claudeMessage, 0, len(turns))
+	for _, m := range turns {
+		messages = append(messages, claudeMessage{Role: m.Role, Content: m.Content})
+	}
+
+	return claudeRequest{
+		Model:       c.model,
+		System:      system,
+		Messages:    messages,
+		MaxTokens:   maxTokens,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+	}
+}
+
+func (c *ClaudeProvider) makeRequest(ctx context.Context, req claudeRequest) (*claudeResponse, error) {
+	// Marshal request
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	// Create HTTP request
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	// Set headers
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", c.apiKey)
+	httpReq.Header.Set("anthropic-version", claudeAPIVersion)
+
+	// Make request with timeout
+	client := &http.Client{Timeout: c.timeout}
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("http request: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	// Read response body
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	// Check for errors
+	if httpResp.StatusCode != http.StatusOK {
+		var errResp claudeErrorResponse
+		if err := json.Unmarshal(respBody, &errResp); err == nil && errResp.Error.Message != "" {
+			return nil, apiError("claude", httpResp, errResp.Error.Message)
+		}
+		return nil, apiError("claude", httpResp, string(respBody))
+	}
+
+	// Parse response
+	var resp claudeResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("unmarshal response: %w", err)
+	}
+
+	return &resp, nil
+}
+
+func (c *ClaudeProvider) parseResponse(resp *claudeResponse, startTime time.Time) *GenerateResponse {
+	// Concatenate text blocks
+	var content strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+
+	model := resp.Model
+	if model == "" {
+		model = c.model
+	}
+
+	return &GenerateResponse{
+		Content:  content.String(),
+		Provider: "claude",
+		Model:    model,
+		Duration: time.Since(startTime),
+		TokensUsed: TokenUsage{
+			PromptTokens:     resp.Usage.InputTokens,
+			CompletionTokens: resp.Usage.OutputTokens,
+			TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
+		},
+		Cost:         c.calculateCost(resp.Usage.InputTokens, resp.Usage.OutputTokens),
+		FinishReason: claudeFinishReason(resp.StopReason),
+		CacheHit:     false,
+	}
+}
+
+// claudeFinishReason maps Anthropic stop reasons onto the OpenAI-style
+// values used by the other providers
+func claudeFinishReason(stopReason string) string {
+	switch stopReason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "refusal":
+		return "content_filter"
+	default:
+		return "unknown"
+	}
+}
+
+// calculateCost converts token counts into USD using the model family's price list
+func (c *ClaudeProvider) calculateCost(inputTokens, outputTokens int) float64 {
+	var price [2]float64
+	matched := ""
+	for family, p := range claudePricing {
+		if strings.HasPrefix(c.model, family) && len(family) > len(matched) {
+			matched, price = family, p
+		}
+	}
+	if matched == "" {
+		return 0.0
+	}
+
+	inputCost := float64(inputTokens) * price[0] / 1_000_000
+	outputCost := float64(outputTokens) * price[1] / 1_000_000
+	return inputCost + outputCost
+}
+
+func (c *ClaudeProvider) Validate() error {
+	if c.apiKey == "" {
+		return fmt.Errorf("claude API key is required")
+	}
+	return nil
+}
+
+func (c *ClaudeProvider) Health(ctx context.Context) error {
+	req := &GenerateRequest{
+		SystemPrompt: "You are a test assistant.",
+		UserPrompt:   "Respond with 'OK'",
+		Temperature:  0.0,
+		MaxTokens:    10,
+		Metadata:     map[string]string{MetadataTask: TaskHealth},
+	}
+
+	_, err := c.Generate(ctx, req)
+	return err
+}
+
+func (c *ClaudeProvider) EstimateCost(req *GenerateRequest) (float64, error) {
+	// Rough estimation: ~4 characters per token for input, max tokens for output
+	estimatedInputTokens := req.promptLength() / 4
+	estimatedOutputTokens := req.MaxTokens
+	if estimatedOutputTokens <= 0 {
+		estimatedOutputTokens = claudeDefaultMaxTokens
+	}
+
+	return c.calculateCost(estimatedInputTokens, estimatedOutputTokens), nil
+}
@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClaudeProviderGenerate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/messages" {
+			t.Errorf("Expected path /messages, got %s", r.URL.Path)
+		}
+		if got := r.Header.Get("x-api-key"); got != "test-key" {
+			t.Errorf("Expected x-api-key header, got %q", got)
+		}
+		if got := r.Header.Get("anthropic-version"); got != claudeAPIVersion {
+			t.Errorf("Expected anthropic-version %s, got %q", claudeAPIVersion, got)
+		}
+
+		var req claudeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if req.System != "You are a test assistant" {
+			t.Errorf("Expected separate system prompt, got %q", req.System)
+		}
+		if len(req.Messages) != 1 || req.Messages[0].Role != "user" {
+			t.Errorf("Expected a single user message, got %+v", req.Messages)
+		}
+		if req.MaxTokens != claudeDefaultMaxTokens {
+			t.Errorf("Expected default max tokens %d, got %d", claudeDefaultMaxTokens, req.MaxTokens)
+		}
+
+		w.Write([]byte(`{
+			"model": "claude-3-5-sonnet-20241022",
+			"content": [{"type": "text", "text": "Hello "}, {"type": "text", "text": "world"}],
+			"stop_reason": "max_tokens",
+			"usage": {"input_tokens": 100, "output_tokens": 50}
+		}`))
+	}))
+	defer server.Close()
+
+	provider := NewClaudeProvider("test-key", "", server.URL, 5*time.Second, 0)
+	resp, err := provider.Generate(context.Background(), &GenerateRequest{
+		SystemPrompt: "You are a test assistant",
+		UserPrompt:   "Hello",
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if resp.Content != "Hello world" {
+		t.Errorf("Expected content 'Hello world', got %q", resp.Content)
+	}
+	if resp.FinishReason != "length" {
+		t.Errorf("Expected finish reason 'length', got %q", resp.FinishReason)
+	}
+	if resp.TokensUsed.TotalTokens != 150 {
+		t.Errorf("Expected total tokens 150, got %d", resp.TokensUsed.TotalTokens)
+	}
+	if resp.Cost <= 0 {
+		t.Errorf("Expected positive cost, got %f", resp.Cost)
+	}
+}
+
+func TestClaudeBuildRequestConversation(t *testing.T) {
+	provider := NewClaudeProvider("test-key", "", "", time.Second, 0)
+	req := provider.buildRequest(&GenerateRequest{
+		SystemPrompt: "You are a test assistant",
+		UserPrompt:   "Hello",
+		Messages: []Message{
+			{Role: RoleSystem, Content: "Answer in one word"},
+			{Role: RoleAssistant, Content: "Hi there, how can I help?"},
+			{Role: RoleUser, Content: "Shorter"},
+		},
+	})
+
+	if req.System != "You are a test assistant\n\nAnswer in one word" {
+		t.Errorf("Expected every system message in the system prompt, got %q", req.System)
+	}
+	want := []claudeMessage{
+		{Role: "user", Content: "Hello"},
+		{Role: "assistant", Content: "Hi there, how can I help?"},
+		{Role: "user", Content: "Shorter"},
+	}
+	if !reflect.DeepEqual(req.Messages, want) {
+		t.Errorf("Expected messages %+v, got %+v", want, req.Messages)
+	}
+}
+
+func TestClaudeFinishReason(t *testing.T) {
+	tests := []struct {
+		stopReason string
+		want       string
+	}{
+		{"end_turn", "stop"},
+		{"stop_sequence", "stop"},
+		{"max_tokens", "length"},
+		{"tool_use", "tool_calls"},
+		{"refusal", "content_filter"},
+		{"something_new", "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.stopReason, func(t *testing.T) {
+			if got := claudeFinishReason(tt.stopReason); got != tt.want {
+				t.Errorf("claudeFinishReason(%q) = %q, want %q", tt.stopReason, got, tt.want)
+			}
+		})
+	}
+}
@@ -8,7 +8,15 @@ import (
 type Config struct {
 	AI        AIConfig    `yaml:"ai"`
 	Cache     CacheConfig `yaml:"cache"`
+	Usage     UsageConfig `yaml:"usage"`
 	Generator GenConfig   `yaml:"generator"`
+
+	// Profile names the entry of Profiles applied over AI, if any
+	Profile string `yaml:"profile"`
+
+	// Profiles are named AI setups, each holding only the settings it
+	// changes, e.g. a personal free tier and a company gateway
+	Profiles map[string]AIConfig `yaml:"profiles"`
 }
 
 // AIConfig holds AI provider configuration
@@ -16,6 +24,46 @@ type AIConfig struct {
 	PrimaryProvider   string          `yaml:"primary_provider"`
 	FallbackProviders []string        `yaml:"fallback_providers"`
 	Providers         ProvidersConfig `yaml:"providers"`
+
+	// Routing maps a task (see MetadataTask) to the providers tried for it,
+	// in order. An entry names a provider, optionally with a model, e.g.
+	// groq/llama-3.1-8b-instant. Tasks without a rule use the primary and
+	// fallback providers.
+	Routing map[string][]string `yaml:"routing"`
+
+	// OpenAICompatible declares any number of named OpenAI-compatible endpoints
+	OpenAICompatible []OpenAICompatibleConfig `yaml:"openai_compatible"`
+
+	// MaxRepairs bounds how often invalid output is sent back for fixing
+	MaxRepairs int `yaml:"max_repairs"`
+
+	// CircuitBreaker controls when failing providers are skipped
+	CircuitBreaker BreakerConfig `yaml:"circuit_breaker"`
+
+	// MaxRateLimitWait is the longest a request waits for a rate-limited
+	// provider; longer delays fall back to the next provider instead
+	MaxRateLimitWait time.Duration `yaml:"max_rate_limit_wait"`
+
+	// HedgeDelay races the next provider when the current one has not
+	// answered within this delay; zero disables hedging
+	HedgeDelay time.Duration `yaml:"hedge_delay"`
+
+	// Fixtures records responses and replays them without calling providers
+	Fixtures FixtureConfig `yaml:"fixtures"`
+}
+
+// FixtureConfig holds where recorded responses live and whether to record
+type FixtureConfig struct {
+	Directory string `yaml:"directory"`
+	Record    bool   `yaml:"record"` // Save every live response as a fixture
+}
+
+// BreakerConfig holds per-provider circuit breaker settings
+type BreakerConfig struct {
+	FailureThreshold int           `yaml:"failure_threshold"` // Consecutive failures before opening
+	Cooldown         time.Duration `yaml:"cooldown"`          // Time before a half-open trial
+	Window           int           `yaml:"window"`            // Calls kept for rolling statistics
+	StateFile        string        `yaml:"state_file"`        // Where state persists between runs
 }
 
 // ProvidersConfig holds individual provider configurations
@@ -35,6 +83,33 @@ type ProviderConfig struct {
 	Model      string        `yaml:"model"`
 	Timeout    time.Duration `yaml:"timeout"`
 	MaxRetries int           `yaml:"max_retries"`
+
+	// Client-side rate limits, zero means unlimited
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	TokensPerMinute   int `yaml:"tokens_per_minute"`
+}
+
+// OpenAICompatibleConfig holds configuration for a user-defined endpoint
+// that speaks the OpenAI chat completions API
+type OpenAICompatibleConfig struct {
+	Name       string            `yaml:"name"`
+	APIKey     string            `yaml:"api_key"`
+	BaseURL    string            `yaml:"base_url"`
+	Model      string            `yaml:"model"`
+	Headers    map[string]string `yaml:"headers"`
+	Timeout    time.Duration     `yaml:"timeout"`
+	MaxRetries int               `yaml:"max_retries"`
+
+	// Client-side rate limits, zero means unlimited
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	TokensPerMinute   int `yaml:"tokens_per_minute"`
+}
+
+// UsageConfig holds the usage ledger location and spending caps
+type UsageConfig struct {
+	File         string  `yaml:"file"`
+	DailyLimit   float64 `yaml:"daily_limit"`   // USD, 0 means no cap
+	MonthlyLimit float64 `yaml:"monthly_limit"` // USD, 0 means no cap
 }
 
 // CacheConfig holds cache configuration
@@ -43,6 +118,10 @@ type CacheConfig struct {
 	Directory string        `yaml:"directory"`
 	TTL       time.Duration `yaml:"ttl"`
 	MaxSize   string        `yaml:"max_size"`
+
+	// AnyProvider serves a response cached by any provider or model, rather
+	// than only one from the provider and model handling the request
+	AnyProvider bool `yaml:"any_provider"`
 }
 
 // GenConfig holds generator configuration
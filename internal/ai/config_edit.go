@@ -0,0 +1,399 @@
+package ai
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetConfigValue sets a dotted key in the config file at path, creating the
+// file and any mappings on the way. Only the lines of the changed entry are
+// rewritten, so comments, blank lines and the other keys stay as they were.
+func SetConfigValue(path, key string, value any) error {
+	if _, err := configKeyType(key); err != nil {
+		return err
+	}
+	if err := checkConfigValue(key, value); err != nil {
+		return err
+	}
+
+	data, doc, err := readConfigNode(path)
+	if err != nil {
+		return err
+	}
+
+	var valueNode yaml.Node
+	if err := valueNode.Encode(value); err != nil {
+		return fmt.Errorf("encode %s: %w", key, err)
+	}
+
+	parts := strings.Split(key, ".")
+	if lines, ok := setConfigLines(splitConfigLines(data), doc.Content[0], parts, &valueNode); ok {
+		return saveConfigFile(path, joinConfigLines(lines))
+	}
+
+	// Flow-style mappings can't be edited in place, re-encode the file
+	mapping := doc.Content[0]
+	for _, part := range parts[:len(parts)-1] {
+		child := mappingValue(mapping, part)
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			mapping.Content = append(mapping.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part}, child)
+		} else if child.Kind != yaml.MappingNode {
+			*child = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", LineComment: child.LineComment}
+		}
+		mapping = child
+	}
+
+	last := parts[len(parts)-1]
+	if existing := mappingValue(mapping, last); existing != nil {
+		valueNode.LineComment = existing.LineComment
+		*existing = valueNode
+	} else {
+		mapping.Content = append(mapping.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: last}, &valueNode)
+	}
+
+	return writeConfigNode(path, doc)
+}
+
+// UnsetConfigValue removes a dotted key from the config file at path, along
+// with mappings it leaves empty. It reports whether the key was there.
+func UnsetConfigValue(path, key string) (bool, error) {
+	if _, err := configKeyType(key); err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return false, nil
+	}
+
+	data, doc, err := readConfigNode(path)
+	if err != nil {
+		return false, err
+	}
+
+	parts := strings.Split(key, ".")
+	mappings, keys := configPath(doc.Content[0], parts)
+	if keys == nil {
+		return false, nil
+	}
+
+	for _, mapping := range mappings {
+		if mapping.Style&yaml.FlowStyle != 0 {
+			// Flow-style mappings can't be edited in place, re-encode the file
+			removeMappingKey(doc.Content[0], parts)
+			return true, writeConfigNode(path, doc)
+		}
+	}
+
+	// Remove the outermost entry that only holds the key
+	i := len(keys) - 1
+	for i > 0 && len(mappings[i].Content) == 2 {
+		i--
+	}
+
+	lines := splitConfigLines(data)
+	start, end := entrySpan(lines, keys[i], mappingValue(mappings[i], keys[i].Value))
+	lines = append(lines[:start], lines[end+1:]...)
+	return true, saveConfigFile(path, joinConfigLines(lines))
+}
+
+// setConfigLines sets the key at parts by replacing or inserting the lines
+// of a single entry. A scalar is replaced within its line, keeping any
+// trailing comment. It reports false when a flow-style mapping is in the way.
+func setConfigLines(lines []string, mapping *yaml.Node, parts []string, value *yaml.Node) ([]string, bool) {
+	for i, part := range parts {
+		if mapping.Style&yaml.FlowStyle != 0 {
+			return nil, false
+		}
+
+		rest := nestedConfigValue(parts[i+1:], value)
+		key, existing := mappingEntry(mapping, part)
+		if key == nil {
+			// Append a new entry after the mapping's last one
+			at, indent := len(lines), 0
+			if n := len(mapping.Content); n > 0 {
+				_, end := entrySpan(lines, mapping.Content[n-2], mapping.Content[n-1])
+				at, indent = end+1, mapping.Content[0].Column-1
+			}
+			entry, err := renderConfigEntry(part, rest, indent)
+			if err != nil {
+				return nil, false
+			}
+			return slices.Insert(lines, at, entry...), true
+		}
+
+		if i < len(parts)-1 && existing.Kind == yaml.MappingNode {
+			mapping = existing
+			continue
+		}
+
+		start, end := entrySpan(lines, key, existing)
+		if start == end {
+			if line, ok := replaceScalar(lines[start], key, existing, rest); ok {
+				lines[start] = line
+				return lines, true
+			}
+		}
+
+		rest.LineComment = existing.LineComment
+		entry, err := renderConfigEntry(part, rest, key.Column-1)
+		if err != nil {
+			return nil, false
+		}
+		return slices.Replace(lines, start, end+1, entry...), true
+	}
+	return nil, false
+}
+
+// replaceScalar swaps the scalar value on a "key: value" line for value,
+// keeping the text before it and any trailing comment
+func replaceScalar(line string, key, existing, value *yaml.Node) (string, bool) {
+	if existing.Kind != yaml.ScalarNode || value.Kind != yaml.ScalarNode ||
+		existing.Line != key.Line || isImplicitNull(existing) ||
+		existing.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return "", false
+	}
+
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+	text := strings.TrimSuffix(string(out), "\n")
+	if strings.Contains(text, "\n") {
+		return "", false
+	}
+
+	runes := []rune(line)
+	if existing.Column-1 > len(runes) {
+		return "", false
+	}
+	prefix := string(runes[:existing.Column-1])
+
+	comment := existing.LineComment
+	if comment == "" {
+		comment = key.LineComment
+	}
+	var suffix string
+	if comment != "" {
+		at := strings.LastIndex(line, comment)
+		if at < 0 {
+			return "", false
+		}
+		for at > 0 && (line[at-1] == ' ' || line[at-1] == '\t') {
+			at--
+		}
+		suffix = line[at:]
+	}
+
+	return prefix + text + suffix, true
+}
+
+// entrySpan returns the first and last line (0-based) of a mapping entry:
+// the key line, the lines of its value and any more deeply indented lines
+// after them. Blank lines and comments that follow at the key's indentation
+// belong to the next entry.
+func entrySpan(lines []string, key, value *yaml.Node) (int, int) {
+	start := key.Line - 1
+	end := max(start, lastNodeLine(value)-1)
+
+	indent := key.Column - 1
+	for i := end + 1; i < len(lines); i++ {
+		trimmed := strings.TrimLeft(lines[i], " \t")
+		if trimmed == "" {
+			continue
+		}
+		if len(lines[i])-len(trimmed) <= indent {
+			break
+		}
+		end = i
+	}
+	return start, min(end, len(lines)-1)
+}
+
+// lastNodeLine returns the last line (1-based) a node or its children start on
+func lastNodeLine(node *yaml.Node) int {
+	if isImplicitNull(node) {
+		// An empty value has no position of its own
+		return 0
+	}
+	line := node.Line
+	for _, child := range node.Content {
+		line = max(line, lastNodeLine(child))
+	}
+	return line
+}
+
+// isImplicitNull reports whether node is a value left empty, as in "key:"
+func isImplicitNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null" && node.Value == "" && node.Style == 0
+}
+
+// nestedConfigValue wraps value in one mapping per part, outermost first
+func nestedConfigValue(parts []string, value *yaml.Node) *yaml.Node {
+	for i := len(parts) - 1; i >= 0; i-- {
+		value = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: parts[i]}, value,
+		}}
+	}
+	return value
+}
+
+// renderConfigEntry encodes "key: value" as lines indented by indent spaces
+func renderConfigEntry(key string, value *yaml.Node, indent int) ([]string, error) {
+	entry := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value,
+	}}
+	data, err := encodeConfigNode(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := splitConfigLines(data)
+	pad := strings.Repeat(" ", indent)
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return lines, nil
+}
+
+// configPath returns the mappings leading to the key at parts and the key
+// node in each of them, or nil if the key is not set
+func configPath(root *yaml.Node, parts []string) (mappings, keys []*yaml.Node) {
+	mapping := root
+	for _, part := range parts {
+		if mapping.Kind != yaml.MappingNode {
+			return nil, nil
+		}
+		key, value := mappingEntry(mapping, part)
+		if key == nil {
+			return nil, nil
+		}
+		mappings = append(mappings, mapping)
+		keys = append(keys, key)
+		mapping = value
+	}
+	return mappings, keys
+}
+
+// removeMappingKey removes the key at parts below mapping, dropping mappings
+// that end up empty
+func removeMappingKey(mapping *yaml.Node, parts []string) bool {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != parts[0] {
+			continue
+		}
+		if len(parts) > 1 {
+			child := mapping.Content[i+1]
+			if child.Kind != yaml.MappingNode || !removeMappingKey(child, parts[1:]) {
+				return false
+			}
+			if len(child.Content) > 0 {
+				return true
+			}
+		}
+		mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+		return true
+	}
+	return false
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	_, value := mappingEntry(mapping, key)
+	return value
+}
+
+// mappingEntry returns the key and value nodes of key in a mapping node, or
+// nils
+func mappingEntry(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// readConfigNode reads a config file and parses it as a YAML document whose
+// root is a mapping; a missing or empty file is an empty mapping
+func readConfigNode(path string) ([]byte, *yaml.Node, error) {
+	empty := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, empty, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("read config: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("read config %s: %w", path, err)
+	}
+	if doc.Kind == 0 {
+		// Only comments or nothing at all; keep the comments
+		empty.HeadComment = doc.HeadComment
+		return data, empty, nil
+	}
+	if len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("read config %s: not a YAML mapping", path)
+	}
+	return data, &doc, nil
+}
+
+// splitConfigLines splits a file into lines without their line breaks
+func splitConfigLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// joinConfigLines is the inverse of splitConfigLines
+func joinConfigLines(lines []string) []byte {
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+func encodeConfigNode(node *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, fmt.Errorf("encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("encode config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func writeConfigNode(path string, doc *yaml.Node) error {
+	data, err := encodeConfigNode(doc)
+	if err != nil {
+		return err
+	}
+	return saveConfigFile(path, data)
+}
+
+func saveConfigFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetConfigValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, `# Team settings
+ai:
+  # Ask Groq first
+  primary_provider: groq # fastest
+generator:
+  go_version: "1.22"
+`)
+
+	if err := SetConfigValue(path, "ai.primary_provider", "openai"); err != nil {
+		t.Fatalf("SetConfigValue failed: %v", err)
+	}
+	if err := SetConfigValue(path, "ai.providers.openai.model", "gpt-4o"); err != nil {
+		t.Fatalf("SetConfigValue failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	content := string(data)
+	for _, want := range []string{
+		"# Team settings",
+		"# Ask Groq first",
+		"primary_provider: openai # fastest",
+		"providers:\n    openai:\n      model: gpt-4o",
+		`go_version: "1.22"`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Expected %q in:\n%s", want, content)
+		}
+	}
+}
+
+func TestSetConfigValueCreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "project", ProjectConfigFile)
+
+	if err := SetConfigValue(path, "ai.max_repairs", 0); err != nil {
+		t.Fatalf("SetConfigValue failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "ai:\n  max_repairs: 0\n" {
+		t.Errorf("Unexpected config file %q (%v)", data, err)
+	}
+}
+
+func TestSetConfigValueRejectsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	if err := SetConfigValue(path, "ai.primary_providers", "groq"); err == nil || !strings.Contains(err.Error(), "unknown config key") {
+		t.Errorf("Expected an unknown key error, got %v", err)
+	}
+	if err := SetConfigValue(path, "cache.ttl", "forever"); err == nil || !strings.Contains(err.Error(), "cache.ttl") {
+		t.Errorf("Expected an invalid value error, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected no file to be written for a rejected value")
+	}
+}
+
+func TestUnsetConfigValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, `ai:
+  primary_provider: groq
+  providers:
+    groq:
+      model: llama
+`)
+
+	removed, err := UnsetConfigValue(path, "ai.providers.groq.model")
+	if err != nil || !removed {
+		t.Fatalf("UnsetConfigValue = %v, %v; want true", removed, err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "ai:\n  primary_provider: groq\n" {
+		t.Errorf("Expected the empty mappings to be dropped, got:\n%s", data)
+	}
+
+	removed, err = UnsetConfigValue(path, "ai.providers.groq.model")
+	if err != nil || removed {
+		t.Errorf("UnsetConfigValue of a missing key = %v, %v; want false", removed, err)
+	}
+}
+
+func TestConfigEditKeepsLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, defaultConfigYAML)
+
+	edits := []struct {
+		key   string
+		value any
+	}{
+		{"ai.primary_provider", "groq"},
+		{"ai.providers.groq.enabled", true},
+		{"ai.fallback_providers", []string{"openai"}},
+		{"ai.providers.groq.requests_per_minute", 30},
+		{"usage.daily_limit", 5},
+	}
+	for _, edit := range edits {
+		if err := SetConfigValue(path, edit.key, edit.value); err != nil {
+			t.Fatalf("SetConfigValue(%s) failed: %v", edit.key, err)
+		}
+	}
+
+	data, _ := os.ReadFile(path)
+	want := defaultConfigYAML
+	for _, r := range []struct{ old, new string }{
+		{"  primary_provider: gemini\n", "  primary_provider: groq\n"},
+		{"    - groq\n    - openai\n", "    - openai\n"},
+		{"      enabled: false\n      api_key: ${GROQ_API_KEY}", "      enabled: true\n      api_key: ${GROQ_API_KEY}"},
+		{"      # Client-side rate limits", "      requests_per_minute: 30\n      # Client-side rate limits"},
+		{"  daily_limit: 0\n", "  daily_limit: 5\n"},
+	} {
+		if !strings.Contains(want, r.old) {
+			t.Fatalf("Default config no longer contains %q", r.old)
+		}
+		want = strings.Replace(want, r.old, r.new, 1)
+	}
+	if string(data) != want {
+		t.Errorf("Expected only the edited lines to change, got:\n%s", data)
+	}
+
+	// Unsetting what was set restores the other lines untouched
+	for _, key := range []string{"ai.providers.groq.requests_per_minute", "usage.daily_limit"} {
+		if removed, err := UnsetConfigValue(path, key); err != nil || !removed {
+			t.Fatalf("UnsetConfigValue(%s) = %v, %v; want true", key, removed, err)
+		}
+	}
+	data, _ = os.ReadFile(path)
+	want = strings.Replace(want, "      requests_per_minute: 30\n", "", 1)
+	want = strings.Replace(want, "  daily_limit: 5\n", "", 1)
+	if string(data) != want {
+		t.Errorf("Expected only the removed lines to change, got:\n%s", data)
+	}
+}
+
+func TestSetConfigValueLayoutCases(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		key     string
+		value   any
+		want    string
+	}{
+		{
+			name:    "comment spacing",
+			content: "ai:\n  max_repairs: 2    # retries\n\ncache:\n  enabled: true\n",
+			key:     "ai.max_repairs",
+			value:   0,
+			want:    "ai:\n  max_repairs: 0    # retries\n\ncache:\n  enabled: true\n",
+		},
+		{
+			name:    "empty value",
+			content: "ai:\n  routing:\n\ncache:\n  enabled: true\n",
+			key:     "ai.routing.domain",
+			value:   []string{"groq"},
+			want:    "ai:\n  routing:\n    domain:\n      - groq\n\ncache:\n  enabled: true\n",
+		},
+		{
+			name:    "new top-level key",
+			content: "# Settings\nai:\n  max_repairs: 2\n\n# end\n",
+			key:     "cache.enabled",
+			value:   false,
+			want:    "# Settings\nai:\n  max_repairs: 2\ncache:\n  enabled: false\n\n# end\n",
+		},
+		{
+			name:    "flow mapping",
+			content: "ai: {max_repairs: 2}\n",
+			key:     "ai.max_repairs",
+			value:   1,
+			want:    "ai: {max_repairs: 1}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			writeConfigFile(t, path, tt.content)
+
+			if err := SetConfigValue(path, tt.key, tt.value); err != nil {
+				t.Fatalf("SetConfigValue failed: %v", err)
+			}
+			data, _ := os.ReadFile(path)
+			if string(data) != tt.want {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.want, data)
+			}
+		})
+	}
+}
@@ -3,69 +3,74 @@ package ai
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 )
 
 // DomainSpec represents the parsed domain specification from AI
 type DomainSpec struct {
-	DomainName          string            `json:"domain_name"`
-	Entities            []EntitySpec      `json:"entities"`
-	ValueObjects        []ValueObjectSpec `json:"value_objects"`
-	RepositoryInterface RepositorySpec    `json:"repository_interface"`
-	ServiceInterface    ServiceSpec       `json:"service_interface"`
+	DomainName          string            `json:"domain_name" yaml:"domain_name"`
+	Entities            []EntitySpec      `json:"entities" yaml:"entities"`
+	ValueObjects        []ValueObjectSpec `json:"value_objects,omitempty" yaml:"value_objects,omitempty"`
+	RepositoryInterface RepositorySpec    `json:"repository_interface" yaml:"repository_interface"`
+	ServiceInterface    ServiceSpec       `json:"service_interface" yaml:"service_interface"`
 }
 
 // EntitySpec represents an entity specification
 type EntitySpec struct {
-	Name            string       `json:"name"`
-	IsAggregateRoot bool         `json:"is_aggregate_root"`
-	Fields          []FieldSpec  `json:"fields"`
-	Methods         []MethodSpec `json:"methods"`
+	Name            string       `json:"name" yaml:"name"`
+	IsAggregateRoot bool         `json:"is_aggregate_root,omitempty" yaml:"is_aggregate_root,omitempty"`
+	Fields          []FieldSpec  `json:"fields" yaml:"fields"`
+	Methods         []MethodSpec `json:"methods,omitempty" yaml:"methods,omitempty"`
 }
 
 // FieldSpec represents a field specification
 type FieldSpec struct {
-	Name        string `json:"name"`
-	Type        string `json:"type"`
-	Description string `json:"description"`
-	Validation  string `json:"validation"`
+	Name        string `json:"name" yaml:"name"`
+	Type        string `json:"type" yaml:"type"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Validation  string `json:"validation,omitempty" yaml:"validation,omitempty"`
 }
 
 // MethodSpec represents a method specification
 type MethodSpec struct {
-	Name           string `json:"name"`
-	Description    string `json:"description"`
-	Signature      string `json:"signature"`
-	Implementation string `json:"implementation"`
+	Name           string `json:"name" yaml:"name"`
+	Description    string `json:"description,omitempty" yaml:"description,omitempty"`
+	Signature      string `json:"signature" yaml:"signature"`
+	Implementation string `json:"implementation,omitempty" yaml:"implementation,omitempty"`
 }
 
 // ValueObjectSpec represents a value object specification
 type ValueObjectSpec struct {
-	Name       string      `json:"name"`
-	Fields     []FieldSpec `json:"fields"`
-	Validation string      `json:"validation"`
+	Name       string      `json:"name" yaml:"name"`
+	Fields     []FieldSpec `json:"fields" yaml:"fields"`
+	Validation string      `json:"validation,omitempty" yaml:"validation,omitempty"`
 }
 
 // RepositorySpec represents a repository interface specification
 type RepositorySpec struct {
-	Name    string            `json:"name"`
-	Methods []InterfaceMethod `json:"methods"`
+	Name    string            `json:"name" yaml:"name"`
+	Methods []InterfaceMethod `json:"methods" yaml:"methods"`
 }
 
 // ServiceSpec represents a service interface specification
 type ServiceSpec struct {
-	Name    string            `json:"name"`
-	Methods []InterfaceMethod `json:"methods"`
+	Name    string            `json:"name" yaml:"name"`
+	Methods []InterfaceMethod `json:"methods" yaml:"methods"`
 }
 
 // InterfaceMethod represents a method in an interface
 type InterfaceMethod struct {
-	Name        string `json:"name"`
-	Signature   string `json:"signature"`
-	Description string `json:"description"`
+	Name        string `json:"name" yaml:"name"`
+	Signature   string `json:"signature" yaml:"signature"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
 }
 
+// DomainSpecSchema is the JSON schema of DomainSpec, derived from its json
+// tags. Fields tagged omitempty are optional.
+var DomainSpecSchema = SchemaFor(DomainSpec{}, "domain_spec")
+
 // ParseDomainSpec parses the AI response into a DomainSpec
 func ParseDomainSpec(content string) (*DomainSpec, error) {
 	// Clean up the content - remove markdown code blocks if present
@@ -75,10 +80,19 @@ func ParseDomainSpec(content string) (*DomainSpec, error) {
 	content = strings.TrimSuffix(content, "```")
 	content = strings.TrimSpace(content)
 
+	// Validate against the schema first so every problem is reported by path
+	if err := DomainSpecSchema.ValidateJSON([]byte(content)); err != nil {
+		var schemaErrs SchemaErrors
+		if errors.As(err, &schemaErrs) {
+			return nil, schemaErrs
+		}
+		return nil, err
+	}
+
 	// Parse JSON
 	var spec DomainSpec
 	if err := json.Unmarshal([]byte(content), &spec); err != nil {
-		return nil, fmt.Errorf("parse JSON: %w\nContent:\n%s", err, content)
+		return nil, fmt.Errorf("parse JSON: %w", err)
 	}
 
 	// Validate required fields
@@ -93,28 +107,54 @@ func ParseDomainSpec(content string) (*DomainSpec, error) {
 	return &spec, nil
 }
 
-// GenerateDomain generates domain code using AI
+// GenerateDomain generates domain code using AI. Output that fails to parse
+// is sent back for repair, see GenerateDomainWithRepair.
 func GenerateDomain(ctx context.Context, orchestrator *Orchestrator, description string) (*DomainSpec, error) {
-	// Create request
-	req := &GenerateRequest{
-		SystemPrompt: SystemPromptDDD,
-		UserPrompt:   UserPromptTemplate(description),
-		Temperature:  0.3,  // Lower temperature for more consistent output
-		MaxTokens:    8000, // Increased for complex domain specs
-		TopP:         0.9,
-	}
+	spec, _, err := GenerateDomainWithRepair(ctx, orchestrator, description)
+	return spec, err
+}
 
-	// Generate
-	resp, err := orchestrator.Generate(ctx, req)
+// StreamDomain streams the raw domain spec as it is generated. The final
+// chunk's Response should be passed to RepairDomainSpec.
+func StreamDomain(ctx context.Context, orchestrator *Orchestrator, description string) (<-chan StreamChunk, error) {
+	req, err := domainRequest(ctx, orchestrator.prompts, description)
 	if err != nil {
-		return nil, fmt.Errorf("generate: %w", err)
+		return nil, err
 	}
+	return orchestrator.GenerateStream(withDomainSpecCheck(ctx), req)
+}
 
-	// Parse response
-	spec, err := ParseDomainSpec(resp.Content)
-	if err != nil {
-		return nil, fmt.Errorf("parse spec: %w", err)
+// domainRequest builds the generation request for a domain description from
+// the domain prompts, including the project context attached to ctx. The
+// prompt versions become part of the cache key.
+func domainRequest(ctx context.Context, prompts *PromptLoader, description string) (*GenerateRequest, error) {
+	data := DomainPromptData{
+		Description:    description,
+		ProjectContext: projectContextFrom(ctx),
+	}
+
+	var rendered [2]string
+	var versions []string
+	for i, name := range []string{PromptDomainSystem, PromptDomainUser} {
+		prompt, err := prompts.Load(name)
+		if err != nil {
+			return nil, err
+		}
+		if rendered[i], err = prompt.Render(data); err != nil {
+			return nil, err
+		}
+		versions = append(versions, prompt.CacheVersion())
 	}
 
-	return spec, nil
+	return &GenerateRequest{
+		SystemPrompt:  rendered[0],
+		UserPrompt:    rendered[1],
+		PromptVersion: strings.Join(versions, ","),
+		Temperature:   0.3,  // Lower temperature for more consistent output
+		MaxTokens:     8000, // Increased for complex domain specs
+		TopP:          0.9,
+		Metadata:      map[string]string{MetadataTask: TaskDomain},
+		// Constrain output on providers that support structured output
+		ResponseSchema: DomainSpecSchema,
+	}, nil
 }
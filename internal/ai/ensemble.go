@@ -0,0 +1,306 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// EnsembleResult is the domain spec one provider produced for an ensemble
+type EnsembleResult struct {
+	Provider string
+	Spec     *DomainSpec
+	Attempts []RepairAttempt
+	Err      error
+}
+
+// GenerateEnsemble asks every named provider for a domain spec in parallel.
+// Each provider repairs its own output; results keep the order of providers.
+// An error is returned only if no provider produced a spec.
+func GenerateEnsemble(ctx context.Context, orchestrator *Orchestrator, description string, providers []string) ([]EnsembleResult, error) {
+	results := make([]EnsembleResult, len(providers))
+
+	var wg sync.WaitGroup
+	for i, name := range providers {
+		results[i].Provider = name
+
+		single, err := orchestrator.WithProvider(name)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *EnsembleResult) {
+			defer wg.Done()
+			r.Spec, r.Attempts, r.Err = GenerateDomainWithRepair(ctx, single, description)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	var errs []string
+	for _, r := range results {
+		if r.Err == nil {
+			return results, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", r.Provider, r.Err))
+	}
+
+	return results, fmt.Errorf("no provider produced a domain spec:\n  %s", strings.Join(errs, "\n  "))
+}
+
+// MajorityVotes is the number of votes an element needs to win a strict
+// majority among n specs
+func MajorityVotes(n int) int {
+	return n/2 + 1
+}
+
+// MergeDomainSpecs combines specs by vote. An entity, value object or
+// interface method is kept when at least minVotes specs contain it; a field
+// or entity method when at least minVotes of the specs with its entity do.
+// Names are matched case-insensitively. Ties in types and signatures go to
+// the earliest spec, and elements keep the order in which they first appear.
+// Pass MajorityVotes(len(specs)) for a majority merge or 1 for a union.
+func MergeDomainSpecs(specs []*DomainSpec, minVotes int) *DomainSpec {
+	if len(specs) == 0 {
+		return nil
+	}
+
+	// vote picks the most common name across specs
+	vote := func(name func(*DomainSpec) string) string {
+		values := make([]string, len(specs))
+		for i, s := range specs {
+			values[i] = name(s)
+		}
+		return mostCommon(values)
+	}
+
+	merged := &DomainSpec{
+		DomainName: vote(func(s *DomainSpec) string { return s.DomainName }),
+	}
+
+	merged.Entities = mergeNamed(specs, minVotes,
+		func(s *DomainSpec) []EntitySpec { return s.Entities },
+		func(e EntitySpec) string { return e.Name },
+		func(versions []EntitySpec) EntitySpec {
+			votes := min(minVotes, MajorityVotes(len(versions)))
+
+			aggregate := 0
+			for _, v := range versions {
+				if v.IsAggregateRoot {
+					aggregate++
+				}
+			}
+
+			return EntitySpec{
+				Name:            versions[0].Name,
+				IsAggregateRoot: aggregate >= MajorityVotes(len(versions)),
+				Fields:          mergeFields(versions, votes, func(e EntitySpec) []FieldSpec { return e.Fields }),
+				Methods: mergeNamed(versions, votes,
+					func(e EntitySpec) []MethodSpec { return e.Methods },
+					func(m MethodSpec) string { return m.Name },
+					func(ms []MethodSpec) MethodSpec {
+						return pickBy(ms, func(m MethodSpec) string { return m.Signature })
+					}),
+			}
+		})
+
+	merged.ValueObjects = mergeNamed(specs, minVotes,
+		func(s *DomainSpec) []ValueObjectSpec { return s.ValueObjects },
+		func(v ValueObjectSpec) string { return v.Name },
+		func(versions []ValueObjectSpec) ValueObjectSpec {
+			votes := min(minVotes, MajorityVotes(len(versions)))
+
+			vo := pickBy(versions, func(v ValueObjectSpec) string { return v.Validation })
+			vo.Name = versions[0].Name
+			vo.Fields = mergeFields(versions, votes, func(v ValueObjectSpec) []FieldSpec { return v.Fields })
+			return vo
+		})
+
+	interfaceMethods := func(methods func(*DomainSpec) []InterfaceMethod) []InterfaceMethod {
+		return mergeNamed(specs, minVotes, methods,
+			func(m InterfaceMethod) string { return m.Name },
+			func(ms []InterfaceMethod) InterfaceMethod {
+				return pickBy(ms, func(m InterfaceMethod) string { return m.Signature })
+			})
+	}
+
+	merged.RepositoryInterface = RepositorySpec{
+		Name:    vote(func(s *DomainSpec) string { return s.RepositoryInterface.Name }),
+		Methods: interfaceMethods(func(s *DomainSpec) []InterfaceMethod { return s.RepositoryInterface.Methods }),
+	}
+	merged.ServiceInterface = ServiceSpec{
+		Name:    vote(func(s *DomainSpec) string { return s.ServiceInterface.Name }),
+		Methods: interfaceMethods(func(s *DomainSpec) []InterfaceMethod { return s.ServiceInterface.Methods }),
+	}
+
+	return merged
+}
+
+// mergeFields votes on fields by name, then on the type of each kept field
+func mergeFields[T any](owners []T, minVotes int, fields func(T) []FieldSpec) []FieldSpec {
+	return mergeNamed(owners, minVotes, fields,
+		func(f FieldSpec) string { return f.Name },
+		func(fs []FieldSpec) FieldSpec {
+			return pickBy(fs, func(f FieldSpec) string { return f.Type })
+		})
+}
+
+// mergeNamed keeps the named elements found in at least minVotes owners.
+// merge combines the versions of one element, in owner order.
+func mergeNamed[O, T any](owners []O, minVotes int, elements func(O) []T, name func(T) string, merge func([]T) T) []T {
+	var order []string
+	versions := make(map[string][]T)
+
+	for _, owner := range owners {
+		seen := make(map[string]bool)
+		for _, element := range elements(owner) {
+			key := strings.ToLower(name(element))
+			if seen[key] {
+				continue // A duplicate within one spec is a single vote
+			}
+			seen[key] = true
+
+			if _, ok := versions[key]; !ok {
+				order = append(order, key)
+			}
+			versions[key] = append(versions[key], element)
+		}
+	}
+
+	var merged []T
+	for _, key := range order {
+		if len(versions[key]) >= minVotes {
+			merged = append(merged, merge(versions[key]))
+		}
+	}
+	return merged
+}
+
+// pickBy returns the first version carrying the most common value of key
+func pickBy[T any](versions []T, key func(T) string) T {
+	values := make([]string, len(versions))
+	for i, v := range versions {
+		values[i] = key(v)
+	}
+
+	winner := mostCommon(values)
+	for i, value := range values {
+		if value == winner {
+			return versions[i]
+		}
+	}
+	return versions[0]
+}
+
+// mostCommon returns the most frequent non-empty value, earliest on ties
+func mostCommon(values []string) string {
+	counts := make(map[string]int)
+	best := ""
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		counts[v]++
+		if best == "" || counts[v] > counts[best] {
+			best = v
+		}
+	}
+	return best
+}
+
+// SpecDifference is one element the compared specs disagree on. Values holds
+// each spec's version (a field type, a method signature or an element name),
+// empty where the spec does not have the element.
+type SpecDifference struct {
+	Path   string // e.g. "entities.Order.fields.Total"
+	Values []string
+}
+
+// DiffDomainSpecs lists the elements that are missing from some specs or
+// differ between them, in the order they first appear
+func DiffDomainSpecs(specs []*DomainSpec) []SpecDifference {
+	var order []string
+	paths := make(map[string]string)
+	values := make(map[string][]string)
+
+	for i, spec := range specs {
+		for _, item := range flattenSpec(spec) {
+			if _, ok := values[item.key]; !ok {
+				order = append(order, item.key)
+				paths[item.key] = item.path
+				values[item.key] = make([]string, len(specs))
+			}
+			values[item.key][i] = item.value
+		}
+	}
+
+	var diffs []SpecDifference
+	for _, key := range order {
+		if !allEqual(values[key]) {
+			diffs = append(diffs, SpecDifference{Path: paths[key], Values: values[key]})
+		}
+	}
+
+	return diffs
+}
+
+// specItem is one comparable element of a spec
+type specItem struct {
+	key   string // Case-insensitive identity
+	path  string // Display path with the spec's own spelling
+	value string
+}
+
+// flattenSpec lists a spec's comparable elements in document order
+func flattenSpec(spec *DomainSpec) []specItem {
+	items := []specItem{{key: "domain_name", path: "domain_name", value: spec.DomainName}}
+
+	add := func(key, path, value string) {
+		items = append(items, specItem{key: strings.ToLower(key), path: path, value: value})
+	}
+
+	for _, e := range spec.Entities {
+		base := "entities." + e.Name
+		add(base, base, e.Name)
+		for _, f := range e.Fields {
+			add(base+".fields."+f.Name, base+".fields."+f.Name, f.Type)
+		}
+		for _, m := range e.Methods {
+			add(base+".methods."+m.Name, base+".methods."+m.Name, m.Signature)
+		}
+	}
+
+	for _, vo := range spec.ValueObjects {
+		base := "value_objects." + vo.Name
+		add(base, base, vo.Name)
+		for _, f := range vo.Fields {
+			add(base+".fields."+f.Name, base+".fields."+f.Name, f.Type)
+		}
+	}
+
+	for _, m := range spec.RepositoryInterface.Methods {
+		add("repository_interface.methods."+m.Name, "repository_interface.methods."+m.Name, m.Signature)
+	}
+	for _, m := range spec.ServiceInterface.Methods {
+		add("service_interface.methods."+m.Name, "service_interface.methods."+m.Name, m.Signature)
+	}
+
+	return items
+}
+
+// allEqual reports whether every spec has the element with the same value.
+// Names are compared case-insensitively, types and signatures ignoring spaces.
+func allEqual(values []string) bool {
+	normalize := func(v string) string {
+		return strings.ToLower(strings.Join(strings.Fields(v), ""))
+	}
+
+	for _, v := range values {
+		if v == "" || normalize(v) != normalize(values[0]) {
+			return false
+		}
+	}
+	return true
+}
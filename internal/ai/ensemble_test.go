@@ -0,0 +1,157 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func ensembleSpecs() []*DomainSpec {
+	return []*DomainSpec{
+		{
+			DomainName: "order",
+			Entities: []EntitySpec{
+				{Name: "Order", IsAggregateRoot: true, Fields: []FieldSpec{{Name: "ID", Type: "uuid.UUID"}, {Name: "Total", Type: "decimal.Decimal"}}},
+				{Name: "Invoice", Fields: []FieldSpec{{Name: "ID", Type: "uuid.UUID"}}},
+			},
+			RepositoryInterface: RepositorySpec{Name: "OrderRepository", Methods: []InterfaceMethod{{Name: "Save", Signature: "Save(ctx context.Context, o *entity.Order) error"}}},
+			ServiceInterface:    ServiceSpec{Name: "OrderService"},
+		},
+		{
+			DomainName: "order",
+			Entities: []EntitySpec{
+				{Name: "order", IsAggregateRoot: true, Fields: []FieldSpec{{Name: "ID", Type: "uuid.UUID"}, {Name: "Total", Type: "float64"}, {Name: "Notes", Type: "string"}}},
+			},
+			ValueObjects:        []ValueObjectSpec{{Name: "Money", Fields: []FieldSpec{{Name: "Amount", Type: "int64"}}}},
+			RepositoryInterface: RepositorySpec{Name: "OrderRepository", Methods: []InterfaceMethod{{Name: "Save", Signature: "Save(ctx context.Context, o *entity.Order) error"}}},
+			ServiceInterface:    ServiceSpec{Name: "OrderService"},
+		},
+		{
+			DomainName: "orders",
+			Entities: []EntitySpec{
+				{Name: "Order", Fields: []FieldSpec{{Name: "ID", Type: "uuid.UUID"}, {Name: "Total", Type: "float64"}}},
+			},
+			ValueObjects:        []ValueObjectSpec{{Name: "Money", Fields: []FieldSpec{{Name: "Amount", Type: "int64"}}}},
+			RepositoryInterface: RepositorySpec{Name: "OrderRepo", Methods: []InterfaceMethod{{Name: "Delete", Signature: "Delete(ctx context.Context, id uuid.UUID) error"}}},
+			ServiceInterface:    ServiceSpec{Name: "OrderService"},
+		},
+	}
+}
+
+func TestMergeDomainSpecsMajority(t *testing.T) {
+	specs := ensembleSpecs()
+	merged := MergeDomainSpecs(specs, MajorityVotes(len(specs)))
+
+	if merged.DomainName != "order" || merged.RepositoryInterface.Name != "OrderRepository" {
+		t.Errorf("Expected majority names, got %q and %q", merged.DomainName, merged.RepositoryInterface.Name)
+	}
+
+	if len(merged.Entities) != 1 || merged.Entities[0].Name != "Order" {
+		t.Fatalf("Expected only Order to win a majority, got %+v", merged.Entities)
+	}
+
+	order := merged.Entities[0]
+	if !order.IsAggregateRoot {
+		t.Error("Expected Order to stay an aggregate root")
+	}
+	if len(order.Fields) != 2 || order.Fields[1].Type != "float64" {
+		t.Errorf("Expected ID and Total with the majority type float64, got %+v", order.Fields)
+	}
+
+	if len(merged.ValueObjects) != 1 || merged.ValueObjects[0].Name != "Money" {
+		t.Errorf("Expected Money value object, got %+v", merged.ValueObjects)
+	}
+	if len(merged.RepositoryInterface.Methods) != 1 || merged.RepositoryInterface.Methods[0].Name != "Save" {
+		t.Errorf("Expected only Save in the repository, got %+v", merged.RepositoryInterface.Methods)
+	}
+}
+
+func TestMergeDomainSpecsUnion(t *testing.T) {
+	merged := MergeDomainSpecs(ensembleSpecs(), 1)
+
+	if len(merged.Entities) != 2 {
+		t.Fatalf("Expected every entity in a union, got %+v", merged.Entities)
+	}
+	if fields := merged.Entities[0].Fields; len(fields) != 3 {
+		t.Errorf("Expected ID, Total and Notes, got %+v", fields)
+	}
+	if len(merged.RepositoryInterface.Methods) != 2 {
+		t.Errorf("Expected Save and Delete, got %+v", merged.RepositoryInterface.Methods)
+	}
+}
+
+func TestMergeDomainSpecsKeepsMethodOfDroppedType(t *testing.T) {
+	// Only one spec has Invoice, but two agree on the method using it
+	specs := ensembleSpecs()
+	issue := InterfaceMethod{Name: "Issue", Signature: "Issue(ctx context.Context, invoice *entity.Invoice) error"}
+	specs[0].ServiceInterface.Methods = []InterfaceMethod{issue}
+	specs[1].ServiceInterface.Methods = []InterfaceMethod{issue}
+
+	merged := MergeDomainSpecs(specs, MajorityVotes(len(specs)))
+	if len(merged.ServiceInterface.Methods) != 1 || len(merged.Entities) != 1 {
+		t.Fatalf("Expected Issue to be kept and Invoice dropped, got %+v and %+v", merged.ServiceInterface.Methods, merged.Entities)
+	}
+
+	err := ValidateDomainSpec(merged, nil)
+	if err == nil || !strings.Contains(err.Error(), "entity.Invoice") {
+		t.Errorf("Expected validation to report the dropped entity.Invoice, got %v", err)
+	}
+}
+
+func TestDiffDomainSpecs(t *testing.T) {
+	diffs := DiffDomainSpecs(ensembleSpecs())
+
+	got := make(map[string][]string)
+	for _, d := range diffs {
+		got[d.Path] = d.Values
+	}
+
+	expected := map[string][]string{
+		"domain_name":                         {"order", "order", "orders"},
+		"entities.Order.fields.Total":         {"decimal.Decimal", "float64", "float64"},
+		"entities.Invoice":                    {"Invoice", "", ""},
+		"value_objects.Money":                 {"", "Money", "Money"},
+		"repository_interface.methods.Delete": {"", "", "Delete(ctx context.Context, id uuid.UUID) error"},
+	}
+	for path, values := range expected {
+		if strings.Join(got[path], "|") != strings.Join(values, "|") {
+			t.Errorf("Expected %s to differ as %q, got %q", path, values, got[path])
+		}
+	}
+
+	// Case differences in names and agreeing elements are not reported
+	for _, path := range []string{"entities.Order", "entities.Order.fields.ID", "service_interface.methods.Save"} {
+		if _, ok := got[path]; ok {
+			t.Errorf("Did not expect %s in the diff", path)
+		}
+	}
+}
+
+func TestGenerateEnsemble(t *testing.T) {
+	good := &scriptedProvider{fakeProvider: fakeProvider{name: "good"}, replies: []string{validSpecJSON}}
+	broken := &fakeProvider{name: "broken", err: fmt.Errorf("unavailable")}
+
+	orchestrator := newTestOrchestrator(t, good, 0)
+	orchestrator.providers["broken"] = broken
+
+	results, err := GenerateEnsemble(context.Background(), orchestrator, "Order", []string{"good", "broken", "missing"})
+	if err != nil {
+		t.Fatalf("Expected the ensemble to succeed with one provider, got %v", err)
+	}
+
+	if results[0].Spec == nil || results[0].Spec.DomainName != "order" {
+		t.Errorf("Expected a spec from the good provider, got %+v", results[0])
+	}
+	if results[1].Err == nil || results[2].Err == nil {
+		t.Errorf("Expected errors for the broken and missing providers, got %v and %v", results[1].Err, results[2].Err)
+	}
+	if len(good.prompts) != 1 || broken.calls != 1 {
+		t.Errorf("Expected one call per member without fallback, got good=%d broken=%d", len(good.prompts), broken.calls)
+	}
+
+	_, err = GenerateEnsemble(context.Background(), orchestrator, "Order", []string{"broken"})
+	if err == nil {
+		t.Error("Expected an error when no provider produced a spec")
+	}
+}
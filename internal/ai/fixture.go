@@ -0,0 +1,186 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ReplayProviderName selects the provider that serves recorded fixtures
+const ReplayProviderName = "replay"
+
+// Fixture is a recorded request and the response it produced
+type Fixture struct {
+	Request    *GenerateRequest  `json:"request"`
+	Response   *GenerateResponse `json:"response"`
+	Provider   string            `json:"provider"`
+	RecordedAt time.Time         `json:"recorded_at"`
+}
+
+// fixturePath returns where the fixture for a request is stored. Fixtures
+// are keyed by the provider-independent request hash also used by the cache.
+func fixturePath(dir string, req *GenerateRequest) string {
+	return filepath.Join(dir, hashRequest(req)+".json")
+}
+
+// MissingFixtureError reports a request that was never recorded
+type MissingFixtureError struct {
+	Hash      string
+	Directory string
+}
+
+func (e *MissingFixtureError) Error() string {
+	return fmt.Sprintf("no fixture for request %s in %s (record it with ai.fixtures.record or --record)", e.Hash[:12], e.Directory)
+}
+
+// RecordingProvider wraps a provider and saves every successful response as
+// a fixture that ReplayProvider can serve later
+type RecordingProvider struct {
+	Provider
+	dir string
+}
+
+// NewRecordingProvider records the responses of provider into dir
+func NewRecordingProvider(provider Provider, dir string) *RecordingProvider {
+	return &RecordingProvider{Provider: provider, dir: dir}
+}
+
+func (r *RecordingProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	resp, err := r.Provider.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.record(req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GenerateStream forwards the wrapped provider's stream and records the
+// complete response once the final chunk arrives
+func (r *RecordingProvider) GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan StreamChunk, error) {
+	chunks, err := streamFrom(ctx, r.Provider, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamChunk)
+	go func() {
+		defer close(out)
+
+		for chunk := range chunks {
+			if chunk.Done && chunk.Response != nil {
+				if err := r.record(req, chunk.Response); err != nil {
+					chunk = StreamChunk{Err: err}
+				}
+			}
+			if !sendChunk(ctx, out, chunk) {
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// record writes the fixture atomically, replacing an older recording
+func (r *RecordingProvider) record(req *GenerateRequest, resp *GenerateResponse) error {
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return fmt.Errorf("create fixture directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(Fixture{
+		Request:    req,
+		Response:   resp,
+		Provider:   r.Name(),
+		RecordedAt: time.Now(),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal fixture: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(r.dir, ".fixture-*")
+	if err != nil {
+		return fmt.Errorf("record fixture: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("record fixture: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("record fixture: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), fixturePath(r.dir, req)); err != nil {
+		return fmt.Errorf("record fixture: %w", err)
+	}
+	return nil
+}
+
+// ReplayProvider serves recorded fixtures instead of calling an AI service,
+// for deterministic runs without API keys. A request without a fixture is
+// an error; it never falls through to a live provider.
+type ReplayProvider struct {
+	dir string
+}
+
+// NewReplayProvider serves the fixtures recorded in dir
+func NewReplayProvider(dir string) *ReplayProvider {
+	return &ReplayProvider{dir: dir}
+}
+
+func (r *ReplayProvider) Name() string {
+	return ReplayProviderName
+}
+
+func (r *ReplayProvider) Model() string {
+	return "fixtures"
+}
+
+func (r *ReplayProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	path := fixturePath(r.dir, req)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, &MissingFixtureError{Hash: hashRequest(req), Directory: r.dir}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read fixture: %w", err)
+	}
+
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil || fixture.Response == nil {
+		return nil, fmt.Errorf("parse fixture %s: invalid fixture file", path)
+	}
+
+	resp := fixture.Response
+	resp.Provider = ReplayProviderName
+	resp.Cost = 0 // Replaying spends nothing
+	return resp, nil
+}
+
+func (r *ReplayProvider) Validate() error {
+	info, err := os.Stat(r.dir)
+	if err != nil {
+		return fmt.Errorf("fixture directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("fixture directory %s is not a directory", r.dir)
+	}
+	return nil
+}
+
+func (r *ReplayProvider) Health(ctx context.Context) error {
+	return r.Validate()
+}
+
+func (r *ReplayProvider) EstimateCost(req *GenerateRequest) (float64, error) {
+	return 0, nil
+}
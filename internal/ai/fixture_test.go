@@ -0,0 +1,74 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	live := &fakeProvider{name: "openai", content: "recorded"}
+	recorder := NewRecordingProvider(live, dir)
+
+	generated := &GenerateRequest{SystemPrompt: "system", UserPrompt: "Order"}
+	if _, err := recorder.Generate(context.Background(), generated); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	streamed := &GenerateRequest{SystemPrompt: "system", UserPrompt: "Invoice"}
+	chunks, err := recorder.GenerateStream(context.Background(), streamed)
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	if text, _, err := collectStream(chunks); err != nil || text != "recorded" {
+		t.Fatalf("Expected the stream to pass through, got %q (%v)", text, err)
+	}
+
+	replay := NewReplayProvider(dir)
+	for _, req := range []*GenerateRequest{generated, streamed} {
+		resp, err := replay.Generate(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Expected a fixture for %q, got %v", req.UserPrompt, err)
+		}
+		if resp.Content != "recorded" || resp.Provider != ReplayProviderName {
+			t.Errorf("Unexpected replayed response %+v", resp)
+		}
+	}
+
+	_, err = replay.Generate(context.Background(), &GenerateRequest{SystemPrompt: "system", UserPrompt: "Cart"})
+	var missing *MissingFixtureError
+	if !errors.As(err, &missing) {
+		t.Errorf("Expected a missing fixture error, got %v", err)
+	}
+}
+
+func TestNewOrchestratorReplayDoesNotFallBack(t *testing.T) {
+	cfg := &Config{
+		AI: AIConfig{
+			PrimaryProvider:   ReplayProviderName,
+			FallbackProviders: []string{"ollama"},
+			Providers: ProvidersConfig{
+				Ollama: ProviderConfig{Enabled: true},
+			},
+			Fixtures: FixtureConfig{Directory: t.TempDir()},
+		},
+		Cache: CacheConfig{Enabled: true, Directory: t.TempDir()},
+	}
+
+	orchestrator, err := NewOrchestrator(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewOrchestrator failed: %v", err)
+	}
+
+	_, err = orchestrator.Generate(context.Background(), &GenerateRequest{UserPrompt: "Order"})
+	var missing *MissingFixtureError
+	if !errors.As(err, &missing) {
+		t.Errorf("Expected the missing fixture to surface, got %v", err)
+	}
+	if chain := orchestrator.chain(&GenerateRequest{}); len(chain) != 1 {
+		t.Errorf("Expected replay alone in the chain, got %v", chain)
+	}
+}
@@ -3,9 +3,12 @@ package ai
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
@@ -35,6 +38,10 @@ func (g *GeminiProvider) Name() string {
 	return "gemini"
 }
 
+func (g *GeminiProvider) Model() string {
+	return g.model
+}
+
 func (g *GeminiProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
 	startTime := time.Now()
 
@@ -45,42 +52,183 @@ func (g *GeminiProvider) Generate(ctx context.Context, req *GenerateRequest) (*G
 	}
 	defer client.Close()
 
-	// Get model
-	model := client.GenerativeModel(g.model)
-
-	// Configure generation
-	model.SetTemperature(float32(req.Temperature))
-	model.SetTopP(float32(req.TopP))
-	model.SetMaxOutputTokens(int32(req.MaxTokens))
-
-	// Combine system and user prompts
-	fullPrompt := req.SystemPrompt + "\n\n" + req.UserPrompt
+	model, history, last, err := g.configureModel(client, req)
+	if err != nil {
+		return nil, err
+	}
 
 	// Retry logic
 	var lastErr error
 	for attempt := 0; attempt <= g.retries; attempt++ {
 		if attempt > 0 {
-			// Exponential backoff
-			backoff := time.Duration(attempt) * time.Second
 			select {
 			case <-ctx.Done():
 				return nil, ctx.Err()
-			case <-time.After(backoff):
+			case <-time.After(retryBackoff(attempt)):
 			}
 		}
 
-		// Generate content
-		resp, err := model.GenerateContent(ctx, genai.Text(fullPrompt))
+		// Generate content. A chat session records the turns it sends, so
+		// every attempt starts a new one.
+		chat := model.StartChat()
+		chat.History = slices.Clone(history)
+		resp, err := chat.SendMessage(ctx, last...)
 		if err == nil && resp != nil {
 			return g.parseResponse(resp, startTime), nil
 		}
 
+		// Leave waiting out quota errors to the orchestrator
+		if rateErr := geminiRateLimitError(err); rateErr != nil {
+			return nil, rateErr
+		}
+
 		lastErr = err
 	}
 
 	return nil, fmt.Errorf("gemini request failed after %d retries: %w", g.retries, lastErr)
 }
 
+// GenerateStream streams content using the Gemini streaming endpoint
+func (g *GeminiProvider) GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan StreamChunk, error) {
+	startTime := time.Now()
+
+	// Create client; it is closed by the reader goroutine
+	client, err := genai.NewClient(ctx, option.WithAPIKey(g.apiKey))
+	if err != nil {
+		return nil, fmt.Errorf("create gemini client: %w", err)
+	}
+
+	model, history, last, err := g.configureModel(client, req)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	chat := model.StartChat()
+	chat.History = history
+	iter := chat.SendMessageStream(ctx, last...)
+
+	out := make(chan StreamChunk)
+	go func() {
+		defer close(out)
+		defer client.Close()
+
+		var content strings.Builder
+		var last *genai.GenerateContentResponse
+		for {
+			resp, err := iter.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				if rateErr := geminiRateLimitError(err); rateErr != nil {
+					sendChunk(ctx, out, StreamChunk{Err: rateErr})
+					return
+				}
+				sendChunk(ctx, out, StreamChunk{Err: fmt.Errorf("gemini stream: %w", err)})
+				return
+			}
+
+			last = resp
+			text := g.parseResponse(resp, startTime).Content
+			if text == "" {
+				continue
+			}
+			content.WriteString(text)
+			if !sendChunk(ctx, out, StreamChunk{Content: text}) {
+				return
+			}
+		}
+
+		if last == nil {
+			sendChunk(ctx, out, StreamChunk{Err: fmt.Errorf("gemini stream returned no content")})
+			return
+		}
+
+		// The last chunk carries usage metadata and the finish reason
+		final := g.parseResponse(last, startTime)
+		final.Content = content.String()
+		sendChunk(ctx, out, StreamChunk{Done: true, Response: final})
+	}()
+
+	return out, nil
+}
+
+// configureModel applies request parameters and returns the model with the
+// earlier turns of the conversation and the user turn to send
+func (g *GeminiProvider) configureModel(client *genai.Client, req *GenerateRequest) (*genai.GenerativeModel, []*genai.Content, []genai.Part, error) {
+	// Get model
+	model := client.GenerativeModel(g.model)
+
+	// Configure generation
+	model.SetTemperature(float32(req.Temperature))
+	model.SetTopP(float32(req.TopP))
+	model.SetMaxOutputTokens(int32(req.MaxTokens))
+
+	// Constrain output to the requested schema
+	if req.ResponseSchema != nil {
+		model.ResponseMIMEType = "application/json"
+		model.ResponseSchema = toGeminiSchema(req.ResponseSchema)
+	}
+
+	// Gemini takes the system prompt as an instruction and calls the
+	// assistant "model"
+	system, turns := splitSystem(req.Conversation())
+	if system != "" {
+		model.SystemInstruction = genai.NewUserContent(genai.Text(system))
+	}
+	if len(turns) == 0 || turns[len(turns)-1].Role != RoleUser {
+		return nil, nil, nil, fmt.Errorf("gemini request must end with a user message")
+	}
+
+	history := make([]*genai.Content, 0, len(turns)-1)
+	for _, m := range turns[:len(turns)-1] {
+		role := "user"
+		if m.Role == RoleAssistant {
+			role = "model"
+		}
+		history = append(history, &genai.Content{Role: role, Parts: []genai.Part{genai.Text(m.Content)}})
+	}
+
+	return model, history, []genai.Part{genai.Text(turns[len(turns)-1].Content)}, nil
+}
+
+// toGeminiSchema converts a JSON schema to the Gemini representation
+func toGeminiSchema(s *JSONSchema) *genai.Schema {
+	if s == nil {
+		return nil
+	}
+
+	schema := &genai.Schema{
+		Description: s.Description,
+		Required:    s.Required,
+		Items:       toGeminiSchema(s.Items),
+	}
+
+	switch s.Type {
+	case "object":
+		schema.Type = genai.TypeObject
+	case "array":
+		schema.Type = genai.TypeArray
+	case "integer":
+		schema.Type = genai.TypeInteger
+	case "number":
+		schema.Type = genai.TypeNumber
+	case "boolean":
+		schema.Type = genai.TypeBoolean
+	default:
+		schema.Type = genai.TypeString
+	}
+
+	if len(s.Properties) > 0 {
+		schema.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, prop := range s.Properties {
+			schema.Properties[name] = toGeminiSchema(prop)
+		}
+	}
+
+	return schema
+}
+
 func (g *GeminiProvider) parseResponse(resp *genai.GenerateContentResponse, startTime time.Time) *GenerateResponse {
 	// Extract text from response
 	var content string
@@ -157,6 +305,7 @@ func (g *GeminiProvider) Health(ctx context.Context) error {
 		UserPrompt:   "Respond with 'OK'",
 		Temperature:  0.0,
 		MaxTokens:    10,
+		Metadata:     map[string]string{MetadataTask: TaskHealth},
 	}
 
 	_, err := g.Generate(ctx, req)
@@ -170,7 +319,7 @@ func (g *GeminiProvider) EstimateCost(req *GenerateRequest) (float64, error) {
 	}
 
 	// Rough estimation for Pro model
-	estimatedInputTokens := len(req.SystemPrompt+req.UserPrompt) / 4
+	estimatedInputTokens := req.promptLength() / 4
 	estimatedOutputTokens := req.MaxTokens
 
 	inputCost := float64(estimatedInputTokens) * 0.35 / 1_000_000
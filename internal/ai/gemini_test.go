@@ -0,0 +1,53 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+func TestGeminiConfigureModelConversation(t *testing.T) {
+	client, err := genai.NewClient(context.Background(), option.WithAPIKey("test-key"))
+	if err != nil {
+		t.Fatalf("create client: %v", err)
+	}
+	defer client.Close()
+
+	provider := NewGeminiProvider("test-key", "", time.Second, 0)
+	model, history, last, err := provider.configureModel(client, &GenerateRequest{
+		SystemPrompt: "You are a test assistant",
+		UserPrompt:   "Hello",
+		Messages: []Message{
+			{Role: RoleAssistant, Content: "Hi there, how can I help?"},
+			{Role: RoleUser, Content: "Shorter"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("configureModel failed: %v", err)
+	}
+
+	if model.SystemInstruction == nil || model.SystemInstruction.Parts[0] != genai.Text("You are a test assistant") {
+		t.Errorf("Expected the system prompt as the instruction, got %+v", model.SystemInstruction)
+	}
+	if len(history) != 2 || history[0].Role != "user" || history[1].Role != "model" {
+		t.Fatalf("Expected a user and a model turn in the history, got %+v", history)
+	}
+	if history[1].Parts[0] != genai.Text("Hi there, how can I help?") {
+		t.Errorf("Unexpected model turn: %+v", history[1].Parts)
+	}
+	if len(last) != 1 || last[0] != genai.Text("Shorter") {
+		t.Errorf("Expected the last user turn to be sent, got %+v", last)
+	}
+
+	// Gemini cannot continue from an assistant turn
+	_, _, _, err = provider.configureModel(client, &GenerateRequest{
+		UserPrompt: "Hello",
+		Messages:   []Message{{Role: RoleAssistant, Content: "Hi"}},
+	})
+	if err == nil {
+		t.Error("Expected an error for a conversation ending with the assistant")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -17,20 +18,26 @@ type GroqProvider struct {
 	timeout time.Duration
 	retries int
 	baseURL string
+	stream  *http.Client // Shared by streaming requests, keeping their connections pooled
 }
 
 // NewGroqProvider creates a new Groq provider
-func NewGroqProvider(apiKey, model string, timeout time.Duration, retries int) *GroqProvider {
+func NewGroqProvider(apiKey, model, baseURL string, timeout time.Duration, retries int) *GroqProvider {
 	if model == "" {
 		model = "llama-3.3-70b-versatile" // Default to Llama 3.3 70B (fast and capable)
 	}
 
+	if baseURL == "" {
+		baseURL = "https://api.groq.com/openai/v1"
+	}
+
 	return &GroqProvider{
 		apiKey:  apiKey,
 		model:   model,
 		timeout: timeout,
 		retries: retries,
-		baseURL: "https://api.groq.com/openai/v1",
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		stream:  streamingClient(timeout),
 	}
 }
 
@@ -38,6 +45,10 @@ func (g *GroqProvider) Name() string {
 	return "groq"
 }
 
+func (g *GroqProvider) Model() string {
+	return g.model
+}
+
 // GroqRequest represents a request to the Groq API
 type groqRequest struct {
 	Model       string        `json:"model"`
@@ -46,6 +57,8 @@ type groqRequest struct {
 	MaxTokens   int           `json:"max_tokens,omitempty"`
 	TopP        float64       `json:"top_p"`
 	Stream      bool          `json:"stream"`
+
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
 }
 
 type groqMessage struct {
@@ -88,33 +101,16 @@ type groqErrorResponse struct {
 
 func (g *GroqProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
 	startTime := time.Now()
-
-	// Build messages
-	messages := []groqMessage{
-		{Role: "system", Content: req.SystemPrompt},
-		{Role: "user", Content: req.UserPrompt},
-	}
-
-	// Prepare request
-	groqReq := groqRequest{
-		Model:       g.model,
-		Messages:    messages,
-		Temperature: req.Temperature,
-		MaxTokens:   req.MaxTokens,
-		TopP:        req.TopP,
-		Stream:      false,
-	}
+	groqReq := g.buildRequest(req, false)
 
 	// Retry logic
 	var lastErr error
 	for attempt := 0; attempt <= g.retries; attempt++ {
 		if attempt > 0 {
-			// Exponential backoff
-			backoff := time.Duration(attempt) * time.Second
 			select {
 			case <-ctx.Done():
 				return nil, ctx.Err()
-			case <-time.After(backoff):
+			case <-time.After(retryBackoff(attempt)):
 			}
 		}
 
@@ -123,12 +119,60 @@ func (g *GroqProvider) Generate(ctx context.Context, req *GenerateRequest) (*Gen
 			return g.parseResponse(resp, startTime), nil
 		}
 
+		// Leave waiting out rate limits to the orchestrator
+		if isRateLimited(err) {
+			return nil, err
+		}
+
 		lastErr = err
 	}
 
 	return nil, fmt.Errorf("groq request failed after %d retries: %w", g.retries, lastErr)
 }
 
+// GenerateStream streams the completion as server-sent events
+func (g *GroqProvider) GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan StreamChunk, error) {
+	startTime := time.Now()
+
+	headers := map[string]string{"Authorization": "Bearer " + g.apiKey}
+	httpResp, err := openStream(ctx, "groq", g.baseURL+"/chat/completions", headers, g.buildRequest(req, true), g.stream, g.retries)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamChunk)
+	final := &GenerateResponse{Provider: "groq", Model: g.model}
+	go readOpenAIStream(ctx, httpResp.Body, out, final, startTime, nil)
+
+	return out, nil
+}
+
+func (g *GroqProvider) buildRequest(req *GenerateRequest, stream bool) groqRequest {
+	// Roles map one to one onto chat completion messages
+	var messages []groqMessage
+	for _, m := range req.Conversation() {
+		messages = append(messages, groqMessage{Role: m.Role, Content: m.Content})
+	}
+
+	groqReq := groqRequest{
+		Model:       g.model,
+		Messages:    messages,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		TopP:        req.TopP,
+		Stream:      stream,
+	}
+
+	// Schema-constrained output is limited to a few Groq models, so use
+	// JSON mode, which every model supports. It cannot be combined with
+	// streaming; streamed replies are still validated after parsing.
+	if req.ResponseSchema != nil && !stream {
+		groqReq.ResponseFormat = &openAIResponseFormat{Type: "json_object"}
+	}
+
+	return groqReq
+}
+
 func (g *GroqProvider) makeRequest(ctx context.Context, req groqRequest) (*groqResponse, error) {
 	// Marshal request
 	body, err := json.Marshal(req)
@@ -164,9 +208,9 @@ func (g *GroqProvider) makeRequest(ctx context.Context, req groqRequest) (*groqR
 	if httpResp.StatusCode != http.StatusOK {
 		var errResp groqErrorResponse
 		if err := json.Unmarshal(respBody, &errResp); err == nil && errResp.Error.Message != "" {
-			return nil, fmt.Errorf("groq API error (%d): %s", httpResp.StatusCode, errResp.Error.Message)
+			return nil, apiError("groq", httpResp, errResp.Error.Message)
 		}
-		return nil, fmt.Errorf("groq API error (%d): %s", httpResp.StatusCode, string(respBody))
+		return nil, apiError("groq", httpResp, string(respBody))
 	}
 
 	// Parse response
@@ -220,6 +264,7 @@ func (g *GroqProvider) Health(ctx context.Context) error {
 		UserPrompt:   "Respond with 'OK'",
 		Temperature:  0.0,
 		MaxTokens:    10,
+		Metadata:     map[string]string{MetadataTask: TaskHealth},
 	}
 
 	_, err := g.Generate(ctx, req)
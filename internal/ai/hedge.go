@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"context"
+	"time"
+)
+
+// GenerateHedged trades a little extra cost for latency. The request goes to
+// the first provider in the chain; if no usable response arrives within the
+// orchestrator's hedge delay, it is also sent to the next provider, and so
+// on. A provider that fails hands over to the next one immediately. The
+// first response accepted by accept wins and the requests still in flight
+// are cancelled through their context.
+//
+// Without a hedge delay this behaves like Generate with accept applied.
+func (o *Orchestrator) GenerateHedged(ctx context.Context, req *GenerateRequest, accept func(*GenerateResponse) error) (*GenerateResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // Cancels the losers once a winner returns
+
+	type result struct {
+		provider string
+		resp     *GenerateResponse
+		err      error
+	}
+
+	chain := o.chain(req)
+	results := make(chan result, len(chain))
+	next, inFlight := 0, 0
+
+	// launch starts the next configured provider, reporting false once the
+	// chain is exhausted
+	launch := func() bool {
+		for next < len(chain) {
+			providerName := chain[next]
+			next++
+			if _, exists := o.providers[providerName]; !exists {
+				continue
+			}
+
+			inFlight++
+			go func() {
+				resp, err := o.generateFrom(ctx, providerName, req, accept)
+				results <- result{provider: providerName, resp: resp, err: err}
+			}()
+			return true
+		}
+		return false
+	}
+
+	if !launch() {
+		return nil, (&chainErrors{}).err(o.health)
+	}
+
+	// Without a delay, providers are only tried one after another
+	var hedge <-chan time.Time
+	resetHedge := func() {
+		if o.hedgeDelay > 0 {
+			hedge = time.After(o.hedgeDelay)
+		}
+	}
+	resetHedge()
+
+	var errs chainErrors
+	for inFlight > 0 {
+		select {
+		case <-hedge:
+			hedge = nil
+			if launch() {
+				o.logger.Info("no response within hedge delay, racing next provider",
+					"delay", o.hedgeDelay,
+					"provider", chain[next-1],
+				)
+				resetHedge()
+			}
+
+		case r := <-results:
+			inFlight--
+			if r.err == nil {
+				o.logger.Info("hedged request won",
+					"provider", r.provider,
+					"cancelled", inFlight,
+				)
+				return r.resp, nil
+			}
+
+			errs.add(r.err)
+			if launch() {
+				resetHedge()
+			}
+		}
+	}
+
+	return nil, errs.err(o.health)
+}
@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// slowProvider answers after a delay unless its context is cancelled first
+type slowProvider struct {
+	fakeProvider
+	delay     time.Duration
+	cancelled chan struct{}
+}
+
+func newSlowProvider(name, content string, delay time.Duration) *slowProvider {
+	return &slowProvider{
+		fakeProvider: fakeProvider{name: name, content: content},
+		delay:        delay,
+		cancelled:    make(chan struct{}),
+	}
+}
+
+func (s *slowProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	select {
+	case <-time.After(s.delay):
+		return s.fakeProvider.Generate(ctx, req)
+	case <-ctx.Done():
+		close(s.cancelled)
+		return nil, ctx.Err()
+	}
+}
+
+func acceptContent(want string) func(*GenerateResponse) error {
+	return func(resp *GenerateResponse) error {
+		if resp.Content != want {
+			return fmt.Errorf("unexpected content %q", resp.Content)
+		}
+		return nil
+	}
+}
+
+func newHedgedOrchestrator(t *testing.T, primary, backup Provider, delay time.Duration) *Orchestrator {
+	orchestrator := newTestOrchestrator(t, primary, 0)
+	orchestrator.providers[backup.Name()] = backup
+	orchestrator.fallbackChain = []string{backup.Name()}
+	orchestrator.hedgeDelay = delay
+	return orchestrator
+}
+
+func TestGenerateHedgedRacesSlowPrimary(t *testing.T) {
+	primary := newSlowProvider("primary", "ok", 5*time.Second)
+	backup := newSlowProvider("backup", "ok", 10*time.Millisecond)
+	orchestrator := newHedgedOrchestrator(t, primary, backup, 20*time.Millisecond)
+
+	start := time.Now()
+	resp, err := orchestrator.GenerateHedged(context.Background(), &GenerateRequest{UserPrompt: "Hi"}, acceptContent("ok"))
+	if err != nil {
+		t.Fatalf("GenerateHedged failed: %v", err)
+	}
+	if resp.Provider != "backup" {
+		t.Errorf("Expected the hedged backup to win, got %q", resp.Provider)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the hedge to avoid waiting for the primary, took %s", elapsed)
+	}
+
+	select {
+	case <-primary.cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the losing request to be cancelled")
+	}
+
+	// A cancelled loser is not a provider failure
+	if stats := orchestrator.health.Stats("primary"); stats.Failures != 0 {
+		t.Errorf("Expected no failure recorded for the cancelled primary, got %d", stats.Failures)
+	}
+}
+
+func TestGenerateHedgedPrimaryWithinDelay(t *testing.T) {
+	primary := newSlowProvider("primary", "ok", 0)
+	backup := newSlowProvider("backup", "ok", 0)
+	orchestrator := newHedgedOrchestrator(t, primary, backup, time.Second)
+
+	resp, err := orchestrator.GenerateHedged(context.Background(), &GenerateRequest{UserPrompt: "Hi"}, acceptContent("ok"))
+	if err != nil {
+		t.Fatalf("GenerateHedged failed: %v", err)
+	}
+	if resp.Provider != "primary" {
+		t.Errorf("Expected the primary to answer, got %q", resp.Provider)
+	}
+	if backup.calls != 0 {
+		t.Errorf("Expected no hedged request within the delay, got %d", backup.calls)
+	}
+}
+
+func TestGenerateHedgedSkipsInvalidResponse(t *testing.T) {
+	primary := newSlowProvider("primary", "not json", 0)
+	backup := newSlowProvider("backup", "ok", 0)
+	orchestrator := newHedgedOrchestrator(t, primary, backup, time.Hour)
+	orchestrator.cache = NewCache(t.TempDir(), time.Hour, 0, true)
+
+	req := &GenerateRequest{UserPrompt: "Hi"}
+	resp, err := orchestrator.GenerateHedged(context.Background(), req, acceptContent("ok"))
+	if err != nil {
+		t.Fatalf("GenerateHedged failed: %v", err)
+	}
+	if resp.Provider != "backup" {
+		t.Errorf("Expected an invalid response to hand over to the backup, got %q", resp.Provider)
+	}
+
+	if _, hit := orchestrator.cache.Get("primary", primary.Model(), req); hit {
+		t.Error("Expected the rejected response not to be cached")
+	}
+}
@@ -0,0 +1,542 @@
+package ai
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Config layers, from lowest to highest precedence
+const (
+	LayerDefault = "default" // Built-in defaults
+	LayerGlobal  = "global"  // ~/.anaphase/config.yaml
+	LayerProject = "project" // .anaphase.yaml in the project
+	LayerProfile = "profile" // The selected entry of profiles
+	LayerEnv     = "env"     // Environment variables
+	LayerFlag    = "flag"    // Command-line flags
+)
+
+// ProjectConfigFile pins settings for everyone working on a repository. It
+// is looked up in the working directory and its parents.
+const ProjectConfigFile = ".anaphase.yaml"
+
+// projectDeniedKeys may not be set by a project's .anaphase.yaml, which
+// comes with the repository rather than from the user: an api_key reference
+// could run a command (cmd:) or read a file, and an endpoint could receive
+// the user's own keys. A * matches any single key.
+var projectDeniedKeys = [][]string{
+	{"ai", "providers", "*", "api_key"},
+	{"ai", "providers", "*", "base_url"},
+	{"ai", "openai_compatible"},
+	{"profiles", "*", "providers", "*", "api_key"},
+	{"profiles", "*", "providers", "*", "base_url"},
+	{"profiles", "*", "openai_compatible"},
+}
+
+// EnvPrefix prefixes the environment variable of every config key, e.g.
+// ANAPHASE_AI_PRIMARY_PROVIDER sets ai.primary_provider
+const EnvPrefix = "ANAPHASE_"
+
+// providerKeyEnv enables a provider when its usual API key variable is set
+var providerKeyEnv = []struct {
+	provider string
+	env      string
+}{
+	{"gemini", "GEMINI_API_KEY"},
+	{"groq", "GROQ_API_KEY"},
+	{"openai", "OPENAI_API_KEY"},
+	{"claude", "CLAUDE_API_KEY"},
+}
+
+// ConfigSource is where a config value was set
+type ConfigSource struct {
+	Layer string
+	Path  string // Config file, environment variable or flag; empty for defaults
+}
+
+func (s ConfigSource) String() string {
+	if s.Path == "" {
+		return s.Layer
+	}
+	return s.Layer + ":" + s.Path
+}
+
+// FlagValue is a command-line flag that overrides a config key
+type FlagValue struct {
+	Flag  string // e.g. --provider
+	Key   string // e.g. ai.primary_provider
+	Value any
+}
+
+// ConfigLayers is the configuration merged from every layer before it is
+// decoded, remembering which layer set each value
+type ConfigLayers struct {
+	Values  map[string]any          // Merged values, nested like the YAML
+	Origins map[string]ConfigSource // By dotted key, for every leaf value
+	Profile string                  // Selected profile, empty for none
+}
+
+// override is a value set by the env or flag layer
+type override struct {
+	key    string
+	value  any
+	source ConfigSource
+}
+
+// GlobalConfigPath returns the path of the user's config file
+func GlobalConfigPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".anaphase", "config.yaml"), nil
+}
+
+// FindProjectConfig looks for ProjectConfigFile in the working directory and
+// its parents
+func FindProjectConfig() (string, bool) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	for {
+		path := filepath.Join(dir, ProjectConfigFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// ReadConfigLayers merges the built-in defaults, the global config file, the
+// project's .anaphase.yaml, the selected profile, environment variables and
+// flags, in that order. The global config file is created on first use.
+func ReadConfigLayers(flags ...FlagValue) (*ConfigLayers, error) {
+	globalPath, err := GlobalConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(globalPath); os.IsNotExist(err) {
+		if err := createDefaultConfig(filepath.Dir(globalPath), globalPath); err != nil {
+			return nil, fmt.Errorf("create default config: %w", err)
+		}
+	}
+
+	layers := &ConfigLayers{
+		Values:  make(map[string]any),
+		Origins: make(map[string]ConfigSource),
+	}
+
+	defaults, err := parseConfigYAML([]byte(defaultConfigYAML))
+	if err != nil {
+		return nil, fmt.Errorf("parse default config: %w", err)
+	}
+	layers.merge("", layers.Values, defaults, ConfigSource{Layer: LayerDefault})
+
+	files := []ConfigSource{{Layer: LayerGlobal, Path: globalPath}}
+	if path, ok := FindProjectConfig(); ok {
+		files = append(files, ConfigSource{Layer: LayerProject, Path: path})
+	}
+	for _, source := range files {
+		data, err := os.ReadFile(source.Path)
+		if err != nil {
+			return nil, fmt.Errorf("read config: %w", err)
+		}
+		values, err := parseConfigYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("read config %s: %w", source.Path, err)
+		}
+		if source.Layer == LayerProject {
+			for _, key := range slices.Sorted(maps.Keys(values)) {
+				if err := CheckProjectSetting(key, values[key]); err != nil {
+					return nil, fmt.Errorf("read config %s: %w", source.Path, err)
+				}
+			}
+		}
+		layers.merge("", layers.Values, values, source)
+	}
+
+	overrides, err := readEnv()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range flags {
+		overrides = append(overrides, override{f.Key, f.Value, ConfigSource{Layer: LayerFlag, Path: f.Flag}})
+	}
+
+	// The profile may be picked by any layer, but is applied right above
+	// the config files so the environment and flags still override it
+	if profile, ok := layers.Lookup("profile"); ok {
+		layers.Profile = fmt.Sprint(profile)
+	}
+	for _, o := range overrides {
+		if o.key == "profile" {
+			layers.Profile = fmt.Sprint(o.value)
+		}
+	}
+	layers.Profile = strings.ToLower(layers.Profile)
+	if profile, ok := layers.Lookup("profiles." + layers.Profile); ok && layers.Profile != "" {
+		if values, ok := profile.(map[string]any); ok {
+			layers.Set("ai", values, ConfigSource{Layer: LayerProfile, Path: layers.Profile})
+		}
+	}
+
+	for _, o := range overrides {
+		layers.Set(o.key, o.value, o.source)
+	}
+
+	return layers, nil
+}
+
+// CheckProjectSetting returns an error if a project's .anaphase.yaml may not
+// set key to value. API keys, provider endpoints and OpenAI-compatible
+// endpoints belong in the global config or the environment.
+func CheckProjectSetting(key string, value any) error {
+	parts := strings.Split(key, ".")
+	for _, denied := range projectDeniedKeys {
+		if len(parts) < len(denied) {
+			continue
+		}
+		matches := true
+		for i, part := range denied {
+			if part != "*" && part != parts[i] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return fmt.Errorf("%s cannot be set in a project's %s; set it in the global config or the environment",
+				strings.Join(parts[:len(denied)], "."), ProjectConfigFile)
+		}
+	}
+
+	if m, ok := value.(map[string]any); ok {
+		for _, k := range slices.Sorted(maps.Keys(m)) {
+			if err := CheckProjectSetting(key+"."+k, m[k]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readEnv returns the provider key variables, OLLAMA_HOST and then every
+// ANAPHASE_ variable that names a config key as overrides
+func readEnv() ([]override, error) {
+	var overrides []override
+	for _, p := range providerKeyEnv {
+		if key := os.Getenv(p.env); key != "" {
+			source := ConfigSource{Layer: LayerEnv, Path: p.env}
+			overrides = append(overrides,
+				override{"ai.providers." + p.provider + ".api_key", key, source},
+				override{"ai.providers." + p.provider + ".enabled", true, source})
+		}
+	}
+
+	// Ollama needs no key; pointing at a server is enough to enable it
+	if host := os.Getenv("OLLAMA_HOST"); host != "" {
+		if !strings.Contains(host, "://") {
+			host = "http://" + host
+		}
+		source := ConfigSource{Layer: LayerEnv, Path: "OLLAMA_HOST"}
+		overrides = append(overrides,
+			override{"ai.providers.ollama.base_url", host, source},
+			override{"ai.providers.ollama.enabled", true, source})
+	}
+
+	for _, key := range ConfigKeys() {
+		name := ConfigKeyEnv(key)
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		value, err := ParseConfigValue(key, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		overrides = append(overrides, override{key, value, ConfigSource{Layer: LayerEnv, Path: name}})
+	}
+	return overrides, nil
+}
+
+// Set overrides a dotted key, as a layer above the current ones would
+func (l *ConfigLayers) Set(key string, value any, source ConfigSource) {
+	parts := strings.Split(key, ".")
+	nested := map[string]any{parts[len(parts)-1]: value}
+	for i := len(parts) - 2; i >= 0; i-- {
+		nested = map[string]any{parts[i]: nested}
+	}
+	l.merge("", l.Values, nested, source)
+}
+
+// merge copies src over dst. Mappings merge key by key; any other value,
+// lists included, replaces what was there.
+func (l *ConfigLayers) merge(prefix string, dst, src map[string]any, source ConfigSource) {
+	for k, v := range src {
+		if v == nil {
+			continue // An empty key in a file sets nothing
+		}
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		if m, ok := v.(map[string]any); ok {
+			child, ok := dst[k].(map[string]any)
+			if !ok {
+				l.forget(key)
+				child = make(map[string]any)
+				dst[k] = child
+			}
+			l.merge(key, child, m, source)
+			continue
+		}
+
+		l.forget(key)
+		dst[k] = v
+		l.Origins[key] = source
+	}
+}
+
+// forget drops the origins of key and everything below it
+func (l *ConfigLayers) forget(key string) {
+	delete(l.Origins, key)
+	for k := range l.Origins {
+		if strings.HasPrefix(k, key+".") {
+			delete(l.Origins, k)
+		}
+	}
+}
+
+// Lookup returns the merged value of a dotted key, which may be a mapping
+func (l *ConfigLayers) Lookup(key string) (any, bool) {
+	var value any = l.Values
+	for _, part := range strings.Split(key, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// Keys returns the dotted keys of every leaf value, sorted
+func (l *ConfigLayers) Keys() []string {
+	keys := make([]string, 0, len(l.Origins))
+	for k := range l.Origins {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Decode turns the merged values into a Config, filling in defaults and
+// resolving API keys
+func (l *ConfigLayers) Decode() (*Config, error) {
+	if _, ok := l.Lookup("profiles." + l.Profile); l.Profile != "" && !ok {
+		return nil, fmt.Errorf("profile %q is not defined", l.Profile)
+	}
+
+	config, err := decodeConfig(l.Values)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal config: %w", err)
+	}
+
+	applyDefaults(config)
+
+	// Expand ${ENV}, file: and cmd: API keys; a placeholder that stays
+	// unresolved must not pass for a configured key
+	if err := config.ResolveSecrets(); err != nil {
+		return nil, fmt.Errorf("resolve API keys: %w", err)
+	}
+
+	return config, nil
+}
+
+// decodeConfig decodes nested values the way a config file is read
+func decodeConfig(values map[string]any) (*Config, error) {
+	v := viper.New()
+	if err := v.MergeConfigMap(values); err != nil {
+		return nil, err
+	}
+
+	// Keys follow the yaml struct tags, e.g. primary_provider
+	var config Config
+	if err := v.Unmarshal(&config, decodeWithYAMLTags); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// parseConfigYAML reads a config file into nested maps with lower-case keys,
+// which is how viper matches them
+func parseConfigYAML(data []byte) (map[string]any, error) {
+	var values map[string]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	if values == nil {
+		return map[string]any{}, nil
+	}
+	return lowerKeys(values).(map[string]any), nil
+}
+
+func lowerKeys(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		lowered := make(map[string]any, len(v))
+		for k, item := range v {
+			lowered[strings.ToLower(k)] = lowerKeys(item)
+		}
+		return lowered
+	case []any:
+		for i, item := range v {
+			v[i] = lowerKeys(item)
+		}
+	}
+	return value
+}
+
+// ConfigKeys returns the dotted key of every setting in Config, sorted
+func ConfigKeys() []string {
+	var keys []string
+	var walk func(prefix string, t reflect.Type)
+	walk = func(prefix string, t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			key := prefix + field.Tag.Get("yaml")
+			if field.Type.Kind() == reflect.Struct {
+				walk(key+".", field.Type)
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+	walk("", reflect.TypeOf(Config{}))
+	sort.Strings(keys)
+	return keys
+}
+
+// ConfigKeyEnv returns the environment variable that sets a config key
+func ConfigKeyEnv(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// configKeyType returns the Go type a dotted key decodes into, or an error
+// for a key Config does not have. Keys inside a map, such as a header name,
+// are accepted as is.
+func configKeyType(key string) (reflect.Type, error) {
+	t := reflect.TypeOf(Config{})
+	for _, part := range strings.Split(key, ".") {
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := structFieldByTag(t, part)
+			if !ok {
+				return nil, fmt.Errorf("unknown config key %q", key)
+			}
+			t = field.Type
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return nil, fmt.Errorf("unknown config key %q", key)
+		}
+	}
+	return t, nil
+}
+
+func structFieldByTag(t reflect.Type, tag string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("yaml") == tag {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// ParseConfigValue turns a value given on the command line or in an
+// environment variable into the value stored for key. Strings stay strings
+// (so go_version 1.20 is not read as a number), a list of strings may be
+// written comma-separated, and anything else is read as YAML.
+func ParseConfigValue(key, raw string) (any, error) {
+	t, err := configKeyType(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	switch {
+	case t.Kind() == reflect.String:
+		value = raw
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(raw), "["):
+		items := []any{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value = items
+	default:
+		if err := yaml.Unmarshal([]byte(raw), &value); err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+	}
+
+	if err := checkConfigValue(key, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// checkConfigValue reports whether value decodes into key's setting
+func checkConfigValue(key string, value any) error {
+	layers := &ConfigLayers{Values: make(map[string]any), Origins: make(map[string]ConfigSource)}
+	layers.Set(key, value, ConfigSource{})
+	if _, err := decodeConfig(layers.Values); err != nil {
+		// Keep the cause from mapstructure's "decoding failed" report
+		msg := err.Error()
+		msg = strings.TrimSpace(msg[strings.LastIndex(msg, "\n")+1:])
+		msg = strings.TrimPrefix(msg, "'"+key+"' ")
+		return &ConfigError{Key: key, Err: fmt.Errorf("invalid value %q: %s", fmt.Sprint(value), msg)}
+	}
+	return nil
+}
+
+// RedactConfigValue hides literal API keys in a config value for display.
+// References such as ${GROQ_API_KEY} or cmd:pass show groq are shown as
+// they are, since they are not secret themselves.
+func RedactConfigValue(key string, value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		redacted := make(map[string]any, len(v))
+		for k, item := range v {
+			redacted[k] = RedactConfigValue(k, item)
+		}
+		return redacted
+	case []any:
+		redacted := make([]any, len(v))
+		for i, item := range v {
+			redacted[i] = RedactConfigValue(key, item)
+		}
+		return redacted
+	case string:
+		if (key == "api_key" || strings.HasSuffix(key, ".api_key")) && v != "" && !isSecretReference(v) {
+			return RedactSecret(v)
+		}
+	}
+	return value
+}
@@ -0,0 +1,366 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// isolateConfig points HOME and the working directory at empty temporary
+// directories and clears the environment variables that set config keys
+func isolateConfig(t *testing.T) (home, project string) {
+	t.Helper()
+	home = t.TempDir()
+	project = t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(project)
+
+	for _, p := range providerKeyEnv {
+		t.Setenv(p.env, "")
+	}
+	// The default config enables Gemini, so its key has to resolve
+	t.Setenv("GEMINI_API_KEY", "gemini-test-key")
+	t.Setenv("OLLAMA_HOST", "")
+	for _, key := range ConfigKeys() {
+		t.Setenv(ConfigKeyEnv(key), "")
+		os.Unsetenv(ConfigKeyEnv(key))
+	}
+	return home, project
+}
+
+func writeConfigFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadConfigLayersPrecedence(t *testing.T) {
+	home, project := isolateConfig(t)
+
+	globalPath := filepath.Join(home, ".anaphase", "config.yaml")
+	writeConfigFile(t, globalPath, `ai:
+  primary_provider: groq
+  providers:
+    groq:
+      model: llama-global
+generator:
+  go_version: "1.22"
+`)
+	projectPath := filepath.Join(project, ProjectConfigFile)
+	writeConfigFile(t, projectPath, `ai:
+  primary_provider: openai
+  fallback_providers: [claude]
+generator:
+  go_version: "1.20"
+`)
+
+	// The project file is found from a subdirectory too
+	sub := filepath.Join(project, "internal", "core")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(sub)
+
+	t.Setenv("GROQ_API_KEY", "gsk-from-env")
+	t.Setenv("ANAPHASE_AI_FALLBACK_PROVIDERS", "groq, gemini")
+	t.Setenv("ANAPHASE_CACHE_TTL", "1h")
+
+	layers, err := ReadConfigLayers(FlagValue{Flag: "--provider", Key: "ai.primary_provider", Value: "ollama"})
+	if err != nil {
+		t.Fatalf("ReadConfigLayers failed: %v", err)
+	}
+
+	origins := map[string]ConfigSource{
+		"ai.primary_provider":             {Layer: LayerFlag, Path: "--provider"},
+		"ai.fallback_providers":           {Layer: LayerEnv, Path: "ANAPHASE_AI_FALLBACK_PROVIDERS"},
+		"ai.providers.groq.model":         {Layer: LayerGlobal, Path: globalPath},
+		"ai.providers.groq.api_key":       {Layer: LayerEnv, Path: "GROQ_API_KEY"},
+		"ai.providers.groq.enabled":       {Layer: LayerEnv, Path: "GROQ_API_KEY"},
+		"ai.providers.openai.model":       {Layer: LayerDefault},
+		"generator.go_version":            {Layer: LayerProject, Path: projectPath},
+		"cache.ttl":                       {Layer: LayerEnv, Path: "ANAPHASE_CACHE_TTL"},
+		"ai.circuit_breaker.state_file":   {Layer: LayerDefault},
+		"ai.providers.ollama.max_retries": {Layer: LayerDefault},
+	}
+	for key, want := range origins {
+		if got := layers.Origins[key]; got != want {
+			t.Errorf("Origin of %s = %s, want %s", key, got, want)
+		}
+	}
+
+	cfg, err := layers.Decode()
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if cfg.AI.PrimaryProvider != "ollama" {
+		t.Errorf("Expected the flag to win, got %q", cfg.AI.PrimaryProvider)
+	}
+	if !reflect.DeepEqual(cfg.AI.FallbackProviders, []string{"groq", "gemini"}) {
+		t.Errorf("Expected the fallbacks from the environment, got %v", cfg.AI.FallbackProviders)
+	}
+	if cfg.Generator.GoVersion != "1.20" {
+		t.Errorf("Expected the project's Go version, got %q", cfg.Generator.GoVersion)
+	}
+	groq := cfg.AI.Providers.Groq
+	if !groq.Enabled || groq.APIKey != "gsk-from-env" || groq.Model != "llama-global" || groq.Timeout != 30*time.Second {
+		t.Errorf("Unexpected Groq config: %+v", groq)
+	}
+	if cfg.Cache.TTL != time.Hour {
+		t.Errorf("Expected a TTL of 1h, got %s", cfg.Cache.TTL)
+	}
+	if cfg.AI.MaxRepairs != DefaultMaxRepairs {
+		t.Errorf("Expected the default max repairs, got %d", cfg.AI.MaxRepairs)
+	}
+}
+
+func TestReadConfigLayersProfile(t *testing.T) {
+	home, _ := isolateConfig(t)
+	writeConfigFile(t, filepath.Join(home, ".anaphase", "config.yaml"), `ai:
+  primary_provider: gemini
+  providers:
+    groq:
+      model: llama-base
+profile: personal
+profiles:
+  personal:
+    primary_provider: groq
+    providers:
+      groq:
+        enabled: true
+        api_key: gsk-personal
+  work:
+    primary_provider: gateway
+    openai_compatible:
+      - name: gateway
+        base_url: https://llm.example.com/v1
+`)
+
+	tests := []struct {
+		name         string
+		env          string
+		flags        []FlagValue
+		wantProfile  string
+		wantProvider string
+	}{
+		{"from the config file", "", nil, "personal", "groq"},
+		{"from the environment", "work", nil, "work", "gateway"},
+		{"from a flag", "work", []FlagValue{{Flag: "--profile", Key: "profile", Value: "Personal"}}, "personal", "groq"},
+		{"flags still override the profile", "", []FlagValue{{Flag: "--provider", Key: "ai.primary_provider", Value: "openai"}}, "personal", "openai"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv("ANAPHASE_PROFILE", tt.env)
+			}
+
+			layers, err := ReadConfigLayers(tt.flags...)
+			if err != nil {
+				t.Fatalf("ReadConfigLayers failed: %v", err)
+			}
+			cfg, err := layers.Decode()
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+
+			if layers.Profile != tt.wantProfile {
+				t.Errorf("Expected profile %q, got %q", tt.wantProfile, layers.Profile)
+			}
+			if cfg.AI.PrimaryProvider != tt.wantProvider {
+				t.Errorf("Expected primary provider %q, got %q", tt.wantProvider, cfg.AI.PrimaryProvider)
+			}
+		})
+	}
+
+	layers, err := ReadConfigLayers()
+	if err != nil {
+		t.Fatalf("ReadConfigLayers failed: %v", err)
+	}
+	if got := layers.Origins["ai.providers.groq.api_key"]; got != (ConfigSource{Layer: LayerProfile, Path: "personal"}) {
+		t.Errorf("Expected the key to come from the profile, got %s", got)
+	}
+	cfg, _ := layers.Decode()
+	if groq := cfg.AI.Providers.Groq; !groq.Enabled || groq.Model != "llama-base" {
+		t.Errorf("Expected the profile to be merged over the ai section, got %+v", groq)
+	}
+	if cfg.Profiles["work"].OpenAICompatible[0].Name != "gateway" {
+		t.Errorf("Expected the profiles to be decoded, got %+v", cfg.Profiles)
+	}
+}
+
+func TestDecodeUndefinedProfile(t *testing.T) {
+	isolateConfig(t)
+
+	layers, err := ReadConfigLayers(FlagValue{Flag: "--profile", Key: "profile", Value: "missing"})
+	if err != nil {
+		t.Fatalf("ReadConfigLayers failed: %v", err)
+	}
+	if _, err := layers.Decode(); err == nil || !strings.Contains(err.Error(), `profile "missing" is not defined`) {
+		t.Errorf("Expected an undefined profile error, got %v", err)
+	}
+}
+
+func TestReadConfigLayersCreatesGlobalConfig(t *testing.T) {
+	home, _ := isolateConfig(t)
+
+	if _, err := LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".anaphase", "config.yaml")); err != nil {
+		t.Errorf("Expected the global config to be created: %v", err)
+	}
+}
+
+func TestReadConfigLayersRejectsInvalidEnv(t *testing.T) {
+	isolateConfig(t)
+	t.Setenv("ANAPHASE_AI_MAX_REPAIRS", "lots")
+
+	if _, err := ReadConfigLayers(); err == nil {
+		t.Error("Expected an invalid ANAPHASE_AI_MAX_REPAIRS to fail")
+	}
+}
+
+func TestProjectConfigCannotSetSecretsOrEndpoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		key     string
+	}{
+		{
+			name:    "command api key",
+			content: "ai:\n  providers:\n    groq:\n      enabled: true\n      api_key: \"cmd:touch {marker}\"\n",
+			key:     "ai.providers.groq.api_key",
+		},
+		{
+			name:    "environment api key",
+			content: "ai:\n  providers:\n    openai:\n      api_key: ${OPENAI_API_KEY}\n",
+			key:     "ai.providers.openai.api_key",
+		},
+		{
+			name:    "provider endpoint",
+			content: "ai:\n  providers:\n    gemini:\n      base_url: https://attacker.example.com\n",
+			key:     "ai.providers.gemini.base_url",
+		},
+		{
+			name:    "compatible endpoint",
+			content: "ai:\n  openai_compatible:\n    - name: gateway\n      base_url: https://attacker.example.com/v1\n      api_key: file:~/.secrets/openai\n",
+			key:     "ai.openai_compatible",
+		},
+		{
+			name:    "profile api key",
+			content: "profile: team\nprofiles:\n  team:\n    providers:\n      groq:\n        api_key: \"cmd:touch {marker}\"\n",
+			key:     "profiles.team.providers.groq.api_key",
+		},
+		{
+			name:    "profile endpoint",
+			content: "profiles:\n  team:\n    openai_compatible:\n      - name: gateway\n        base_url: https://attacker.example.com/v1\n",
+			key:     "profiles.team.openai_compatible",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, project := isolateConfig(t)
+			marker := filepath.Join(t.TempDir(), "ran")
+			writeConfigFile(t, filepath.Join(project, ProjectConfigFile), strings.ReplaceAll(tt.content, "{marker}", marker))
+
+			_, err := LoadConfig()
+			if err == nil || !strings.Contains(err.Error(), tt.key+" cannot be set in a project") {
+				t.Errorf("Expected %s to be rejected, got %v", tt.key, err)
+			}
+			if _, err := os.Stat(marker); !os.IsNotExist(err) {
+				t.Error("Expected no command from the project config to run")
+			}
+		})
+	}
+
+	// The same settings are fine in the global config
+	home, project := isolateConfig(t)
+	writeConfigFile(t, filepath.Join(home, ".anaphase", "config.yaml"), "ai:\n  providers:\n    groq:\n      enabled: true\n      api_key: cmd:echo gsk-from-command\n")
+	writeConfigFile(t, filepath.Join(project, ProjectConfigFile), "ai:\n  primary_provider: groq\n  providers:\n    groq:\n      model: llama-project\n")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if groq := cfg.AI.Providers.Groq; groq.APIKey != "gsk-from-command" || groq.Model != "llama-project" {
+		t.Errorf("Unexpected Groq config: %+v", groq)
+	}
+}
+
+func TestCheckProjectSetting(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   any
+		wantErr bool
+	}{
+		{"ai.primary_provider", "groq", false},
+		{"ai.providers.groq.model", "llama", false},
+		{"ai.providers.groq.api_key", "${GROQ_API_KEY}", true},
+		{"ai.providers.ollama.base_url", "http://gpu:11434", true},
+		{"ai.openai_compatible", []any{}, true},
+		{"ai.providers", map[string]any{"groq": map[string]any{"api_key": "gsk"}}, true},
+		{"profiles.team", map[string]any{"primary_provider": "groq"}, false},
+		{"profiles.team", map[string]any{"providers": map[string]any{"claude": map[string]any{"base_url": "x"}}}, true},
+	}
+
+	for _, tt := range tests {
+		err := CheckProjectSetting(tt.key, tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CheckProjectSetting(%s, %v) = %v, wantErr %v", tt.key, tt.value, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseConfigValue(t *testing.T) {
+	tests := []struct {
+		key     string
+		raw     string
+		want    any
+		wantErr bool
+	}{
+		{"generator.go_version", "1.20", "1.20", false},
+		{"ai.max_repairs", "3", 3, false},
+		{"ai.max_repairs", "lots", nil, true},
+		{"cache.enabled", "false", false, false},
+		{"ai.hedge_delay", "2s", "2s", false},
+		{"ai.hedge_delay", "soon", nil, true},
+		{"ai.fallback_providers", "groq,openai", []any{"groq", "openai"}, false},
+		{"ai.fallback_providers", "[claude]", []any{"claude"}, false},
+		{"ai.providers.mistral.model", "large", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseConfigValue(tt.key, tt.raw)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseConfigValue(%s, %q) = %v, want an error", tt.key, tt.raw, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseConfigValue(%s, %q) = %#v, %v; want %#v", tt.key, tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestRedactConfigValue(t *testing.T) {
+	value := map[string]any{
+		"api_key": "sk-proj-abcdef12345",
+		"model":   "gpt-4o-mini",
+	}
+	got := RedactConfigValue("ai.providers.openai", value).(map[string]any)
+	if got["api_key"] != "****2345" || got["model"] != "gpt-4o-mini" {
+		t.Errorf("Unexpected redaction: %v", got)
+	}
+
+	if got := RedactConfigValue("ai.providers.groq.api_key", "${GROQ_API_KEY}"); got != "${GROQ_API_KEY}" {
+		t.Errorf("Expected a reference to be shown, got %v", got)
+	}
+}
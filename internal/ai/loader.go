@@ -4,63 +4,129 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
-	"github.com/spf13/viper"
+	"github.com/go-viper/mapstructure/v2"
 )
 
-// LoadConfig loads AI configuration from file and environment
-func LoadConfig() (*Config, error) {
-	// Set config file path
-	homeDir, err := os.UserHomeDir()
+// LoadConfig loads the configuration from every layer (see
+// ReadConfigLayers), with flags applied on top
+func LoadConfig(flags ...FlagValue) (*Config, error) {
+	layers, err := ReadConfigLayers(flags...)
 	if err != nil {
-		return nil, fmt.Errorf("get home directory: %w", err)
+		return nil, err
+	}
+	return layers.Decode()
+}
+
+// decodeWithYAMLTags makes viper match config keys against the yaml struct tags
+func decodeWithYAMLTags(dc *mapstructure.DecoderConfig) {
+	dc.TagName = "yaml"
+}
+
+func createDefaultConfig(configDir, configFile string) error {
+	// Create config directory
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return fmt.Errorf("create config directory: %w", err)
+	}
+
+	// Write config file
+	if err := os.WriteFile(configFile, []byte(defaultConfigYAML), 0644); err != nil {
+		return fmt.Errorf("write config file: %w", err)
 	}
 
-	configDir := filepath.Join(homeDir, ".anaphase")
-	configFile := filepath.Join(configDir, "config.yaml")
+	return nil
+}
 
-	// Create default config if not exists
-	if _, err := os.Stat(configFile); os.IsNotExist(err) {
-		if err := createDefaultConfig(configDir, configFile); err != nil {
-			return nil, fmt.Errorf("create default config: %w", err)
+// applyDefaults fills in settings that are empty after decoding and expands
+// ~/ in paths
+func applyDefaults(config *Config) {
+	// Parse durations (viper doesn't auto-parse to time.Duration from env)
+	if config.AI.Providers.Gemini.Timeout == 0 {
+		config.AI.Providers.Gemini.Timeout = 30 * time.Second
+	}
+	if config.AI.Providers.Groq.Timeout == 0 {
+		config.AI.Providers.Groq.Timeout = 30 * time.Second
+	}
+	if config.AI.Providers.OpenAI.Timeout == 0 {
+		config.AI.Providers.OpenAI.Timeout = 30 * time.Second
+	}
+	if config.AI.Providers.Claude.Timeout == 0 {
+		config.AI.Providers.Claude.Timeout = 45 * time.Second
+	}
+	if config.AI.Providers.Ollama.Timeout == 0 {
+		config.AI.Providers.Ollama.Timeout = 60 * time.Second
+	}
+
+	for i := range config.AI.OpenAICompatible {
+		if config.AI.OpenAICompatible[i].Timeout == 0 {
+			config.AI.OpenAICompatible[i].Timeout = 60 * time.Second
 		}
 	}
 
-	// Load config
-	v := viper.New()
-	v.SetConfigFile(configFile)
-	v.SetConfigType("yaml")
+	// Set default primary provider if not set
+	if config.AI.PrimaryProvider == "" {
+		config.AI.PrimaryProvider = "gemini"
+	}
 
-	// Read environment variables
-	v.AutomaticEnv()
-	v.SetEnvPrefix("ANAPHASE")
+	// Set default fallback chain if empty
+	if len(config.AI.FallbackProviders) == 0 {
+		config.AI.FallbackProviders = []string{"groq", "openai"}
+	}
 
-	// Read config file
-	if err := v.ReadInConfig(); err != nil {
-		return nil, fmt.Errorf("read config: %w", err)
+	// Parse cache TTL
+	if config.Cache.TTL == 0 {
+		config.Cache.TTL = 24 * time.Hour
 	}
 
-	// Unmarshal config
-	var config Config
-	if err := v.Unmarshal(&config); err != nil {
-		return nil, fmt.Errorf("unmarshal config: %w", err)
+	// Wait briefly for rate-limited providers before falling back
+	if config.AI.MaxRateLimitWait == 0 {
+		config.AI.MaxRateLimitWait = DefaultMaxRateLimitWait
 	}
 
-	// Override with environment variables
-	overrideWithEnv(&config)
+	// Persist circuit breaker state next to the config by default
+	if config.AI.CircuitBreaker.StateFile == "" {
+		config.AI.CircuitBreaker.StateFile = "~/.anaphase/health.json"
+	}
+	if strings.HasPrefix(config.AI.CircuitBreaker.StateFile, "~/") {
+		homeDir, _ := os.UserHomeDir()
+		config.AI.CircuitBreaker.StateFile = filepath.Join(homeDir, config.AI.CircuitBreaker.StateFile[2:])
+	}
 
-	return &config, nil
-}
+	// Fixtures live in the project so they can be committed
+	if config.AI.Fixtures.Directory == "" {
+		config.AI.Fixtures.Directory = ".anaphase/fixtures"
+	}
+	if strings.HasPrefix(config.AI.Fixtures.Directory, "~/") {
+		homeDir, _ := os.UserHomeDir()
+		config.AI.Fixtures.Directory = filepath.Join(homeDir, config.AI.Fixtures.Directory[2:])
+	}
 
-func createDefaultConfig(configDir, configFile string) error {
-	// Create config directory
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return fmt.Errorf("create config directory: %w", err)
+	// Record usage next to the config by default
+	if config.Usage.File == "" {
+		config.Usage.File = "~/.anaphase/usage.jsonl"
+	}
+	if strings.HasPrefix(config.Usage.File, "~/") {
+		homeDir, _ := os.UserHomeDir()
+		config.Usage.File = filepath.Join(homeDir, config.Usage.File[2:])
 	}
 
-	// Default configuration
-	defaultConfig := `# Anaphase CLI Configuration
+	// Expand home directory in cache path
+	if config.Cache.Directory == "" {
+		config.Cache.Directory = "~/.anaphase/cache"
+	}
+	if config.Cache.Directory != "" {
+		if config.Cache.Directory[:2] == "~/" {
+			homeDir, _ := os.UserHomeDir()
+			config.Cache.Directory = filepath.Join(homeDir, config.Cache.Directory[2:])
+		}
+	}
+}
+
+// defaultConfigYAML is written as the global config on first run and is the
+// built-in defaults layer every other layer overrides
+const defaultConfigYAML = `# Anaphase CLI Configuration
 version: "1.0"
 
 # AI Provider Configuration
@@ -73,7 +139,40 @@ ai:
     - groq
     - openai
 
-  # Provider-specific settings
+  # How many times invalid output (bad JSON or Go signatures) is sent back
+  # to the provider for repair. Set to 0 to fail immediately.
+  max_repairs: 2
+
+  # Skip a provider after repeated failures and retry it after a cooldown
+  circuit_breaker:
+    failure_threshold: 3
+    cooldown: 2m
+    window: 20
+    state_file: ~/.anaphase/health.json
+
+  # Wait this long at most for a rate-limited provider (Retry-After or the
+  # client-side limits below) before falling back to the next provider
+  max_rate_limit_wait: 15s
+
+  # Send the request to the next provider as well when the current one has
+  # not answered within this delay; the first valid response wins (0 = off)
+  hedge_delay: 0s
+
+  # Use other providers for some tasks instead of the chain above. Entries
+  # are a provider or provider/model; tasks are domain, refine and health.
+  # routing:
+  #   domain: [openai/gpt-4o, groq]
+  #   health: [groq/llama-3.1-8b-instant]
+
+  # Record responses as fixtures, then replay them without API keys by
+  # setting primary_provider to replay (or using --provider replay)
+  fixtures:
+    directory: .anaphase/fixtures
+    record: false
+
+  # Provider-specific settings. api_key accepts ${ENV_VAR}, file:<path> or
+  # cmd:<command> (e.g. cmd:pass show groq). Setting a provider's environment
+  # variable (GEMINI_API_KEY, GROQ_API_KEY, ...) also enables it.
   providers:
     gemini:
       enabled: true
@@ -90,6 +189,9 @@ ai:
       model: llama-3.3-70b-versatile
       timeout: 30s
       max_retries: 3
+      # Client-side rate limits, e.g. to stay within the free tier (0 = unlimited)
+      # requests_per_minute: 30
+      # tokens_per_minute: 12000
 
     openai:
       enabled: false
@@ -114,12 +216,57 @@ ai:
       timeout: 60s
       max_retries: 1
 
+  # Any number of OpenAI-compatible endpoints (vLLM, LM Studio, LiteLLM, gateways).
+  # Each entry can be referenced by name in primary_provider/fallback_providers.
+  # openai_compatible:
+  #   - name: lmstudio
+  #     base_url: http://localhost:1234/v1
+  #     model: qwen2.5-coder-7b-instruct
+  #     timeout: 60s
+  #     max_retries: 1
+  #   - name: gateway
+  #     base_url: https://llm.internal.example.com/v1
+  #     api_key: ${GATEWAY_API_KEY}
+  #     model: gpt-4o-mini
+  #     headers:
+  #       X-Team: platform
+
+# Named profiles override the ai section above with the settings they
+# list. Pick one with "anaphase config profile use <name>", --profile or
+# ANAPHASE_PROFILE.
+# profile: personal
+# profiles:
+#   personal:
+#     primary_provider: groq
+#     providers:
+#       groq:
+#         enabled: true
+#         api_key: ${GROQ_API_KEY}
+#   work:
+#     primary_provider: gateway
+#     fallback_providers: [openai]
+#     openai_compatible:
+#       - name: gateway
+#         base_url: https://llm.internal.example.com/v1
+#         api_key: ${GATEWAY_API_KEY}
+#         model: gpt-4o-mini
+
 # Cache Configuration
 cache:
   enabled: true
   directory: ~/.anaphase/cache
   ttl: 24h
   max_size: 100MB
+  # Entries are keyed by provider and model. Set to true to also reuse a
+  # response to the same prompt that was cached by another provider.
+  any_provider: false
+
+# Usage Ledger
+usage:
+  file: ~/.anaphase/usage.jsonl
+  # Spending caps in USD; requests estimated to exceed them are refused (0 = no cap)
+  daily_limit: 0
+  monthly_limit: 0
 
 # Generator Settings
 generator:
@@ -127,77 +274,3 @@ generator:
   go_version: "1.22"
   code_style: gofmt
 `
-
-	// Write config file
-	if err := os.WriteFile(configFile, []byte(defaultConfig), 0644); err != nil {
-		return fmt.Errorf("write config file: %w", err)
-	}
-
-	return nil
-}
-
-func overrideWithEnv(config *Config) {
-	// Gemini
-	if key := os.Getenv("GEMINI_API_KEY"); key != "" {
-		config.AI.Providers.Gemini.APIKey = key
-		config.AI.Providers.Gemini.Enabled = true
-	}
-
-	// Groq
-	if key := os.Getenv("GROQ_API_KEY"); key != "" {
-		config.AI.Providers.Groq.APIKey = key
-		config.AI.Providers.Groq.Enabled = true
-	}
-
-	// OpenAI
-	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
-		config.AI.Providers.OpenAI.APIKey = key
-		config.AI.Providers.OpenAI.Enabled = true
-	}
-
-	// Claude
-	if key := os.Getenv("CLAUDE_API_KEY"); key != "" {
-		config.AI.Providers.Claude.APIKey = key
-		config.AI.Providers.Claude.Enabled = true
-	}
-
-	// Parse durations (viper doesn't auto-parse to time.Duration from env)
-	if config.AI.Providers.Gemini.Timeout == 0 {
-		config.AI.Providers.Gemini.Timeout = 30 * time.Second
-	}
-	if config.AI.Providers.Groq.Timeout == 0 {
-		config.AI.Providers.Groq.Timeout = 30 * time.Second
-	}
-	if config.AI.Providers.OpenAI.Timeout == 0 {
-		config.AI.Providers.OpenAI.Timeout = 30 * time.Second
-	}
-	if config.AI.Providers.Claude.Timeout == 0 {
-		config.AI.Providers.Claude.Timeout = 45 * time.Second
-	}
-	if config.AI.Providers.Ollama.Timeout == 0 {
-		config.AI.Providers.Ollama.Timeout = 60 * time.Second
-	}
-
-	// Set default primary provider if not set
-	if config.AI.PrimaryProvider == "" {
-		config.AI.PrimaryProvider = "gemini"
-	}
-
-	// Set default fallback chain if empty
-	if len(config.AI.FallbackProviders) == 0 {
-		config.AI.FallbackProviders = []string{"groq", "openai"}
-	}
-
-	// Parse cache TTL
-	if config.Cache.TTL == 0 {
-		config.Cache.TTL = 24 * time.Hour
-	}
-
-	// Expand home directory in cache path
-	if config.Cache.Directory != "" {
-		if config.Cache.Directory[:2] == "~/" {
-			homeDir, _ := os.UserHomeDir()
-			config.Cache.Directory = filepath.Join(homeDir, config.Cache.Directory[2:])
-		}
-	}
-}
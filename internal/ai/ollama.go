@@ -0,0 +1,315 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OllamaProvider implements the Provider interface for a local Ollama server
+type OllamaProvider struct {
+	model   string
+	timeout time.Duration
+	retries int
+	baseURL string
+	stream  *http.Client // Shared by streaming requests, keeping their connections pooled
+}
+
+// NewOllamaProvider creates a new Ollama provider. No API key is needed,
+// the server is expected to run locally or on the internal network.
+func NewOllamaProvider(model, baseURL string, timeout time.Duration, retries int) *OllamaProvider {
+	if model == "" {
+		model = "qwen2.5-coder:7b" // Default to Qwen 2.5 Coder 7B (good code model that fits on a laptop)
+	}
+
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+
+	return &OllamaProvider{
+		model:   model,
+		timeout: timeout,
+		retries: retries,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		stream:  streamingClient(timeout),
+	}
+}
+
+func (o *OllamaProvider) Name() string {
+	return "ollama"
+}
+
+func (o *OllamaProvider) Model() string {
+	return o.model
+}
+
+// ollamaRequest represents a request to the Ollama chat API
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   *JSONSchema     `json:"format,omitempty"` // Structured output schema
+	Options  ollamaOptions   `json:"options"`
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaOptions struct {
+	Temperature float64 `json:"temperature"`
+	TopP        float64 `json:"top_p,omitempty"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+}
+
+// ollamaResponse represents a response from the Ollama chat API
+type ollamaResponse struct {
+	Model           string        `json:"model"`
+	CreatedAt       string        `json:"created_at"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+}
+
+type ollamaErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// ollamaTagsResponse lists the models available on the server
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name  string `json:"name"`
+		Model string `json:"model"`
+	} `json:"models"`
+}
+
+func (o *OllamaProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	startTime := time.Now()
+	ollamaReq := o.buildRequest(req, false)
+
+	// Retry logic
+	var lastErr error
+	for attempt := 0; attempt <= o.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(retryBackoff(attempt)):
+			}
+		}
+
+		resp, err := o.makeRequest(ctx, ollamaReq)
+		if err == nil {
+			return o.parseResponse(resp, startTime), nil
+		}
+
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("ollama request failed after %d retries: %w", o.retries, lastErr)
+}
+
+// GenerateStream streams the reply as newline-delimited JSON objects
+func (o *OllamaProvider) GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan StreamChunk, error) {
+	startTime := time.Now()
+
+	httpResp, err := openStream(ctx, "ollama", o.baseURL+"/api/chat", nil, o.buildRequest(req, true), o.stream, o.retries)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamChunk)
+	go o.readStream(ctx, httpResp.Body, out, startTime)
+
+	return out, nil
+}
+
+func (o *OllamaProvider) readStream(ctx context.Context, body io.ReadCloser, out chan<- StreamChunk, startTime time.Time) {
+	defer close(out)
+	defer body.Close()
+
+	var content strings.Builder
+	scanner := scanLines(body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var chunk ollamaResponse
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			sendChunk(ctx, out, StreamChunk{Err: fmt.Errorf("unmarshal stream chunk: %w", err)})
+			return
+		}
+
+		if chunk.Message.Content != "" {
+			content.WriteString(chunk.Message.Content)
+			if !sendChunk(ctx, out, StreamChunk{Content: chunk.Message.Content}) {
+				return
+			}
+		}
+
+		if chunk.Done {
+			// The final object carries token counts but no content
+			chunk.Message.Content = content.String()
+			sendChunk(ctx, out, StreamChunk{Done: true, Response: o.parseResponse(&chunk, startTime)})
+			return
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		sendChunk(ctx, out, StreamChunk{Err: fmt.Errorf("read stream: %w", err)})
+		return
+	}
+
+	sendChunk(ctx, out, StreamChunk{Err: fmt.Errorf("ollama stream ended before completion")})
+}
+
+func (o *OllamaProvider) buildRequest(req *GenerateRequest, stream bool) ollamaRequest {
+	// Ollama's chat API uses the same roles
+	var messages []ollamaMessage
+	for _, m := range req.Conversation() {
+		messages = append(messages, ollamaMessage{Role: m.Role, Content: m.Content})
+	}
+
+	return ollamaRequest{
+		Model:    o.model,
+		Messages: messages,
+		Stream:   stream,
+		Format:   req.ResponseSchema,
+		Options: ollamaOptions{
+			Temperature: req.Temperature,
+			TopP:        req.TopP,
+			NumPredict:  req.MaxTokens,
+		},
+	}
+}
+
+func (o *OllamaProvider) makeRequest(ctx context.Context, req ollamaRequest) (*ollamaResponse, error) {
+	// Marshal request
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	// Create HTTP request
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	// Make request with timeout
+	client := &http.Client{Timeout: o.timeout}
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("http request: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	// Read response body
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	// Check for errors
+	if httpResp.StatusCode != http.StatusOK {
+		var errResp ollamaErrorResponse
+		if err := json.Unmarshal(respBody, &errResp); err == nil && errResp.Error != "" {
+			return nil, fmt.Errorf("ollama API error (%d): %s", httpResp.StatusCode, errResp.Error)
+		}
+		return nil, fmt.Errorf("ollama API error (%d): %s", httpResp.StatusCode, string(respBody))
+	}
+
+	// Parse response
+	var resp ollamaResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("unmarshal response: %w", err)
+	}
+
+	return &resp, nil
+}
+
+func (o *OllamaProvider) parseResponse(resp *ollamaResponse, startTime time.Time) *GenerateResponse {
+	finishReason := resp.DoneReason
+	if finishReason == "" {
+		finishReason = "stop"
+	}
+
+	model := resp.Model
+	if model == "" {
+		model = o.model
+	}
+
+	return &GenerateResponse{
+		Content:  resp.Message.Content,
+		Provider: "ollama",
+		Model:    model,
+		Duration: time.Since(startTime),
+		TokensUsed: TokenUsage{
+			PromptTokens:     resp.PromptEvalCount,
+			CompletionTokens: resp.EvalCount,
+			TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+		},
+		Cost:         0.0, // Local models are free
+		FinishReason: finishReason,
+		CacheHit:     false,
+	}
+}
+
+func (o *OllamaProvider) Validate() error {
+	if o.baseURL == "" {
+		return fmt.Errorf("ollama base URL is required")
+	}
+	if o.model == "" {
+		return fmt.Errorf("ollama model is required")
+	}
+	return nil
+}
+
+// Health checks that the server is reachable and the model has been pulled.
+// It avoids a generation round-trip, which can take a while on CPU-only machines.
+func (o *OllamaProvider) Health(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", o.baseURL+"/api/tags", nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	client := &http.Client{Timeout: o.timeout}
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("ollama server not reachable at %s: %w", o.baseURL, err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ollama API error (%d)", httpResp.StatusCode)
+	}
+
+	var tags ollamaTagsResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&tags); err != nil {
+		return fmt.Errorf("unmarshal response: %w", err)
+	}
+
+	for _, m := range tags.Models {
+		if m.Name == o.model || m.Model == o.model || m.Name == o.model+":latest" {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("model %s not found on ollama server (run: ollama pull %s)", o.model, o.model)
+}
+
+func (o *OllamaProvider) EstimateCost(req *GenerateRequest) (float64, error) {
+	// Local models are free
+	return 0.0, nil
+}
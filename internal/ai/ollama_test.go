@@ -0,0 +1,111 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newOllamaTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/chat":
+			if r.Header.Get("Authorization") != "" {
+				t.Errorf("Ollama requests should not carry credentials")
+			}
+
+			var req ollamaRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("decode request: %v", err)
+			}
+			if req.Stream {
+				t.Errorf("Expected non-streaming request")
+			}
+			if req.Options.NumPredict != 64 {
+				t.Errorf("Expected num_predict 64, got %d", req.Options.NumPredict)
+			}
+
+			w.Write([]byte(`{
+				"model": "qwen2.5-coder:7b",
+				"message": {"role": "assistant", "content": "OK"},
+				"done": true,
+				"done_reason": "stop",
+				"prompt_eval_count": 12,
+				"eval_count": 3
+			}`))
+		case "/api/tags":
+			w.Write([]byte(`{"models": [{"name": "qwen2.5-coder:7b", "model": "qwen2.5-coder:7b"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestOllamaProviderGenerate(t *testing.T) {
+	server := newOllamaTestServer(t)
+	defer server.Close()
+
+	provider := NewOllamaProvider("", server.URL, 5*time.Second, 0)
+	if err := provider.Validate(); err != nil {
+		t.Fatalf("Validate should pass without an API key: %v", err)
+	}
+
+	resp, err := provider.Generate(context.Background(), &GenerateRequest{
+		SystemPrompt: "You are a test assistant",
+		UserPrompt:   "Hello",
+		MaxTokens:    64,
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if resp.Content != "OK" {
+		t.Errorf("Expected content 'OK', got %q", resp.Content)
+	}
+	if resp.Cost != 0 {
+		t.Errorf("Expected zero cost, got %f", resp.Cost)
+	}
+	if resp.TokensUsed.TotalTokens != 15 {
+		t.Errorf("Expected total tokens 15, got %d", resp.TokensUsed.TotalTokens)
+	}
+}
+
+func TestOllamaProviderHealth(t *testing.T) {
+	server := newOllamaTestServer(t)
+	defer server.Close()
+
+	if err := NewOllamaProvider("qwen2.5-coder:7b", server.URL, 5*time.Second, 0).Health(context.Background()); err != nil {
+		t.Errorf("Expected healthy provider, got %v", err)
+	}
+
+	if err := NewOllamaProvider("codellama", server.URL, 5*time.Second, 0).Health(context.Background()); err == nil {
+		t.Error("Expected error for a model that has not been pulled")
+	}
+}
+
+func TestNewOrchestratorWithOllamaOnly(t *testing.T) {
+	cfg := &Config{
+		AI: AIConfig{
+			PrimaryProvider: "ollama",
+			Providers: ProvidersConfig{
+				Ollama: ProviderConfig{Enabled: true},
+			},
+		},
+	}
+
+	orchestrator, err := NewOrchestrator(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("Expected keyless ollama to be enough, got %v", err)
+	}
+
+	if _, ok := orchestrator.providers["ollama"]; !ok {
+		t.Error("Expected ollama provider to be registered")
+	}
+}
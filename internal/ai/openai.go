@@ -0,0 +1,336 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAIProvider implements the Provider interface for OpenAI API
+type OpenAIProvider struct {
+	apiKey  string
+	model   string
+	timeout time.Duration
+	retries int
+	baseURL string
+	stream  *http.Client // Shared by streaming requests, keeping their connections pooled
+}
+
+// openAIPricing holds per-model pricing in USD per 1M tokens (input, output)
+var openAIPricing = map[string][2]float64{
+	"gpt-4o":        {2.50, 10.00},
+	"gpt-4o-mini":   {0.15, 0.60},
+	"gpt-4.1":       {2.00, 8.00},
+	"gpt-4.1-mini":  {0.40, 1.60},
+	"gpt-4.1-nano":  {0.10, 0.40},
+	"gpt-4-turbo":   {10.00, 30.00},
+	"gpt-3.5-turbo": {0.50, 1.50},
+}
+
+// NewOpenAIProvider creates a new OpenAI provider
+func NewOpenAIProvider(apiKey, model, baseURL string, timeout time.Duration, retries int) *OpenAIProvider {
+	if model == "" {
+		model = "gpt-4o-mini" // Default to GPT-4o mini (cheap and capable)
+	}
+
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+
+	return &OpenAIProvider{
+		apiKey:  apiKey,
+		model:   model,
+		timeout: timeout,
+		retries: retries,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		stream:  streamingClient(timeout),
+	}
+}
+
+func (o *OpenAIProvider) Name() string {
+	return "openai"
+}
+
+func (o *OpenAIProvider) Model() string {
+	return o.model
+}
+
+// openAIRequest represents a request to the OpenAI chat completions API
+type openAIRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	Temperature float64         `json:"temperature"`
+	MaxTokens   int             `json:"max_tokens,omitempty"`
+	TopP        float64         `json:"top_p,omitempty"`
+	Stream      bool            `json:"stream"`
+
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+// openAIResponseFormat requests JSON output, optionally against a schema
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openAIJSONSchema struct {
+	Name   string      `json:"name"`
+	Strict bool        `json:"strict"`
+	Schema *JSONSchema `json:"schema"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// openAIResponse represents a response from the OpenAI chat completions API
+type openAIResponse struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index   int `json:"index"`
+		Message struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+type openAIErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+	} `json:"error"`
+}
+
+func (o *OpenAIProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	startTime := time.Now()
+	openAIReq := buildOpenAIRequest(o.model, req)
+
+	// Retry logic
+	var lastErr error
+	for attempt := 0; attempt <= o.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(retryBackoff(attempt)):
+			}
+		}
+
+		resp, err := o.makeRequest(ctx, openAIReq)
+		if err == nil {
+			return o.parseResponse(resp, startTime), nil
+		}
+
+		// Leave waiting out rate limits to the orchestrator
+		if isRateLimited(err) {
+			return nil, err
+		}
+
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("openai request failed after %d retries: %w", o.retries, lastErr)
+}
+
+// GenerateStream streams the completion as server-sent events
+func (o *OpenAIProvider) GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan StreamChunk, error) {
+	startTime := time.Now()
+
+	openAIReq := buildOpenAIRequest(o.model, req)
+	openAIReq.Stream = true
+	openAIReq.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+
+	headers := map[string]string{"Authorization": "Bearer " + o.apiKey}
+	httpResp, err := openStream(ctx, "openai", o.baseURL+"/chat/completions", headers, openAIReq, o.stream, o.retries)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamChunk)
+	final := &GenerateResponse{Provider: "openai", Model: o.model}
+	go readOpenAIStream(ctx, httpResp.Body, out, final, startTime, o.calculateCost)
+
+	return out, nil
+}
+
+// buildOpenAIRequest converts a generation request into a chat completions payload
+func buildOpenAIRequest(model string, req *GenerateRequest) openAIRequest {
+	// Roles map one to one onto chat completion messages
+	var messages []openAIMessage
+	for _, m := range req.Conversation() {
+		messages = append(messages, openAIMessage{Role: m.Role, Content: m.Content})
+	}
+
+	openAIReq := openAIRequest{
+		Model:       model,
+		Messages:    messages,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		TopP:        req.TopP,
+		Stream:      false,
+	}
+
+	// Structured outputs guarantee the reply matches the schema
+	if req.ResponseSchema != nil {
+		openAIReq.ResponseFormat = &openAIResponseFormat{
+			Type: "json_schema",
+			JSONSchema: &openAIJSONSchema{
+				Name:   req.ResponseSchema.Name,
+				Strict: true,
+				Schema: req.ResponseSchema.Strict(),
+			},
+		}
+	}
+
+	return openAIReq
+}
+
+func (o *OpenAIProvider) makeRequest(ctx context.Context, req openAIRequest) (*openAIResponse, error) {
+	// Marshal request
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	// Create HTTP request
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	// Set headers
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+o.apiKey)
+
+	// Make request with timeout
+	client := &http.Client{Timeout: o.timeout}
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("http request: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	// Read response body
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	// Check for errors
+	if httpResp.StatusCode != http.StatusOK {
+		var errResp openAIErrorResponse
+		if err := json.Unmarshal(respBody, &errResp); err == nil && errResp.Error.Message != "" {
+			return nil, apiError("openai", httpResp, errResp.Error.Message)
+		}
+		return nil, apiError("openai", httpResp, string(respBody))
+	}
+
+	// Parse response
+	var resp openAIResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("unmarshal response: %w", err)
+	}
+
+	return &resp, nil
+}
+
+func (o *OpenAIProvider) parseResponse(resp *openAIResponse, startTime time.Time) *GenerateResponse {
+	// Extract content
+	var content string
+	var finishReason string
+	if len(resp.Choices) > 0 {
+		content = resp.Choices[0].Message.Content
+		finishReason = resp.Choices[0].FinishReason
+	}
+
+	model := resp.Model
+	if model == "" {
+		model = o.model
+	}
+
+	return &GenerateResponse{
+		Content:  content,
+		Provider: "openai",
+		Model:    model,
+		Duration: time.Since(startTime),
+		TokensUsed: TokenUsage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		},
+		Cost:         o.calculateCost(resp.Usage.PromptTokens, resp.Usage.CompletionTokens),
+		FinishReason: finishReason,
+		CacheHit:     false,
+	}
+}
+
+// calculateCost converts token counts into USD using the model's price list
+func (o *OpenAIProvider) calculateCost(promptTokens, completionTokens int) float64 {
+	price, ok := openAIPricing[o.model]
+	if !ok {
+		// Dated snapshots (e.g. gpt-4o-2024-08-06) use the longest matching family
+		matched := ""
+		for name, p := range openAIPricing {
+			if strings.HasPrefix(o.model, name+"-") && len(name) > len(matched) {
+				matched, price, ok = name, p, true
+			}
+		}
+	}
+	if !ok {
+		return 0.0
+	}
+
+	inputCost := float64(promptTokens) * price[0] / 1_000_000
+	outputCost := float64(completionTokens) * price[1] / 1_000_000
+	return inputCost + outputCost
+}
+
+func (o *OpenAIProvider) Validate() error {
+	if o.apiKey == "" {
+		return fmt.Errorf("openai API key is required")
+	}
+	return nil
+}
+
+func (o *OpenAIProvider) Health(ctx context.Context) error {
+	req := &GenerateRequest{
+		SystemPrompt: "You are a test assistant.",
+		UserPrompt:   "Respond with 'OK'",
+		Temperature:  0.0,
+		MaxTokens:    10,
+		Metadata:     map[string]string{MetadataTask: TaskHealth},
+	}
+
+	_, err := o.Generate(ctx, req)
+	return err
+}
+
+func (o *OpenAIProvider) EstimateCost(req *GenerateRequest) (float64, error) {
+	// Rough estimation: ~4 characters per token for input, max tokens for output
+	estimatedInputTokens := req.promptLength() / 4
+	estimatedOutputTokens := req.MaxTokens
+
+	return o.calculateCost(estimatedInputTokens, estimatedOutputTokens), nil
+}
@@ -0,0 +1,218 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAICompatibleProvider implements the Provider interface for any endpoint
+// that speaks the OpenAI chat completions API (vLLM, LM Studio, LiteLLM,
+// internal gateways, ...). Each instance is registered under a user-defined name.
+type OpenAICompatibleProvider struct {
+	name    string
+	apiKey  string
+	model   string
+	timeout time.Duration
+	retries int
+	baseURL string
+	stream  *http.Client // Shared by streaming requests, keeping their connections pooled
+	headers map[string]string
+}
+
+// NewOpenAICompatibleProvider creates a provider from a config entry
+func NewOpenAICompatibleProvider(cfg OpenAICompatibleConfig) *OpenAICompatibleProvider {
+	return &OpenAICompatibleProvider{
+		name:    cfg.Name,
+		apiKey:  cfg.APIKey,
+		model:   cfg.Model,
+		timeout: cfg.Timeout,
+		retries: cfg.MaxRetries,
+		baseURL: strings.TrimSuffix(cfg.BaseURL, "/"),
+		stream:  streamingClient(cfg.Timeout),
+		headers: cfg.Headers,
+	}
+}
+
+func (p *OpenAICompatibleProvider) Name() string {
+	return p.name
+}
+
+func (p *OpenAICompatibleProvider) Model() string {
+	return p.model
+}
+
+func (p *OpenAICompatibleProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	startTime := time.Now()
+	compatReq := buildOpenAIRequest(p.model, req)
+
+	// Retry logic
+	var lastErr error
+	for attempt := 0; attempt <= p.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(retryBackoff(attempt)):
+			}
+		}
+
+		resp, err := p.makeRequest(ctx, compatReq)
+		if err == nil {
+			return p.parseResponse(resp, startTime), nil
+		}
+
+		// Leave waiting out rate limits to the orchestrator
+		if isRateLimited(err) {
+			return nil, err
+		}
+
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("%s request failed after %d retries: %w", p.name, p.retries, lastErr)
+}
+
+// GenerateStream streams the completion as server-sent events
+func (p *OpenAICompatibleProvider) GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan StreamChunk, error) {
+	startTime := time.Now()
+
+	compatReq := buildOpenAIRequest(p.model, req)
+	compatReq.Stream = true
+
+	headers := make(map[string]string, len(p.headers)+1)
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+	for key, value := range p.headers {
+		headers[key] = value
+	}
+
+	httpResp, err := openStream(ctx, p.name, p.baseURL+"/chat/completions", headers, compatReq, p.stream, p.retries)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamChunk)
+	final := &GenerateResponse{Provider: p.name, Model: p.model}
+	go readOpenAIStream(ctx, httpResp.Body, out, final, startTime, nil)
+
+	return out, nil
+}
+
+func (p *OpenAICompatibleProvider) makeRequest(ctx context.Context, req openAIRequest) (*openAIResponse, error) {
+	// Marshal request
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	// Create HTTP request
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	// Set headers; local servers often run without authentication
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	for key, value := range p.headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	// Make request with timeout
+	client := &http.Client{Timeout: p.timeout}
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("http request: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	// Read response body
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	// Check for errors
+	if httpResp.StatusCode != http.StatusOK {
+		var errResp openAIErrorResponse
+		if err := json.Unmarshal(respBody, &errResp); err == nil && errResp.Error.Message != "" {
+			return nil, apiError(p.name, httpResp, errResp.Error.Message)
+		}
+		return nil, apiError(p.name, httpResp, string(respBody))
+	}
+
+	// Parse response
+	var resp openAIResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("unmarshal response: %w", err)
+	}
+
+	return &resp, nil
+}
+
+func (p *OpenAICompatibleProvider) parseResponse(resp *openAIResponse, startTime time.Time) *GenerateResponse {
+	// Extract content
+	var content string
+	var finishReason string
+	if len(resp.Choices) > 0 {
+		content = resp.Choices[0].Message.Content
+		finishReason = resp.Choices[0].FinishReason
+	}
+
+	model := resp.Model
+	if model == "" {
+		model = p.model
+	}
+
+	return &GenerateResponse{
+		Content:  content,
+		Provider: p.name,
+		Model:    model,
+		Duration: time.Since(startTime),
+		TokensUsed: TokenUsage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		},
+		Cost:         0.0, // Pricing is unknown for arbitrary endpoints
+		FinishReason: finishReason,
+		CacheHit:     false,
+	}
+}
+
+func (p *OpenAICompatibleProvider) Validate() error {
+	if p.baseURL == "" {
+		return fmt.Errorf("%s base URL is required", p.name)
+	}
+	if p.model == "" {
+		return fmt.Errorf("%s model is required", p.name)
+	}
+	return nil
+}
+
+func (p *OpenAICompatibleProvider) Health(ctx context.Context) error {
+	req := &GenerateRequest{
+		SystemPrompt: "You are a test assistant.",
+		UserPrompt:   "Respond with 'OK'",
+		Temperature:  0.0,
+		MaxTokens:    10,
+		Metadata:     map[string]string{MetadataTask: TaskHealth},
+	}
+
+	_, err := p.Generate(ctx, req)
+	return err
+}
+
+func (p *OpenAICompatibleProvider) EstimateCost(req *GenerateRequest) (float64, error) {
+	// Pricing is unknown for arbitrary endpoints
+	return 0.0, nil
+}
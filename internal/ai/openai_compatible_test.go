@@ -0,0 +1,129 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpenAICompatibleProviderGenerate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Expected path /v1/chat/completions, got %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("Expected no auth header without an API key, got %q", got)
+		}
+		if got := r.Header.Get("X-Team"); got != "platform" {
+			t.Errorf("Expected custom header X-Team, got %q", got)
+		}
+
+		w.Write([]byte(`{
+			"choices": [{"message": {"content": "OK"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 5, "completion_tokens": 1, "total_tokens": 6}
+		}`))
+	}))
+	defer server.Close()
+
+	provider := NewOpenAICompatibleProvider(OpenAICompatibleConfig{
+		Name:    "vllm",
+		BaseURL: server.URL + "/v1/",
+		Model:   "qwen2.5-coder",
+		Headers: map[string]string{"X-Team": "platform"},
+		Timeout: 5 * time.Second,
+	})
+
+	resp, err := provider.Generate(context.Background(), &GenerateRequest{UserPrompt: "Hello"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if resp.Provider != "vllm" {
+		t.Errorf("Expected provider name 'vllm', got %q", resp.Provider)
+	}
+	if resp.Model != "qwen2.5-coder" {
+		t.Errorf("Expected configured model when response omits it, got %q", resp.Model)
+	}
+	if resp.Content != "OK" {
+		t.Errorf("Expected content 'OK', got %q", resp.Content)
+	}
+}
+
+func TestNewOrchestratorRejectsConflictingNames(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	cfg := &Config{AI: AIConfig{
+		OpenAICompatible: []OpenAICompatibleConfig{
+			{Name: "groq", BaseURL: "http://localhost", Model: "m"},
+		},
+	}}
+	if _, err := NewOrchestrator(cfg, logger); err == nil {
+		t.Error("Expected error when a custom provider reuses a built-in name")
+	}
+
+	cfg.AI.OpenAICompatible = []OpenAICompatibleConfig{
+		{Name: "gateway", BaseURL: "http://localhost", Model: "m"},
+		{Name: "gateway", BaseURL: "http://localhost", Model: "m"},
+	}
+	if _, err := NewOrchestrator(cfg, logger); err == nil {
+		t.Error("Expected error for duplicate custom provider names")
+	}
+}
+
+func TestLoadConfigOpenAICompatible(t *testing.T) {
+	home, _ := isolateConfig(t)
+
+	configYAML := `ai:
+  primary_provider: gateway
+  fallback_providers: [lmstudio]
+  openai_compatible:
+    - name: gateway
+      base_url: https://llm.example.com/v1
+      api_key: secret
+      model: gpt-4o-mini
+      headers:
+        X-Team: platform
+    - name: lmstudio
+      base_url: http://localhost:1234/v1
+      model: qwen
+      timeout: 10s
+`
+	if err := os.MkdirAll(filepath.Join(home, ".anaphase"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".anaphase", "config.yaml"), []byte(configYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.AI.PrimaryProvider != "gateway" {
+		t.Errorf("Expected primary provider 'gateway', got %q", cfg.AI.PrimaryProvider)
+	}
+	if len(cfg.AI.OpenAICompatible) != 2 {
+		t.Fatalf("Expected 2 openai_compatible entries, got %d", len(cfg.AI.OpenAICompatible))
+	}
+
+	gateway := cfg.AI.OpenAICompatible[0]
+	if gateway.APIKey != "secret" || gateway.BaseURL != "https://llm.example.com/v1" {
+		t.Errorf("Unexpected gateway config: %+v", gateway)
+	}
+	if gateway.Headers["x-team"] != "platform" {
+		t.Errorf("Expected header to be loaded, got %v", gateway.Headers)
+	}
+	if gateway.Timeout != 60*time.Second {
+		t.Errorf("Expected default timeout 60s, got %s", gateway.Timeout)
+	}
+	if cfg.AI.OpenAICompatible[1].Timeout != 10*time.Second {
+		t.Errorf("Expected timeout 10s, got %s", cfg.AI.OpenAICompatible[1].Timeout)
+	}
+}
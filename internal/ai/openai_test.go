@@ -0,0 +1,150 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOpenAIProviderGenerate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("Expected path /chat/completions, got %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Expected bearer auth header, got %q", got)
+		}
+
+		var req openAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if len(req.Messages) != 2 || req.Messages[0].Role != "system" || req.Messages[1].Role != "user" {
+			t.Errorf("Unexpected messages: %+v", req.Messages)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"model": "gpt-4o-mini",
+			"choices": [{"index": 0, "message": {"role": "assistant", "content": "OK"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 1000, "completion_tokens": 2000, "total_tokens": 3000}
+		}`))
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider("test-key", "gpt-4o-mini", server.URL, 5*time.Second, 0)
+	resp, err := provider.Generate(context.Background(), &GenerateRequest{
+		SystemPrompt: "You are a test assistant",
+		UserPrompt:   "Hello",
+		MaxTokens:    10,
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if resp.Content != "OK" {
+		t.Errorf("Expected content 'OK', got %q", resp.Content)
+	}
+	if resp.Provider != "openai" {
+		t.Errorf("Expected provider 'openai', got %q", resp.Provider)
+	}
+	if resp.TokensUsed.TotalTokens != 3000 {
+		t.Errorf("Expected total tokens 3000, got %d", resp.TokensUsed.TotalTokens)
+	}
+	if resp.FinishReason != "stop" {
+		t.Errorf("Expected finish reason 'stop', got %q", resp.FinishReason)
+	}
+
+	expectedCost := 1000*0.15/1_000_000 + 2000*0.60/1_000_000
+	if diff := resp.Cost - expectedCost; diff > 1e-12 || diff < -1e-12 {
+		t.Errorf("Expected cost %f, got %f", expectedCost, resp.Cost)
+	}
+}
+
+func TestBuildOpenAIRequestMessages(t *testing.T) {
+	req := buildOpenAIRequest("gpt-4o-mini", &GenerateRequest{
+		UserPrompt: "Generate an order domain",
+		Messages: []Message{
+			{Role: RoleAssistant, Content: `{"domain_name": "order",`},
+			{Role: RoleUser, Content: "Your response was rejected"},
+		},
+	})
+
+	want := []openAIMessage{
+		{Role: "user", Content: "Generate an order domain"},
+		{Role: "assistant", Content: `{"domain_name": "order",`},
+		{Role: "user", Content: "Your response was rejected"},
+	}
+	if !reflect.DeepEqual(req.Messages, want) {
+		t.Errorf("Expected messages %+v, got %+v", want, req.Messages)
+	}
+}
+
+func TestOpenAIProviderRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": {"message": "temporary failure"}}`))
+			return
+		}
+		w.Write([]byte(`{"model": "gpt-4o", "choices": [{"message": {"content": "OK"}, "finish_reason": "stop"}]}`))
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider("test-key", "gpt-4o", server.URL, 5*time.Second, 1)
+	if err := provider.Health(context.Background()); err != nil {
+		t.Fatalf("Health failed after retry: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("Expected 2 calls, got %d", got)
+	}
+}
+
+func TestOpenAIProviderAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": {"message": "invalid api key", "type": "invalid_request_error"}}`))
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider("bad-key", "", server.URL, 5*time.Second, 0)
+	_, err := provider.Generate(context.Background(), &GenerateRequest{UserPrompt: "Hello"})
+	if err == nil {
+		t.Fatal("Expected error for unauthorized response")
+	}
+}
+
+func TestOpenAIProviderEstimateCost(t *testing.T) {
+	tests := []struct {
+		name  string
+		model string
+		free  bool
+	}{
+		{"known model", "gpt-4o", false},
+		{"dated snapshot", "gpt-4o-mini-2024-07-18", false},
+		{"unknown model", "my-finetune", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewOpenAIProvider("key", tt.model, "", time.Second, 0)
+			cost, err := provider.EstimateCost(&GenerateRequest{UserPrompt: "Hello world", MaxTokens: 1000})
+			if err != nil {
+				t.Fatalf("EstimateCost failed: %v", err)
+			}
+			if tt.free && cost != 0 {
+				t.Errorf("Expected zero cost for %s, got %f", tt.model, cost)
+			}
+			if !tt.free && cost <= 0 {
+				t.Errorf("Expected positive cost for %s, got %f", tt.model, cost)
+			}
+		})
+	}
+}
@@ -2,8 +2,12 @@ package ai
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"slices"
+	"sync"
 	"time"
 )
 
@@ -12,58 +16,228 @@ type Orchestrator struct {
 	providers       map[string]Provider
 	primaryProvider string
 	fallbackChain   []string
+	routes          map[string][]string // Provider chains by task
 	cache           *Cache
+	health          *HealthTracker
 	logger          *slog.Logger
+	maxRepairs      int
+
+	limitMu          sync.Mutex
+	limiters         map[string]*RateLimiter
+	maxRateLimitWait time.Duration
+
+	usage  *UsageLedger
+	budget UsageConfig
+
+	hedgeDelay time.Duration
+
+	prompts *PromptLoader
 }
 
 // NewOrchestrator creates a new orchestrator
 func NewOrchestrator(cfg *Config, logger *slog.Logger) (*Orchestrator, error) {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	// A cached response would bypass the fixtures, so recording and
+	// replaying runs skip the cache
+	replaying := cfg.AI.PrimaryProvider == ReplayProviderName
+	cacheConfig := cfg.Cache
+	if cfg.AI.Fixtures.Record || replaying {
+		cacheConfig.Enabled = false
+	}
+
 	// Initialize cache
-	cache := NewCache(cfg.Cache.Directory, cfg.Cache.TTL, cfg.Cache.Enabled)
+	cache, err := NewCacheFromConfig(cacheConfig)
+	if err != nil {
+		return nil, err
+	}
+	cache.PruneInBackground(func(err error) {
+		logger.Warn("cache pruning failed", "error", err)
+	})
 
-	// Initialize providers
-	providerMap := make(map[string]Provider)
+	// Initialize providers. Each is built from a function of the model, so
+	// routing rules can ask for other models of the same provider.
+	factories := make(map[string]func(model string) Provider)
+	models := make(map[string]string)
+	p := cfg.AI.Providers
 
 	// Gemini
-	if cfg.AI.Providers.Gemini.Enabled && cfg.AI.Providers.Gemini.APIKey != "" {
-		providerMap["gemini"] = NewGeminiProvider(
-			cfg.AI.Providers.Gemini.APIKey,
-			cfg.AI.Providers.Gemini.Model,
-			cfg.AI.Providers.Gemini.Timeout,
-			cfg.AI.Providers.Gemini.MaxRetries,
-		)
+	if p.Gemini.Enabled && p.Gemini.APIKey != "" {
+		factories["gemini"] = func(model string) Provider {
+			return NewGeminiProvider(p.Gemini.APIKey, model, p.Gemini.Timeout, p.Gemini.MaxRetries)
+		}
+		models["gemini"] = p.Gemini.Model
 	}
 
 	// Groq
-	if cfg.AI.Providers.Groq.Enabled && cfg.AI.Providers.Groq.APIKey != "" {
-		providerMap["groq"] = NewGroqProvider(
-			cfg.AI.Providers.Groq.APIKey,
-			cfg.AI.Providers.Groq.Model,
-			cfg.AI.Providers.Groq.Timeout,
-			cfg.AI.Providers.Groq.MaxRetries,
-		)
+	if p.Groq.Enabled && p.Groq.APIKey != "" {
+		factories["groq"] = func(model string) Provider {
+			return NewGroqProvider(p.Groq.APIKey, model, p.Groq.BaseURL, p.Groq.Timeout, p.Groq.MaxRetries)
+		}
+		models["groq"] = p.Groq.Model
+	}
+
+	// OpenAI
+	if p.OpenAI.Enabled && p.OpenAI.APIKey != "" {
+		factories["openai"] = func(model string) Provider {
+			return NewOpenAIProvider(p.OpenAI.APIKey, model, p.OpenAI.BaseURL, p.OpenAI.Timeout, p.OpenAI.MaxRetries)
+		}
+		models["openai"] = p.OpenAI.Model
+	}
+
+	// Claude
+	if p.Claude.Enabled && p.Claude.APIKey != "" {
+		factories["claude"] = func(model string) Provider {
+			return NewClaudeProvider(p.Claude.APIKey, model, p.Claude.BaseURL, p.Claude.Timeout, p.Claude.MaxRetries)
+		}
+		models["claude"] = p.Claude.Model
+	}
+
+	// Ollama (local, no API key required)
+	if p.Ollama.Enabled {
+		factories["ollama"] = func(model string) Provider {
+			return NewOllamaProvider(model, p.Ollama.BaseURL, p.Ollama.Timeout, p.Ollama.MaxRetries)
+		}
+		models["ollama"] = p.Ollama.Model
+	}
+
+	// Client-side rate limits per provider
+	limiters := map[string]*RateLimiter{
+		"gemini": NewRateLimiter(p.Gemini.RequestsPerMinute, p.Gemini.TokensPerMinute),
+		"groq":   NewRateLimiter(p.Groq.RequestsPerMinute, p.Groq.TokensPerMinute),
+		"openai": NewRateLimiter(p.OpenAI.RequestsPerMinute, p.OpenAI.TokensPerMinute),
+		"claude": NewRateLimiter(p.Claude.RequestsPerMinute, p.Claude.TokensPerMinute),
+		"ollama": NewRateLimiter(p.Ollama.RequestsPerMinute, p.Ollama.TokensPerMinute),
 	}
 
-	// TODO: Add OpenAI provider
+	// User-defined OpenAI-compatible endpoints
+	for _, compat := range cfg.AI.OpenAICompatible {
+		if compat.Name == "" {
+			return nil, fmt.Errorf("openai_compatible provider is missing a name")
+		}
+		if _, exists := factories[compat.Name]; exists || isBuiltinProvider(compat.Name) {
+			return nil, fmt.Errorf("openai_compatible provider %q conflicts with an existing provider", compat.Name)
+		}
+		factories[compat.Name] = func(model string) Provider {
+			endpoint := compat
+			endpoint.Model = model
+			return NewOpenAICompatibleProvider(endpoint)
+		}
+		models[compat.Name] = compat.Model
+		limiters[compat.Name] = NewRateLimiter(compat.RequestsPerMinute, compat.TokensPerMinute)
+	}
+
+	providerMap := make(map[string]Provider)
+	for name, factory := range factories {
+		providerMap[name] = factory(models[name])
+	}
+
+	// A routing entry with a model gets a provider of its own, keyed by the
+	// entry, that shares the rate limits of its provider
+	routes := cfg.AI.Routing
+	for task, route := range routes {
+		for _, entry := range route {
+			name, model := splitRoute(entry)
+			factory, exists := factories[name]
+			if !exists {
+				logger.Warn("routing rule names a provider that is not configured",
+					"task", task,
+					"provider", name,
+				)
+				continue
+			}
+			if model != "" {
+				providerMap[entry] = factory(model)
+				limiters[entry] = limiters[name]
+			}
+		}
+	}
+
+	// Save every live response as a fixture for later replay
+	if cfg.AI.Fixtures.Record {
+		for name, provider := range providerMap {
+			providerMap[name] = NewRecordingProvider(provider, cfg.AI.Fixtures.Directory)
+		}
+	}
+
+	// Replay recorded fixtures when asked to, without falling back to live
+	// providers so that a missing fixture is never papered over
+	fallbackChain := cfg.AI.FallbackProviders
+	if replaying {
+		fallbackChain = nil
+		routes = nil
+	}
+	if replaying || slices.Contains(fallbackChain, ReplayProviderName) {
+		providerMap[ReplayProviderName] = NewReplayProvider(cfg.AI.Fixtures.Directory)
+	}
 
 	// Validate at least one provider is available
 	if len(providerMap) == 0 {
-		return nil, fmt.Errorf("no AI providers configured - please set at least one API key")
+		return nil, fmt.Errorf("no AI providers configured - please set at least one API key or enable ollama")
 	}
 
 	return &Orchestrator{
-		providers:       providerMap,
-		primaryProvider: cfg.AI.PrimaryProvider,
-		fallbackChain:   cfg.AI.FallbackProviders,
-		cache:           cache,
-		logger:          logger,
+		providers:        providerMap,
+		primaryProvider:  cfg.AI.PrimaryProvider,
+		fallbackChain:    fallbackChain,
+		routes:           routes,
+		cache:            cache,
+		health:           NewHealthTracker(cfg.AI.CircuitBreaker),
+		logger:           logger,
+		maxRepairs:       cfg.AI.MaxRepairs,
+		limiters:         limiters,
+		maxRateLimitWait: cfg.AI.MaxRateLimitWait,
+		usage:            NewUsageLedger(cfg.Usage.File),
+		budget:           cfg.Usage,
+		hedgeDelay:       cfg.AI.HedgeDelay,
+		prompts:          DefaultPromptLoader(),
 	}, nil
 }
 
+// BuiltinProviders lists the providers with first-class configuration blocks
+var BuiltinProviders = []string{"gemini", "groq", "openai", "claude", "ollama", ReplayProviderName}
+
+// isBuiltinProvider reports whether name is reserved by a built-in provider
+func isBuiltinProvider(name string) bool {
+	for _, p := range BuiltinProviders {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Generate attempts generation with fallback logic
 func (o *Orchestrator) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
-	// Check cache first
-	if cached, hit := o.cache.Get(req); hit {
+	var errs chainErrors
+	for _, providerName := range o.chain(req) {
+		resp, err := o.generateFrom(ctx, providerName, req, nil)
+		if err == nil {
+			return resp, nil
+		}
+		errs.add(err)
+	}
+
+	return nil, errs.err(o.health)
+}
+
+// generateFrom runs one provider of the chain: its cache, circuit breaker,
+// spending cap and rate limits are checked before the request is sent.
+// When accept is set, a response it rejects is treated as a failure and is
+// neither served from nor written to the cache.
+func (o *Orchestrator) generateFrom(ctx context.Context, providerName string, req *GenerateRequest, accept func(*GenerateResponse) error) (*GenerateResponse, error) {
+	provider, exists := o.providers[providerName]
+	if !exists {
+		o.logger.Warn("provider not available",
+			"provider", providerName,
+		)
+		return nil, errProviderUnavailable
+	}
+
+	// Check this provider's cache first
+	if cached, hit := o.cache.Get(providerName, provider.Model(), req); hit && (accept == nil || accept(cached) == nil) && cacheable(ctx, cached) {
 		o.logger.Info("cache hit",
 			"provider", cached.Provider,
 			"tokens", cached.TokensUsed.TotalTokens,
@@ -71,70 +245,513 @@ func (o *Orchestrator) Generate(ctx context.Context, req *GenerateRequest) (*Gen
 		return cached, nil
 	}
 
-	// Build provider chain (primary + fallbacks)
-	providerChain := []string{o.primaryProvider}
-	providerChain = append(providerChain, o.fallbackChain...)
+	// Skip providers whose circuit breaker is open
+	if !o.health.Allow(providerName) {
+		o.logger.Warn("circuit breaker open, skipping provider",
+			"provider", providerName,
+		)
+		return nil, &breakerSkipError{provider: providerName}
+	}
 
-	var lastErr error
-	for _, providerName := range providerChain {
-		provider, exists := o.providers[providerName]
-		if !exists {
-			o.logger.Warn("provider not available",
-				"provider", providerName,
-			)
-			continue
-		}
+	// Refuse requests that would exceed a spending cap
+	if err := o.checkBudget(provider, req); err != nil {
+		o.logger.Warn("spending cap reached, skipping provider",
+			"provider", providerName,
+			"error", err,
+		)
+		o.health.Release(providerName)
+		return nil, err
+	}
 
-		o.logger.Info("attempting generation",
+	o.logger.Info("attempting generation",
+		"provider", providerName,
+	)
+
+	startTime := time.Now()
+	resp, err := o.generateWithLimits(ctx, providerName, provider, req)
+
+	var rateErr *RateLimitError
+	if errors.As(err, &rateErr) {
+		// Rate limits say nothing about provider health, so fall back
+		// without counting a failure
+		o.logger.Warn("provider rate limited, falling back",
 			"provider", providerName,
+			"retry_after", rateErr.RetryAfter,
 		)
+		o.health.Release(providerName)
+		return nil, err
+	}
 
-		startTime := time.Now()
-		resp, err := provider.Generate(ctx, req)
+	if err != nil {
+		o.logger.Warn("provider failed",
+			"provider", providerName,
+			"error", err,
+			"duration", time.Since(startTime),
+		)
+		o.recordFailure(ctx, providerName, time.Since(startTime))
+		return nil, err
+	}
+	o.health.RecordSuccess(providerName, time.Since(startTime))
+	o.recordUsage(ctx, providerName, req, resp)
 
-		if err != nil {
-			o.logger.Warn("provider failed",
+	if accept != nil {
+		if err := accept(resp); err != nil {
+			o.logger.Warn("provider returned unusable output",
 				"provider", providerName,
 				"error", err,
-				"duration", time.Since(startTime),
 			)
-			lastErr = err
-			continue
+			return nil, fmt.Errorf("%s returned unusable output: %w", providerName, err)
+		}
+	}
+
+	// Success!
+	o.logger.Info("generation successful",
+		"provider", providerName,
+		"tokens", resp.TokensUsed.TotalTokens,
+		"cost", fmt.Sprintf("$%.6f", resp.Cost),
+		"duration", resp.Duration,
+	)
+
+	o.cacheResponse(ctx, providerName, provider, req, resp)
+	return resp, nil
+}
+
+// errProviderUnavailable marks a chain entry with no configured provider
+var errProviderUnavailable = errors.New("provider not available")
+
+// breakerSkipError marks a provider skipped because its breaker is open
+type breakerSkipError struct {
+	provider string
+}
+
+func (e *breakerSkipError) Error() string {
+	return fmt.Sprintf("%s skipped: circuit breaker open", e.provider)
+}
+
+// chainErrors collects why each provider in the chain did not produce a
+// response, to report the most useful error once all have been tried
+type chainErrors struct {
+	lastErr    error
+	skipped    []string
+	limited    []*RateLimitError
+	overBudget error
+	failed     bool
+}
+
+func (c *chainErrors) add(err error) {
+	var skipErr *breakerSkipError
+	var rateErr *RateLimitError
+	var budgetErr *BudgetExceededError
+	switch {
+	case errors.Is(err, errProviderUnavailable):
+	case errors.As(err, &skipErr):
+		c.skipped = append(c.skipped, skipErr.provider)
+	case errors.As(err, &budgetErr):
+		c.overBudget = err
+	case errors.As(err, &rateErr):
+		c.limited = append(c.limited, rateErr)
+		c.lastErr = err
+	default:
+		c.failed = true
+		c.lastErr = err
+	}
+}
+
+// err returns the error to report: rate limits or budgets when they were
+// the only reason, then open breakers, otherwise the last failure
+func (c *chainErrors) err(h *HealthTracker) error {
+	if !c.failed && len(c.limited) > 0 {
+		return rateLimitedError(c.limited)
+	}
+
+	if c.lastErr == nil && c.overBudget != nil {
+		return c.overBudget
+	}
+
+	if c.lastErr == nil && len(c.skipped) > 0 {
+		return breakerOpenError(c.skipped, h)
+	}
+
+	if c.lastErr == nil {
+		return errors.New("none of the providers for this request is configured")
+	}
+
+	return fmt.Errorf("all providers failed, last error: %w", c.lastErr)
+}
+
+// generateWithLimits sends the request once the provider's rate limiter
+// admits it. A rejection with a short Retry-After pauses the limiter and is
+// retried; longer delays are returned as a RateLimitError for fallback.
+func (o *Orchestrator) generateWithLimits(ctx context.Context, providerName string, provider Provider, req *GenerateRequest) (*GenerateResponse, error) {
+	tokens := estimateTokens(req)
+
+	for round := 1; ; round++ {
+		if err := o.awaitCapacity(ctx, providerName, tokens); err != nil {
+			return nil, err
+		}
+
+		resp, err := provider.Generate(ctx, req)
+
+		var rateErr *RateLimitError
+		if !errors.As(err, &rateErr) {
+			return resp, err
 		}
 
-		// Success!
-		o.logger.Info("generation successful",
+		o.pauseProvider(providerName, rateErr)
+		if round >= maxRateLimitRounds {
+			return nil, err
+		}
+	}
+}
+
+// awaitCapacity waits until the provider's rate limiter admits a request of
+// the given size. If the wait would exceed the configured maximum it returns
+// a RateLimitError instead, so the caller can try another provider.
+func (o *Orchestrator) awaitCapacity(ctx context.Context, providerName string, tokens int) error {
+	reservation := o.limiter(providerName).Reserve(tokens)
+
+	delay := reservation.Delay()
+	if delay > o.rateLimitWait() {
+		reservation.Cancel()
+		return &RateLimitError{
+			Provider:   providerName,
+			RetryAfter: delay,
+			Message:    "rate limit reached",
+		}
+	}
+
+	if delay > 0 {
+		o.logger.Info("waiting for rate limit",
 			"provider", providerName,
-			"tokens", resp.TokensUsed.TotalTokens,
-			"cost", fmt.Sprintf("$%.6f", resp.Cost),
-			"duration", resp.Duration,
+			"delay", delay,
+		)
+	}
+	return reservation.Wait(ctx)
+}
+
+// pauseProvider holds back requests to a provider that reported a rate limit
+func (o *Orchestrator) pauseProvider(providerName string, rateErr *RateLimitError) {
+	pause := rateErr.RetryAfter
+	if pause <= 0 {
+		pause = defaultRateLimitPause
+	}
+
+	o.logger.Warn("provider reported rate limit",
+		"provider", providerName,
+		"retry_after", pause,
+	)
+	o.limiter(providerName).PauseFor(pause)
+}
+
+// limiter returns the provider's rate limiter, creating an unlimited one for
+// providers without configured limits
+func (o *Orchestrator) limiter(providerName string) *RateLimiter {
+	o.limitMu.Lock()
+	defer o.limitMu.Unlock()
+
+	if o.limiters == nil {
+		o.limiters = make(map[string]*RateLimiter)
+	}
+
+	limiter, ok := o.limiters[providerName]
+	if !ok {
+		limiter = NewRateLimiter(0, 0)
+		o.limiters[providerName] = limiter
+	}
+
+	return limiter
+}
+
+// rateLimitWait returns the longest acceptable wait for a rate-limited provider
+func (o *Orchestrator) rateLimitWait() time.Duration {
+	if o.maxRateLimitWait <= 0 {
+		return DefaultMaxRateLimitWait
+	}
+	return o.maxRateLimitWait
+}
+
+// chain returns the providers to try for a request (see route), reordered
+// so that unhealthy providers are tried last
+func (o *Orchestrator) chain(req *GenerateRequest) []string {
+	providerChain := o.route(req)
+
+	ordered := o.health.Order(providerChain)
+	if ordered[0] != providerChain[0] {
+		o.logger.Info("reordered provider chain by health",
+			"chain", ordered,
 		)
+	}
+
+	return ordered
+}
+
+// checkBudget refuses a request whose estimated cost on provider would
+// push spending past the daily or monthly cap
+func (o *Orchestrator) checkBudget(provider Provider, req *GenerateRequest) error {
+	if o.usage == nil {
+		return nil
+	}
+
+	// A request that cannot be priced is not held back
+	estimate, err := provider.EstimateCost(req)
+	if err != nil {
+		return nil
+	}
+
+	return o.usage.CheckBudget(o.budget, estimate)
+}
+
+// recordUsage appends a successful generation to the usage ledger
+func (o *Orchestrator) recordUsage(ctx context.Context, providerName string, req *GenerateRequest, resp *GenerateResponse) {
+	err := o.usage.Append(UsageRecord{
+		Command:          commandFromContext(ctx),
+		Provider:         baseProvider(providerName),
+		Model:            resp.Model,
+		PromptTokens:     resp.TokensUsed.PromptTokens,
+		CompletionTokens: resp.TokensUsed.CompletionTokens,
+		TotalTokens:      resp.TokensUsed.TotalTokens,
+		Cost:             resp.Cost,
+		Duration:         resp.Duration,
+		Repair:           repairRound(req),
+	})
+	if err != nil {
+		o.logger.Warn("failed to record usage", "error", err)
+	}
+}
+
+// cacheResponse caches a response unless the check set by
+// WithResponseCheck rejects it
+func (o *Orchestrator) cacheResponse(ctx context.Context, providerName string, provider Provider, req *GenerateRequest, resp *GenerateResponse) {
+	if !cacheable(ctx, resp) {
+		o.logger.Info("not caching rejected response", "provider", providerName)
+		return
+	}
+	if err := o.cache.Set(providerName, provider.Model(), req, resp); err != nil {
+		o.logger.Warn("failed to cache response", "error", err)
+	}
+}
 
-		// Cache the response
-		if err := o.cache.Set(req, resp); err != nil {
-			o.logger.Warn("failed to cache response", "error", err)
+// recordFailure counts a failed call against the provider's circuit breaker,
+// unless the caller gave up on the request
+func (o *Orchestrator) recordFailure(ctx context.Context, providerName string, latency time.Duration) {
+	if ctx.Err() != nil {
+		o.health.Release(providerName)
+		return
+	}
+	o.health.RecordFailure(providerName, latency)
+}
+
+// ProviderStats returns circuit breaker state and rolling statistics for
+// every configured provider
+func (o *Orchestrator) ProviderStats() map[string]ProviderStats {
+	stats := make(map[string]ProviderStats, len(o.providers))
+	for name := range o.providers {
+		stats[name] = o.health.Stats(name)
+	}
+	return stats
+}
+
+// GenerateStream streams a generation through the provider chain. Providers
+// that cannot stream deliver their whole response as a single chunk. A
+// provider that fails before producing any text falls back to the next one;
+// once text has been forwarded, a failure is reported as an error chunk.
+func (o *Orchestrator) GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan StreamChunk, error) {
+	out := make(chan StreamChunk)
+
+	providerChain := o.chain(req)
+
+	go func() {
+		defer close(out)
+
+		var errs chainErrors
+		for _, providerName := range providerChain {
+			provider, exists := o.providers[providerName]
+			if !exists {
+				o.logger.Warn("provider not available",
+					"provider", providerName,
+				)
+				continue
+			}
+
+			// Serve cached responses as a complete stream
+			if cached, hit := o.cache.Get(providerName, provider.Model(), req); hit && cacheable(ctx, cached) {
+				o.logger.Info("cache hit",
+					"provider", cached.Provider,
+					"tokens", cached.TokensUsed.TotalTokens,
+				)
+				if sendChunk(ctx, out, StreamChunk{Content: cached.Content}) {
+					sendChunk(ctx, out, StreamChunk{Done: true, Response: cached})
+				}
+				return
+			}
+
+			// Skip providers whose circuit breaker is open
+			if !o.health.Allow(providerName) {
+				o.logger.Warn("circuit breaker open, skipping provider",
+					"provider", providerName,
+				)
+				errs.add(&breakerSkipError{provider: providerName})
+				continue
+			}
+
+			// Refuse requests that would exceed a spending cap
+			if err := o.checkBudget(provider, req); err != nil {
+				o.logger.Warn("spending cap reached, skipping provider",
+					"provider", providerName,
+					"error", err,
+				)
+				o.health.Release(providerName)
+				errs.add(err)
+				continue
+			}
+
+			o.logger.Info("attempting streaming generation",
+				"provider", providerName,
+			)
+
+			startTime := time.Now()
+			chunks, err := o.streamWithLimits(ctx, providerName, provider, req)
+
+			var rateErr *RateLimitError
+			if errors.As(err, &rateErr) {
+				o.logger.Warn("provider rate limited, falling back",
+					"provider", providerName,
+					"retry_after", rateErr.RetryAfter,
+				)
+				o.health.Release(providerName)
+				errs.add(err)
+				continue
+			}
+
+			if err != nil {
+				o.logger.Warn("provider failed",
+					"provider", providerName,
+					"error", err,
+					"duration", time.Since(startTime),
+				)
+				o.recordFailure(ctx, providerName, time.Since(startTime))
+				errs.add(err)
+				continue
+			}
+
+			resp, started, err := o.forwardStream(ctx, chunks, out)
+			if errors.As(err, &rateErr) && !started {
+				o.pauseProvider(providerName, rateErr)
+				o.health.Release(providerName)
+				errs.add(err)
+				continue
+			}
+			if err != nil {
+				o.logger.Warn("provider failed",
+					"provider", providerName,
+					"error", err,
+					"duration", time.Since(startTime),
+				)
+				o.recordFailure(ctx, providerName, time.Since(startTime))
+				if started || ctx.Err() != nil {
+					sendChunk(ctx, out, StreamChunk{Err: fmt.Errorf("%s stream interrupted: %w", providerName, err)})
+					return
+				}
+				errs.add(err)
+				continue
+			}
+
+			// Success!
+			o.health.RecordSuccess(providerName, time.Since(startTime))
+			o.recordUsage(ctx, providerName, req, resp)
+			o.logger.Info("generation successful",
+				"provider", providerName,
+				"tokens", resp.TokensUsed.TotalTokens,
+				"cost", fmt.Sprintf("$%.6f", resp.Cost),
+				"duration", resp.Duration,
+			)
+
+			o.cacheResponse(ctx, providerName, provider, req, resp)
+			sendChunk(ctx, out, StreamChunk{Done: true, Response: resp})
+			return
 		}
 
-		return resp, nil
+		sendChunk(ctx, out, StreamChunk{Err: errs.err(o.health)})
+	}()
+
+	return out, nil
+}
+
+// streamWithLimits opens a stream once the provider's rate limiter admits
+// the request, retrying short rate limits like generateWithLimits
+func (o *Orchestrator) streamWithLimits(ctx context.Context, providerName string, provider Provider, req *GenerateRequest) (<-chan StreamChunk, error) {
+	tokens := estimateTokens(req)
+
+	for round := 1; ; round++ {
+		if err := o.awaitCapacity(ctx, providerName, tokens); err != nil {
+			return nil, err
+		}
+
+		chunks, err := streamFrom(ctx, provider, req)
+
+		var rateErr *RateLimitError
+		if !errors.As(err, &rateErr) {
+			return chunks, err
+		}
+
+		o.pauseProvider(providerName, rateErr)
+		if round >= maxRateLimitRounds {
+			return nil, err
+		}
+	}
+}
+
+// forwardStream copies content chunks to out and returns the final response.
+// started reports whether any text reached the consumer.
+func (o *Orchestrator) forwardStream(ctx context.Context, chunks <-chan StreamChunk, out chan<- StreamChunk) (resp *GenerateResponse, started bool, err error) {
+	for chunk := range chunks {
+		switch {
+		case chunk.Err != nil:
+			return nil, started, chunk.Err
+		case chunk.Done:
+			resp = chunk.Response
+		case chunk.Content != "":
+			if !sendChunk(ctx, out, chunk) {
+				return nil, started, ctx.Err()
+			}
+			started = true
+		}
 	}
 
-	return nil, fmt.Errorf("all providers failed, last error: %w", lastErr)
+	if resp == nil {
+		if ctx.Err() != nil {
+			return nil, started, ctx.Err()
+		}
+		return nil, started, fmt.Errorf("stream ended without a final response")
+	}
+
+	return resp, started, nil
 }
 
-// ValidateProviders checks health of all configured providers
+// ValidateProviders checks health of all configured providers, using the
+// model of the health routing rule where one names the provider. Results
+// feed the circuit breakers, so a passing check closes an open breaker.
 func (o *Orchestrator) ValidateProviders(ctx context.Context) map[string]error {
 	results := make(map[string]error)
 
-	for name, provider := range o.providers {
+	for name := range o.providers {
+		if baseProvider(name) != name {
+			continue // A routed model, checked through its provider
+		}
+
+		provider := o.healthTarget(name)
 		if err := provider.Validate(); err != nil {
 			results[name] = fmt.Errorf("validation failed: %w", err)
 			continue
 		}
 
+		startTime := time.Now()
 		if err := provider.Health(ctx); err != nil {
+			o.recordFailure(ctx, name, time.Since(startTime))
 			results[name] = fmt.Errorf("health check failed: %w", err)
 			continue
 		}
+		o.health.RecordSuccess(name, time.Since(startTime))
 
 		results[name] = nil // Success
 	}
@@ -142,12 +759,37 @@ func (o *Orchestrator) ValidateProviders(ctx context.Context) map[string]error {
 	return results
 }
 
-// EstimateCost estimates cost for a generation request
+// EstimateCost estimates cost for a generation request on the first
+// available provider of its chain
 func (o *Orchestrator) EstimateCost(req *GenerateRequest) (float64, error) {
-	provider, exists := o.providers[o.primaryProvider]
+	for _, name := range o.route(req) {
+		if provider, exists := o.providers[name]; exists {
+			return provider.EstimateCost(req)
+		}
+	}
+	return 0, fmt.Errorf("no provider available for this request")
+}
+
+// WithProvider returns an orchestrator that only uses the named provider,
+// without fallback. It shares this orchestrator's cache, circuit breakers,
+// rate limits and usage ledger.
+func (o *Orchestrator) WithProvider(name string) (*Orchestrator, error) {
+	provider, exists := o.providers[name]
 	if !exists {
-		return 0, fmt.Errorf("primary provider not available")
+		return nil, fmt.Errorf("provider %q is not configured", name)
 	}
 
-	return provider.EstimateCost(req)
+	return &Orchestrator{
+		providers:        map[string]Provider{name: provider},
+		primaryProvider:  name,
+		cache:            o.cache,
+		health:           o.health,
+		logger:           o.logger.With("ensemble_member", name),
+		maxRepairs:       o.maxRepairs,
+		limiters:         map[string]*RateLimiter{name: o.limiter(name)},
+		maxRateLimitWait: o.maxRateLimitWait,
+		usage:            o.usage,
+		budget:           o.budget,
+		prompts:          o.prompts,
+	}, nil
 }
@@ -0,0 +1,230 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// CorePackages are the packages under the core directory whose types are
+// offered to the AI for reuse
+var CorePackages = []string{"entity", "valueobject", "port"}
+
+// maxProjectContext bounds the summary added to prompts; types beyond it
+// are listed by name only
+const maxProjectContext = 6000
+
+// CoreType is an exported type found in a core package
+type CoreType struct {
+	Package string
+	Name    string
+	Kind    string   // struct, interface, or the underlying type
+	Fields  []string // "Name Type" for structs
+	Methods []string // Business methods or interface methods, as signatures
+}
+
+// QualifiedName returns the name generated code uses, e.g. entity.Customer
+func (t CoreType) QualifiedName() string {
+	return t.Package + "." + t.Name
+}
+
+// CoreSummary describes the types a project already defines
+type CoreSummary struct {
+	Types []CoreType
+}
+
+// SummarizeCore reads the entity, valueobject and port packages under dir
+// with go/ast. Missing packages are skipped and so are files that do not
+// parse, so a half-finished project still yields what can be read.
+func SummarizeCore(dir string) (*CoreSummary, error) {
+	summary := &CoreSummary{}
+
+	for _, pkg := range CorePackages {
+		pkgDir := filepath.Join(dir, pkg)
+		entries, err := os.ReadDir(pkgDir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", pkgDir, err)
+		}
+
+		fset := token.NewFileSet()
+		var files []*ast.File
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+			file, err := parser.ParseFile(fset, filepath.Join(pkgDir, name), nil, parser.SkipObjectResolution)
+			if err != nil {
+				continue
+			}
+			files = append(files, file)
+		}
+
+		summary.Types = append(summary.Types, summarizePackage(pkg, files)...)
+	}
+
+	return summary, nil
+}
+
+// summarizePackage collects the exported types of one package with their
+// fields and methods, sorted by name
+func summarizePackage(pkg string, files []*ast.File) []CoreType {
+	byName := make(map[string]*CoreType)
+	var names []string
+
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				if !typeSpec.Name.IsExported() {
+					continue
+				}
+
+				t := &CoreType{Package: pkg, Name: typeSpec.Name.Name}
+				switch typ := typeSpec.Type.(type) {
+				case *ast.StructType:
+					t.Kind = "struct"
+					for _, field := range typ.Fields.List {
+						for _, name := range field.Names {
+							if name.IsExported() {
+								t.Fields = append(t.Fields, name.Name+" "+types.ExprString(field.Type))
+							}
+						}
+					}
+				case *ast.InterfaceType:
+					t.Kind = "interface"
+					for _, method := range typ.Methods.List {
+						if fn, ok := method.Type.(*ast.FuncType); ok && len(method.Names) > 0 {
+							t.Methods = append(t.Methods, signatureString(method.Names[0].Name, fn))
+						}
+					}
+				default:
+					t.Kind = types.ExprString(typ)
+				}
+
+				byName[t.Name] = t
+				names = append(names, t.Name)
+			}
+		}
+	}
+
+	// Attach exported methods; Validate is generated for every type, so it
+	// is left out
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || len(fn.Recv.List) == 0 || !fn.Name.IsExported() || fn.Name.Name == "Validate" {
+				continue
+			}
+			if t, ok := byName[receiverName(fn.Recv.List[0].Type)]; ok {
+				t.Methods = append(t.Methods, signatureString(fn.Name.Name, fn.Type))
+			}
+		}
+	}
+
+	sort.Strings(names)
+	result := make([]CoreType, 0, len(names))
+	for _, name := range names {
+		result = append(result, *byName[name])
+	}
+	return result
+}
+
+// receiverName returns the type name of a method receiver
+func receiverName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(e.X)
+	case *ast.IndexExpr:
+		return receiverName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// signatureString formats a method as Name(params) results
+func signatureString(name string, fn *ast.FuncType) string {
+	return name + strings.TrimPrefix(types.ExprString(fn), "func")
+}
+
+// String renders the summary compactly for a prompt, one type per line
+func (s *CoreSummary) String() string {
+	var b strings.Builder
+	for i, t := range s.Types {
+		line := t.QualifiedName() + " " + t.Kind
+		if len(t.Fields) > 0 {
+			line += " { " + strings.Join(t.Fields, "; ") + " }"
+		}
+		if len(t.Methods) > 0 {
+			line += "\n  methods: " + strings.Join(t.Methods, "; ")
+		}
+
+		if b.Len()+len(line) > maxProjectContext {
+			var rest []string
+			for _, r := range s.Types[i:] {
+				rest = append(rest, r.QualifiedName())
+			}
+			b.WriteString("also defined: " + strings.Join(rest, ", ") + "\n")
+			break
+		}
+		b.WriteString(line + "\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Without returns a copy of the summary minus the named types, given as
+// qualified names such as entity.Customer and matched case-insensitively
+func (s *CoreSummary) Without(names ...string) *CoreSummary {
+	if s == nil {
+		return nil
+	}
+
+	kept := &CoreSummary{}
+	for _, t := range s.Types {
+		if !slices.ContainsFunc(names, func(name string) bool { return strings.EqualFold(name, t.QualifiedName()) }) {
+			kept.Types = append(kept.Types, t)
+		}
+	}
+	return kept
+}
+
+type projectContextKey struct{}
+
+// WithProjectContext attaches a summary of the project's existing types to
+// ctx; domain prompts include it so generated specs reuse those types
+func WithProjectContext(ctx context.Context, summary *CoreSummary) context.Context {
+	return context.WithValue(ctx, projectContextKey{}, summary)
+}
+
+// coreSummaryFrom returns the project summary attached to ctx, or nil
+func coreSummaryFrom(ctx context.Context) *CoreSummary {
+	summary, _ := ctx.Value(projectContextKey{}).(*CoreSummary)
+	return summary
+}
+
+// projectContextFrom returns the rendered project summary, if any
+func projectContextFrom(ctx context.Context) string {
+	summary := coreSummaryFrom(ctx)
+	if summary == nil {
+		return ""
+	}
+	return summary.String()
+}
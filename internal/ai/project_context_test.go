@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeCoreFile(t *testing.T, dir, pkg, name, src string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, pkg), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, pkg, name), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSummarizeCore(t *testing.T) {
+	dir := t.TempDir()
+	writeCoreFile(t, dir, "entity", "customer.go", `package entity
+
+type Customer struct {
+	ID    uuid.UUID
+	Email valueobject.Email
+	notes string
+}
+
+func NewCustomer() *Customer { return &Customer{} }
+
+func (c *Customer) Validate() error { return nil }
+
+func (c *Customer) Rename(name string) error { return nil }
+
+type status int
+`)
+	writeCoreFile(t, dir, "entity", "broken.go", "package entity\n\ntype Broken struct {")
+	writeCoreFile(t, dir, "valueobject", "email.go", "package valueobject\n\ntype Email string\n")
+	writeCoreFile(t, dir, "port", "customer_repository.go", `package port
+
+type CustomerRepository interface {
+	FindByEmail(ctx context.Context, email valueobject.Email) (*entity.Customer, error)
+}
+`)
+
+	summary, err := SummarizeCore(dir)
+	if err != nil {
+		t.Fatalf("SummarizeCore failed: %v", err)
+	}
+
+	expected := `entity.Customer struct { ID uuid.UUID; Email valueobject.Email }
+  methods: Rename(name string) error
+valueobject.Email string
+port.CustomerRepository interface
+  methods: FindByEmail(ctx context.Context, email valueobject.Email) (*entity.Customer, error)`
+	if got := summary.String(); got != expected {
+		t.Errorf("Unexpected summary:\n%s\nexpected:\n%s", got, expected)
+	}
+
+	// A project without a core directory has nothing to offer
+	empty, err := SummarizeCore(filepath.Join(dir, "missing"))
+	if err != nil || len(empty.Types) != 0 {
+		t.Errorf("Expected an empty summary, got %+v (%v)", empty, err)
+	}
+}
+
+func TestDomainRequestIncludesProjectContext(t *testing.T) {
+	summary := &CoreSummary{Types: []CoreType{{Package: "valueobject", Name: "Email", Kind: "string"}}}
+
+	plain, _ := domainRequest(context.Background(), nil, "Orders")
+	withContext, err := domainRequest(WithProjectContext(context.Background(), summary), nil, "Orders")
+	if err != nil {
+		t.Fatalf("domainRequest failed: %v", err)
+	}
+
+	if strings.Contains(plain.UserPrompt, "EXISTING TYPES") {
+		t.Error("Expected no project context without a summary")
+	}
+	if !strings.Contains(withContext.UserPrompt, "EXISTING TYPES") || !strings.Contains(withContext.UserPrompt, "valueobject.Email string") {
+		t.Errorf("Expected the summary in the prompt, got %q", withContext.UserPrompt)
+	}
+}
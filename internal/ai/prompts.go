@@ -1,168 +1,237 @@
 package ai
 
-// SystemPromptDDD is the system prompt for DDD code generation
-const SystemPromptDDD = `You are a Senior Golang Architect specializing in Domain-Driven Design and Clean Architecture.
-
-Your task is to analyze business requirements and generate Go code following these strict principles:
-
-## ARCHITECTURE RULES:
-1. **Clean Architecture**: Core domain has NO external dependencies
-2. **DDD Patterns**: Entities, Value Objects, Aggregates, Services, Repositories
-3. **Go 1.22+ Syntax**: Use modern Go features
-4. **Strongly Typed**: Use uuid.UUID, not string IDs
-5. **Context Propagation**: All I/O methods accept context.Context
-6. **Error Wrapping**: Use fmt.Errorf("layer: %w", err)
-
-## CODE QUALITY:
-- All entities MUST have Validate() methods
-- Value objects are IMMUTABLE
-- Repository interfaces define contracts (no implementation)
-- Service interfaces define business logic contracts
-- NO database imports in core layer
-- NO HTTP imports in core layer
-- Use descriptive method names (not just CRUD)
-
-## CRITICAL CONSTRAINTS:
-- DO NOT generate constructor methods (NewXXX) in the methods array - these are auto-generated
-- DO NOT generate Validate() methods in the methods array - these are auto-generated
-- ONLY include business logic methods (like Cancel, Approve, Update, etc.)
-- Keep method implementations SIMPLE and SINGLE-PURPOSE
-- Validation rules should be PLAIN ENGLISH descriptions, NOT code
-- In repository/service signatures, ALWAYS use fully qualified types:
-  * Entity types: *entity.EntityName (e.g., *entity.Order, *entity.Product)
-  * Value object types: valueobject.TypeName (e.g., valueobject.Money, valueobject.Email)
-  * Standard types: uuid.UUID, string, int, etc. (no package prefix)
-
-## OUTPUT FORMAT:
-You MUST return ONLY valid JSON with this EXACT structure:
-{
-  "domain_name": "string (lowercase, singular)",
-  "entities": [
-    {
-      "name": "string (PascalCase)",
-      "is_aggregate_root": boolean,
-      "fields": [
-        {
-          "name": "string (PascalCase)",
-          "type": "string (Go type)",
-          "description": "string",
-          "validation": "string (validation rule or empty)"
-        }
-      ],
-      "methods": [
-        {
-          "name": "string (method name)",
-          "description": "string",
-          "signature": "string (full Go signature)",
-          "implementation": "string (Go code)"
-        }
-      ]
-    }
-  ],
-  "value_objects": [
-    {
-      "name": "string (PascalCase)",
-      "fields": [
-        {
-          "name": "string",
-          "type": "string",
-          "description": "string"
-        }
-      ],
-      "validation": "string (validation logic)"
-    }
-  ],
-  "repository_interface": {
-    "name": "string (e.g., 'CartRepository')",
-    "methods": [
-      {
-        "name": "string",
-        "signature": "string (full Go signature)",
-        "description": "string"
-      }
-    ]
-  },
-  "service_interface": {
-    "name": "string (e.g., 'CartService')",
-    "methods": [
-      {
-        "name": "string",
-        "signature": "string",
-        "description": "string"
-      }
-    ]
-  }
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Prompt names used by the generators
+const (
+	PromptDomainSystem = "domain_system"
+	PromptDomainUser   = "domain_user"
+	PromptDomainRefine = "domain_refine"
+	PromptRefineSystem = "domain_refine_system"
+)
+
+// Prompt sources, from highest to lowest precedence
+const (
+	PromptSourceProject  = "project"
+	PromptSourceGlobal   = "global"
+	PromptSourceEmbedded = "embedded"
+)
+
+// promptExt is the file extension of prompt templates
+const promptExt = ".tmpl"
+
+//go:embed prompts/*.tmpl
+var embeddedPrompts embed.FS
+
+// promptVersionPattern matches the version comment on a template's first line,
+// e.g. {{/* version: 2 */ -}}
+var promptVersionPattern = regexp.MustCompile(`^\{\{-?\s*/\*\s*version:\s*(\S+)\s*\*/\s*-?\}\}`)
+
+// Prompt is a text/template prompt resolved from one of the prompt sources
+type Prompt struct {
+	Name    string
+	Version string // Declared in the version comment, "unversioned" if missing
+	Source  string // project, global or embedded
+	Path    string // File the prompt was read from, empty when embedded
+	Text    string
+
+	tmpl *template.Template
 }
 
-## EXAMPLE:
-Input: "Order has ID, Total, Status. Can be cancelled if pending."
-
-Output:
-{
-  "domain_name": "order",
-  "entities": [
-    {
-      "name": "Order",
-      "is_aggregate_root": true,
-      "fields": [
-        {"name": "ID", "type": "uuid.UUID", "description": "Order ID", "validation": "cannot be nil"},
-        {"name": "Total", "type": "Money", "description": "Order total", "validation": "must be a valid Money value object"},
-        {"name": "Status", "type": "OrderStatus", "description": "Order status", "validation": "must be a valid OrderStatus"},
-        {"name": "CreatedAt", "type": "time.Time", "description": "Creation time", "validation": "cannot be zero"}
-      ],
-      "methods": [
-        {
-          "name": "Cancel",
-          "description": "Cancel order if pending",
-          "signature": "func (o *Order) Cancel() error",
-          "implementation": ""
-        }
-      ]
-    }
-  ],
-  "value_objects": [
-    {
-      "name": "Money",
-      "fields": [
-        {"name": "Amount", "type": "int64", "description": "Amount in smallest currency unit (cents)"},
-        {"name": "Currency", "type": "string", "description": "ISO 4217 currency code"}
-      ],
-      "validation": "Amount must be non-negative, Currency must be valid 3-letter ISO code"
-    },
-    {
-      "name": "OrderStatus",
-      "fields": [
-        {"name": "Value", "type": "string", "description": "Status value"}
-      ],
-      "validation": "Must be one of: pending, processing, completed, cancelled"
-    }
-  ],
-  "repository_interface": {
-    "name": "OrderRepository",
-    "methods": [
-      {"name": "Save", "signature": "Save(ctx context.Context, order *entity.Order) error", "description": "Save order"},
-      {"name": "FindByID", "signature": "FindByID(ctx context.Context, id uuid.UUID) (*entity.Order, error)", "description": "Find by ID"}
-    ]
-  },
-  "service_interface": {
-    "name": "OrderService",
-    "methods": [
-      {"name": "PlaceOrder", "signature": "PlaceOrder(ctx context.Context, total valueobject.Money, status valueobject.OrderStatus) (*entity.Order, error)", "description": "Place new order"}
-    ]
-  }
+// DomainPromptData is the data the domain prompts are rendered with
+type DomainPromptData struct {
+	Description    string
+	ProjectContext string // Summary of the project's existing types, may be empty
 }
 
-REMEMBER: Return ONLY the JSON, no markdown, no explanations, no code blocks.`
+// RefinePromptData is the data the refine prompt is rendered with
+type RefinePromptData struct {
+	Change         string // The requested change in plain language
+	Spec           string // The current domain spec as JSON
+	ProjectContext string
+}
+
+// Render executes the template with data. Surrounding whitespace is trimmed.
+func (p *Prompt) Render(data any) (string, error) {
+	var buf bytes.Buffer
+	if err := p.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("render prompt %s: %w", p.Name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// CacheVersion identifies the prompt in cache keys. Embedded prompts use
+// their declared version; overrides also carry a hash of their text, so
+// editing an override without bumping its version never serves responses
+// cached for the old text.
+func (p *Prompt) CacheVersion() string {
+	if p.Source == PromptSourceEmbedded {
+		return p.Name + "@" + p.Version
+	}
+
+	sum := sha256.Sum256([]byte(p.Text))
+	return p.Name + "@" + p.Version + "+" + hex.EncodeToString(sum[:4])
+}
+
+// PromptLoader resolves prompts from the project directory, then the global
+// directory, then the defaults embedded in the binary. A nil loader only
+// uses the embedded defaults.
+type PromptLoader struct {
+	ProjectDir string
+	GlobalDir  string
+}
+
+// NewPromptLoader creates a loader for the given override directories.
+// Either may be empty to skip it.
+func NewPromptLoader(projectDir, globalDir string) *PromptLoader {
+	return &PromptLoader{ProjectDir: projectDir, GlobalDir: globalDir}
+}
+
+// DefaultPromptLoader looks for overrides in .anaphase/prompts of the current
+// project and in ~/.anaphase/prompts
+func DefaultPromptLoader() *PromptLoader {
+	globalDir := ""
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		globalDir = filepath.Join(homeDir, ".anaphase", "prompts")
+	}
+	return NewPromptLoader(filepath.Join(".anaphase", "prompts"), globalDir)
+}
 
-// UserPromptTemplate creates a user prompt from domain description
-func UserPromptTemplate(description string) string {
-	return `Analyze this business requirement and generate DDD domain code:
+// Load resolves the named prompt. An override that fails to parse is an
+// error rather than a silent fallback to the default.
+func (l *PromptLoader) Load(name string) (*Prompt, error) {
+	if l != nil {
+		for _, dir := range l.dirs() {
+			path := filepath.Join(dir.path, name+promptExt)
+			data, err := os.ReadFile(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("read prompt %s: %w", name, err)
+			}
+			return parsePrompt(name, dir.source, path, string(data))
+		}
+	}
+
+	data, err := embeddedPrompts.ReadFile("prompts/" + name + promptExt)
+	if err != nil {
+		return nil, fmt.Errorf("unknown prompt %q", name)
+	}
+	return parsePrompt(name, PromptSourceEmbedded, "", string(data))
+}
+
+// DefaultPrompt returns the embedded default of the named prompt
+func DefaultPrompt(name string) (*Prompt, error) {
+	var defaults *PromptLoader
+	return defaults.Load(name)
+}
+
+// List resolves every prompt with an embedded default, sorted by name
+func (l *PromptLoader) List() ([]*Prompt, error) {
+	entries, err := embeddedPrompts.ReadDir("prompts")
+	if err != nil {
+		return nil, err
+	}
+
+	var prompts []*Prompt
+	for _, entry := range entries {
+		prompt, err := l.Load(strings.TrimSuffix(entry.Name(), promptExt))
+		if err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, prompt)
+	}
+
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+	return prompts, nil
+}
+
+// Eject copies the embedded default of a prompt into the project directory,
+// or the global directory if global is set, and returns the written path.
+// An existing file is only replaced when force is set.
+func (l *PromptLoader) Eject(name string, global, force bool) (string, error) {
+	prompt, err := DefaultPrompt(name)
+	if err != nil {
+		return "", err
+	}
+
+	dir := l.ProjectDir
+	if global {
+		dir = l.GlobalDir
+	}
+	if dir == "" {
+		return "", fmt.Errorf("no prompt directory configured")
+	}
+
+	path := filepath.Join(dir, name+promptExt)
+	if _, err := os.Stat(path); err == nil && !force {
+		return "", fmt.Errorf("%s already exists (use --force to overwrite)", path)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("create prompt directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(prompt.Text), 0644); err != nil {
+		return "", fmt.Errorf("write prompt: %w", err)
+	}
+
+	return path, nil
+}
+
+type promptDir struct {
+	source string
+	path   string
+}
+
+// dirs lists the override directories in lookup order
+func (l *PromptLoader) dirs() []promptDir {
+	var dirs []promptDir
+	if l.ProjectDir != "" {
+		dirs = append(dirs, promptDir{PromptSourceProject, l.ProjectDir})
+	}
+	if l.GlobalDir != "" {
+		dirs = append(dirs, promptDir{PromptSourceGlobal, l.GlobalDir})
+	}
+	return dirs
+}
 
-REQUIREMENT:
-` + description + `
+// parsePrompt parses a template and reads its version comment
+func parsePrompt(name, source, path, text string) (*Prompt, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		where := source
+		if path != "" {
+			where = path
+		}
+		return nil, fmt.Errorf("parse prompt %s (%s): %w", name, where, err)
+	}
 
-Generate complete JSON structure with entities, value objects, repository interface, and service interface.
-Focus on business logic and domain rules.
+	version := "unversioned"
+	if m := promptVersionPattern.FindStringSubmatch(text); m != nil {
+		version = m[1]
+	}
 
-Return ONLY the JSON, nothing else.`
+	return &Prompt{
+		Name:    name,
+		Version: version,
+		Source:  source,
+		Path:    path,
+		Text:    text,
+		tmpl:    tmpl,
+	}, nil
 }
@@ -0,0 +1,119 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePrompt(t *testing.T, dir, name, text string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+promptExt), []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPromptLoaderLookupOrder(t *testing.T) {
+	projectDir := filepath.Join(t.TempDir(), "project")
+	globalDir := filepath.Join(t.TempDir(), "global")
+	loader := NewPromptLoader(projectDir, globalDir)
+
+	prompt, err := loader.Load(PromptDomainUser)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if prompt.Source != PromptSourceEmbedded || prompt.Version != "2" {
+		t.Errorf("Expected the embedded default at version 1, got %s at %s", prompt.Source, prompt.Version)
+	}
+
+	writePrompt(t, globalDir, PromptDomainUser, "{{/* version: 2 */ -}}\nGlobal: {{.Description}}")
+	prompt, _ = loader.Load(PromptDomainUser)
+	if prompt.Source != PromptSourceGlobal || prompt.Version != "2" {
+		t.Errorf("Expected the global override at version 2, got %s at %s", prompt.Source, prompt.Version)
+	}
+
+	writePrompt(t, projectDir, PromptDomainUser, "Project: {{.Description}}")
+	prompt, _ = loader.Load(PromptDomainUser)
+	if prompt.Source != PromptSourceProject || prompt.Version != "unversioned" {
+		t.Errorf("Expected the unversioned project override, got %s at %s", prompt.Source, prompt.Version)
+	}
+
+	rendered, err := prompt.Render(DomainPromptData{Description: "Orders"})
+	if err != nil || rendered != "Project: Orders" {
+		t.Errorf("Expected the project template to render, got %q (%v)", rendered, err)
+	}
+
+	if _, err := loader.Load("missing"); err == nil {
+		t.Error("Expected an error for an unknown prompt")
+	}
+
+	writePrompt(t, projectDir, PromptDomainSystem, "{{.Description")
+	if _, err := loader.Load(PromptDomainSystem); err == nil || !strings.Contains(err.Error(), projectDir) {
+		t.Errorf("Expected a parse error naming the override, got %v", err)
+	}
+}
+
+func TestDomainRequestPromptVersion(t *testing.T) {
+	projectDir := t.TempDir()
+	loader := NewPromptLoader(projectDir, "")
+
+	defaults, err := domainRequest(context.Background(), nil, "Orders")
+	if err != nil {
+		t.Fatalf("domainRequest failed: %v", err)
+	}
+	if !strings.Contains(defaults.UserPrompt, "REQUIREMENT:\nOrders\n") {
+		t.Errorf("Expected the description in the user prompt, got %q", defaults.UserPrompt)
+	}
+	if defaults.PromptVersion != "domain_system@1,domain_user@2" {
+		t.Errorf("Unexpected prompt version %q", defaults.PromptVersion)
+	}
+
+	// Editing an override changes the version even without a bump
+	versions := map[string]bool{defaults.PromptVersion: true}
+	for _, text := range []string{"One {{.Description}}", "Two {{.Description}}"} {
+		writePrompt(t, projectDir, PromptDomainUser, "{{/* version: 1 */ -}}\n"+text)
+		req, err := domainRequest(context.Background(), loader, "Orders")
+		if err != nil {
+			t.Fatalf("domainRequest failed: %v", err)
+		}
+		versions[req.PromptVersion] = true
+	}
+	if len(versions) != 3 {
+		t.Errorf("Expected a distinct prompt version per prompt text, got %v", versions)
+	}
+}
+
+func TestPromptLoaderEject(t *testing.T) {
+	projectDir := filepath.Join(t.TempDir(), "prompts")
+	loader := NewPromptLoader(projectDir, "")
+
+	path, err := loader.Eject(PromptDomainSystem, false, false)
+	if err != nil {
+		t.Fatalf("Eject failed: %v", err)
+	}
+
+	prompt, err := loader.Load(PromptDomainSystem)
+	if err != nil || prompt.Source != PromptSourceProject || prompt.Path != path {
+		t.Fatalf("Expected the ejected prompt to take effect, got %+v (%v)", prompt, err)
+	}
+
+	defaults, _ := DefaultPrompt(PromptDomainSystem)
+	if prompt.Text != defaults.Text {
+		t.Error("Expected the ejected prompt to match the default")
+	}
+
+	if _, err := loader.Eject(PromptDomainSystem, false, false); err == nil {
+		t.Error("Expected an error when the prompt was already ejected")
+	}
+	if _, err := loader.Eject(PromptDomainSystem, false, true); err != nil {
+		t.Errorf("Expected --force to overwrite, got %v", err)
+	}
+	if _, err := loader.Eject(PromptDomainSystem, true, false); err == nil {
+		t.Error("Expected an error without a global directory")
+	}
+}
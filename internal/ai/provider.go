@@ -2,6 +2,7 @@ package ai
 
 import (
 	"context"
+	"strings"
 	"time"
 )
 
@@ -10,6 +11,9 @@ type Provider interface {
 	// Name returns the provider identifier
 	Name() string
 
+	// Model returns the configured model, used to namespace cached responses
+	Model() string
+
 	// Generate sends a prompt and returns structured response
 	Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error)
 
@@ -23,14 +27,81 @@ type Provider interface {
 	EstimateCost(req *GenerateRequest) (float64, error)
 }
 
+// retryBackoff returns the pause before a retry: one second before the
+// first, doubling for each one after it
+func retryBackoff(attempt int) time.Duration {
+	return time.Second << (attempt - 1)
+}
+
+// Message roles in a conversation
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is one turn of a conversation
+type Message struct {
+	Role    string // RoleSystem, RoleUser or RoleAssistant
+	Content string
+}
+
 // GenerateRequest encapsulates generation parameters
 type GenerateRequest struct {
-	SystemPrompt string            // System-level instructions
-	UserPrompt   string            // User's actual request
-	Temperature  float64           // Randomness (0.0-1.0)
-	MaxTokens    int               // Maximum output length
-	TopP         float64           // Nucleus sampling
-	Metadata     map[string]string // Request metadata
+	SystemPrompt  string            // System-level instructions
+	UserPrompt    string            // User's actual request
+	Messages      []Message         // Further turns, after SystemPrompt and UserPrompt
+	Temperature   float64           // Randomness (0.0-1.0)
+	MaxTokens     int               // Maximum output length
+	TopP          float64           // Nucleus sampling
+	Metadata      map[string]string // Request metadata
+	PromptVersion string            // Version of the prompt template, part of the cache key
+
+	// ResponseSchema constrains the output to a JSON document on providers
+	// that support structured output; others rely on the prompt alone
+	ResponseSchema *JSONSchema
+}
+
+// Conversation returns every message of the request in order. SystemPrompt
+// and UserPrompt are shorthand for a leading system and user message.
+func (r *GenerateRequest) Conversation() []Message {
+	messages := make([]Message, 0, len(r.Messages)+2)
+	if r.SystemPrompt != "" {
+		messages = append(messages, Message{Role: RoleSystem, Content: r.SystemPrompt})
+	}
+	if r.UserPrompt != "" {
+		messages = append(messages, Message{Role: RoleUser, Content: r.UserPrompt})
+	}
+	return append(messages, r.Messages...)
+}
+
+// promptLength returns the length of all messages, for token estimates
+func (r *GenerateRequest) promptLength() int {
+	n := 0
+	for _, m := range r.Conversation() {
+		n += len(m.Content)
+	}
+	return n
+}
+
+// splitSystem separates the system messages, joined into one instruction,
+// from the turns of a conversation for APIs that take the system prompt on
+// its own. Consecutive turns of the same role are merged, since those APIs
+// expect users and the assistant to alternate.
+func splitSystem(messages []Message) (system string, turns []Message) {
+	var instructions []string
+	for _, m := range messages {
+		if m.Role == RoleSystem {
+			instructions = append(instructions, m.Content)
+			continue
+		}
+		if n := len(turns); n > 0 && turns[n-1].Role == m.Role {
+			turns[n-1].Content += "\n\n" + m.Content
+			continue
+		}
+		turns = append(turns, m)
+	}
+	return strings.Join(instructions, "\n\n"), turns
 }
 
 // GenerateResponse contains the provider's output
@@ -1,6 +1,7 @@
 package ai
 
 import (
+	"reflect"
 	"testing"
 	"time"
 )
@@ -24,6 +25,69 @@ func TestGenerateRequest(t *testing.T) {
 	}
 }
 
+func TestRetryBackoff(t *testing.T) {
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}
+	for i, want := range expected {
+		if got := retryBackoff(i + 1); got != want {
+			t.Errorf("Attempt %d: expected %s, got %s", i+1, want, got)
+		}
+	}
+}
+
+func TestGenerateRequestConversation(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *GenerateRequest
+		want []Message
+	}{
+		{
+			name: "shorthand",
+			req:  &GenerateRequest{SystemPrompt: "Be brief", UserPrompt: "Hello"},
+			want: []Message{{RoleSystem, "Be brief"}, {RoleUser, "Hello"}},
+		},
+		{
+			name: "shorthand before messages",
+			req: &GenerateRequest{SystemPrompt: "Be brief", UserPrompt: "Hello", Messages: []Message{
+				{RoleAssistant, "Hi"},
+				{RoleUser, "Shorter"},
+			}},
+			want: []Message{{RoleSystem, "Be brief"}, {RoleUser, "Hello"}, {RoleAssistant, "Hi"}, {RoleUser, "Shorter"}},
+		},
+		{
+			name: "messages only",
+			req:  &GenerateRequest{Messages: []Message{{RoleSystem, "Be brief"}, {RoleUser, "Hello"}}},
+			want: []Message{{RoleSystem, "Be brief"}, {RoleUser, "Hello"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.req.Conversation(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Conversation() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitSystem(t *testing.T) {
+	system, turns := splitSystem([]Message{
+		{RoleSystem, "Be brief"},
+		{RoleUser, "Hello"},
+		{RoleSystem, "Answer in JSON"},
+		{RoleUser, "Order"},
+		{RoleAssistant, "{}"},
+		{RoleUser, "Again"},
+	})
+
+	if system != "Be brief\n\nAnswer in JSON" {
+		t.Errorf("Expected the system messages joined, got %q", system)
+	}
+	want := []Message{{RoleUser, "Hello\n\nOrder"}, {RoleAssistant, "{}"}, {RoleUser, "Again"}}
+	if !reflect.DeepEqual(turns, want) {
+		t.Errorf("Expected alternating turns %+v, got %+v", want, turns)
+	}
+}
+
 func TestGenerateResponse(t *testing.T) {
 	resp := &GenerateResponse{
 		Content:  "Test response",
@@ -0,0 +1,322 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/googleapis/gax-go/v2/apierror"
+	"google.golang.org/grpc/codes"
+)
+
+// Defaults used when rate limiting is not configured
+const (
+	// DefaultMaxRateLimitWait is the longest the orchestrator waits for a
+	// rate-limited provider before falling back to the next one
+	DefaultMaxRateLimitWait = 15 * time.Second
+
+	// defaultRateLimitPause is assumed when a provider rejects a request
+	// without saying when to retry
+	defaultRateLimitPause = 5 * time.Second
+
+	// maxRateLimitRounds bounds how often one request waits on a provider
+	// that keeps rejecting it
+	maxRateLimitRounds = 3
+)
+
+// RateLimitError reports that a request was rejected, or held back, because
+// of a provider's rate limit
+type RateLimitError struct {
+	Provider   string
+	RetryAfter time.Duration // Zero when the provider did not say
+	Message    string
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s rate limited, retry in %s: %s", e.Provider, e.RetryAfter.Round(time.Second), e.Message)
+	}
+	return fmt.Sprintf("%s rate limited: %s", e.Provider, e.Message)
+}
+
+// isRateLimited reports whether err is, or wraps, a rate limit error
+func isRateLimited(err error) bool {
+	var rateErr *RateLimitError
+	return errors.As(err, &rateErr)
+}
+
+// newRateLimitError builds a RateLimitError from a 429 response
+func newRateLimitError(provider string, header http.Header, message string) *RateLimitError {
+	return &RateLimitError{
+		Provider:   provider,
+		RetryAfter: retryAfterFromHeaders(header, time.Now()),
+		Message:    message,
+	}
+}
+
+// apiError describes a non-OK response, as a RateLimitError for 429s
+func apiError(provider string, resp *http.Response, message string) error {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return newRateLimitError(provider, resp.Header, message)
+	}
+	return fmt.Errorf("%s API error (%d): %s", provider, resp.StatusCode, message)
+}
+
+// geminiRateLimitError converts a quota error from the Gemini client into a
+// RateLimitError, returning nil for any other error
+func geminiRateLimitError(err error) *RateLimitError {
+	var apiErr *apierror.APIError
+	if !errors.As(err, &apiErr) {
+		return nil
+	}
+
+	exhausted := apiErr.HTTPCode() == http.StatusTooManyRequests
+	if status := apiErr.GRPCStatus(); status != nil && status.Code() == codes.ResourceExhausted {
+		exhausted = true
+	}
+	if !exhausted {
+		return nil
+	}
+
+	return &RateLimitError{
+		Provider:   "gemini",
+		RetryAfter: apiErr.Details().RetryInfo.GetRetryDelay().AsDuration(),
+		Message:    apiErr.Error(),
+	}
+}
+
+// retryAfterFromHeaders works out how long to wait from a rate-limited
+// response. Retry-After wins when present; otherwise the latest reset time
+// of an exhausted limit is used (OpenAI and Groq send durations such as
+// "1m30s", Anthropic sends RFC 3339 timestamps).
+func retryAfterFromHeaders(header http.Header, now time.Time) time.Duration {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+			return time.Duration(seconds * float64(time.Second))
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return positive(at.Sub(now))
+		}
+	}
+
+	if value := header.Get("Retry-After-Ms"); value != "" {
+		if ms, err := strconv.ParseFloat(value, 64); err == nil && ms >= 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+
+	var wait time.Duration
+	for _, limit := range []string{"requests", "tokens"} {
+		// Only a limit with nothing remaining explains the rejection
+		if remaining := header.Get("X-Ratelimit-Remaining-" + limit); remaining != "" && remaining != "0" {
+			continue
+		}
+		if d, err := time.ParseDuration(header.Get("X-Ratelimit-Reset-" + limit)); err == nil {
+			wait = max(wait, d)
+		}
+	}
+
+	for _, limit := range []string{"requests", "tokens", "input-tokens", "output-tokens"} {
+		if remaining := header.Get("Anthropic-Ratelimit-" + limit + "-Remaining"); remaining != "" && remaining != "0" {
+			continue
+		}
+		if at, err := time.Parse(time.RFC3339, header.Get("Anthropic-Ratelimit-"+limit+"-Reset")); err == nil {
+			wait = max(wait, positive(at.Sub(now)))
+		}
+	}
+
+	return wait
+}
+
+func positive(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// estimateTokens roughly sizes a request for the tokens-per-minute bucket:
+// about four characters per prompt token plus the completion budget
+func estimateTokens(req *GenerateRequest) int {
+	return req.promptLength()/4 + req.MaxTokens
+}
+
+// tokenBucket refills continuously up to its per-minute capacity
+type tokenBucket struct {
+	capacity  float64
+	available float64
+	perSecond float64
+	updated   time.Time
+}
+
+func newTokenBucket(perMinute int, now time.Time) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		capacity:  float64(perMinute),
+		available: float64(perMinute),
+		perSecond: float64(perMinute) / 60,
+		updated:   now,
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.available = math.Min(b.capacity, b.available+elapsed*b.perSecond)
+		b.updated = now
+	}
+}
+
+// delay returns how long until n units are available. Requests larger than
+// the whole bucket only wait for it to fill.
+func (b *tokenBucket) delay(n float64, now time.Time) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.refill(now)
+
+	n = math.Min(n, b.capacity)
+	if b.available >= n {
+		return 0
+	}
+	return time.Duration((n - b.available) / b.perSecond * float64(time.Second))
+}
+
+// take consumes n units. The bucket may go negative: the units are then
+// reserved ahead, and later requests wait for them to refill first.
+func (b *tokenBucket) take(n float64, now time.Time) {
+	if b == nil {
+		return
+	}
+	b.refill(now)
+	b.available -= math.Min(n, b.capacity)
+}
+
+// give returns units taken for a request that was not sent
+func (b *tokenBucket) give(n float64, now time.Time) {
+	if b == nil {
+		return
+	}
+	b.refill(now)
+	b.available = math.Min(b.capacity, b.available+math.Min(n, b.capacity))
+}
+
+// RateLimiter paces requests to one provider with client-side token buckets
+// for requests and tokens per minute, and honours pauses requested by the
+// provider itself
+type RateLimiter struct {
+	mu          sync.Mutex
+	requests    *tokenBucket // nil when unlimited
+	tokens      *tokenBucket // nil when unlimited
+	pausedUntil time.Time
+	now         func() time.Time
+}
+
+// NewRateLimiter creates a limiter; zero disables the corresponding limit
+func NewRateLimiter(requestsPerMinute, tokensPerMinute int) *RateLimiter {
+	now := time.Now()
+	return &RateLimiter{
+		requests: newTokenBucket(requestsPerMinute, now),
+		tokens:   newTokenBucket(tokensPerMinute, now),
+		now:      time.Now,
+	}
+}
+
+// Reserve takes capacity for a request of the given size and returns when
+// it may be sent. Capacity is taken under the lock together with the delay
+// being computed, so concurrent requests queue behind one another instead
+// of all seeing the same free capacity. Cancel a reservation that will not
+// be used.
+func (l *RateLimiter) Reserve(tokens int) *Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	n := float64(tokens)
+	wait := max(l.requests.delay(1, now), l.tokens.delay(n, now), positive(l.pausedUntil.Sub(now)))
+	l.requests.take(1, now)
+	l.tokens.take(n, now)
+
+	return &Reservation{limiter: l, tokens: n, ready: now.Add(wait)}
+}
+
+// Reservation is capacity reserved on a RateLimiter for one request
+type Reservation struct {
+	limiter   *RateLimiter
+	tokens    float64
+	ready     time.Time
+	cancelled bool // Guarded by limiter.mu
+}
+
+// Delay returns how long until the reserved request may be sent
+func (r *Reservation) Delay() time.Duration {
+	return positive(r.ready.Sub(r.limiter.now()))
+}
+
+// Wait blocks until the reserved request may be sent. The reservation is
+// cancelled if ctx ends first.
+func (r *Reservation) Wait(ctx context.Context) error {
+	delay := r.Delay()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Cancel returns the reserved capacity to the limiter
+func (r *Reservation) Cancel() {
+	l := r.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if r.cancelled {
+		return
+	}
+	r.cancelled = true
+
+	now := l.now()
+	l.requests.give(1, now)
+	l.tokens.give(r.tokens, now)
+}
+
+// PauseFor holds back requests after the provider reported a rate limit
+func (l *RateLimiter) PauseFor(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until := l.now().Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// rateLimitedError is returned when every provider was rate limited
+func rateLimitedError(limited []*RateLimitError) error {
+	soonest := limited[0]
+	names := make([]string, 0, len(limited))
+	for _, e := range limited {
+		names = append(names, e.Provider)
+		if e.RetryAfter < soonest.RetryAfter {
+			soonest = e
+		}
+	}
+
+	return fmt.Errorf("all providers are rate limited (%s), earliest retry in %s: %w",
+		strings.Join(names, ", "), soonest.RetryAfter.Round(time.Second), soonest)
+}
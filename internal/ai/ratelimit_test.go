@@ -0,0 +1,239 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetryAfterFromHeaders(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected time.Duration
+	}{
+		{"seconds", map[string]string{"Retry-After": "20"}, 20 * time.Second},
+		{"http date", map[string]string{"Retry-After": now.Add(time.Minute).Format(http.TimeFormat)}, time.Minute},
+		{"milliseconds", map[string]string{"Retry-After-Ms": "1500"}, 1500 * time.Millisecond},
+		{
+			"openai reset of exhausted limit",
+			map[string]string{
+				"X-Ratelimit-Remaining-Requests": "0",
+				"X-Ratelimit-Reset-Requests":     "1m30.5s",
+				"X-Ratelimit-Remaining-Tokens":   "4000",
+				"X-Ratelimit-Reset-Tokens":       "5m",
+			},
+			90*time.Second + 500*time.Millisecond,
+		},
+		{
+			"anthropic reset timestamp",
+			map[string]string{
+				"Anthropic-Ratelimit-Tokens-Remaining": "0",
+				"Anthropic-Ratelimit-Tokens-Reset":     now.Add(42 * time.Second).Format(time.RFC3339),
+			},
+			42 * time.Second,
+		},
+		{"retry-after wins", map[string]string{"Retry-After": "3", "X-Ratelimit-Reset-Requests": "1m"}, 3 * time.Second},
+		{"unknown", map[string]string{"Retry-After": "soon"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for key, value := range tt.headers {
+				header.Set(key, value)
+			}
+
+			if got := retryAfterFromHeaders(header, now); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRateLimiterBuckets(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+
+	// Buckets refill continuously, so compare to the nearest millisecond.
+	// The probing reservation is cancelled so it does not count.
+	delay := func(l *RateLimiter, tokens int) time.Duration {
+		r := l.Reserve(tokens)
+		defer r.Cancel()
+		return r.Delay().Round(time.Millisecond)
+	}
+
+	requests := NewRateLimiter(2, 0)
+	requests.now = clock
+	requests.Reserve(100)
+	requests.Reserve(100)
+	if got := delay(requests, 100); got != 30*time.Second {
+		t.Errorf("Expected to wait for one request to refill (30s), got %s", got)
+	}
+
+	tokens := NewRateLimiter(0, 600)
+	tokens.now = clock
+	tokens.Reserve(200)
+	if got := delay(tokens, 500); got != 10*time.Second {
+		t.Errorf("Expected to wait for 100 tokens at 10/s (10s), got %s", got)
+	}
+	if got := delay(tokens, 5000); got != 20*time.Second {
+		t.Errorf("Expected oversized requests to wait for a full bucket (20s), got %s", got)
+	}
+
+	now = now.Add(30 * time.Second)
+	if got := delay(requests, 100); got != 0 {
+		t.Errorf("Expected no wait after refill, got %s", got)
+	}
+
+	requests.PauseFor(time.Minute)
+	if got := delay(requests, 1); got != time.Minute {
+		t.Errorf("Expected provider pause to apply, got %s", got)
+	}
+}
+
+func TestOpenAIProviderRateLimitError(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "42")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error": {"message": "Rate limit reached"}}`)
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider("test-key", "gpt-4o-mini", server.URL, 5*time.Second, 3)
+	_, err := provider.Generate(context.Background(), &GenerateRequest{UserPrompt: "Hello"})
+
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("Expected a rate limit error, got %v", err)
+	}
+	if rateErr.RetryAfter != 42*time.Second {
+		t.Errorf("Expected Retry-After of 42s, got %s", rateErr.RetryAfter)
+	}
+	if calls != 1 {
+		t.Errorf("Expected the provider not to retry a rate limit, got %d calls", calls)
+	}
+}
+
+func TestOrchestratorRateLimitFallback(t *testing.T) {
+	limited := &fakeProvider{name: "primary", err: &RateLimitError{Provider: "primary", RetryAfter: time.Minute, Message: "slow down"}}
+	backup := &fakeProvider{name: "backup", content: "hello"}
+
+	orchestrator := newTestOrchestrator(t, limited, 0)
+	orchestrator.providers["backup"] = backup
+	orchestrator.fallbackChain = []string{"backup"}
+	orchestrator.maxRateLimitWait = time.Second
+
+	resp, err := orchestrator.Generate(context.Background(), &GenerateRequest{UserPrompt: "Hi"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Provider != "backup" {
+		t.Errorf("Expected fallback to backup, got %q", resp.Provider)
+	}
+	if stats := orchestrator.health.Stats("primary"); stats.Failures != 0 {
+		t.Errorf("Expected rate limits not to count as failures, got %d", stats.Failures)
+	}
+
+	// The pause is remembered, so the next request skips the provider entirely
+	if _, err := orchestrator.Generate(context.Background(), &GenerateRequest{UserPrompt: "Again"}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if limited.calls != 1 {
+		t.Errorf("Expected the paused provider to be skipped, got %d calls", limited.calls)
+	}
+
+	delete(orchestrator.providers, "backup")
+	_, err = orchestrator.Generate(context.Background(), &GenerateRequest{UserPrompt: "Once more"})
+	if err == nil || !strings.Contains(err.Error(), "all providers are rate limited") {
+		t.Errorf("Expected rate limited error, got %v", err)
+	}
+}
+
+func TestRateLimiterConcurrentReservations(t *testing.T) {
+	now := time.Now()
+	limiter := NewRateLimiter(1, 0)
+	limiter.now = func() time.Time { return now }
+
+	// Requests racing for a one-request bucket queue a minute apart
+	// rather than all going out at once
+	const n = 8
+	delays := make(chan time.Duration, n)
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			delays <- limiter.Reserve(10).Delay()
+		}()
+	}
+	wg.Wait()
+	close(delays)
+
+	var got []time.Duration
+	for d := range delays {
+		got = append(got, d.Round(time.Millisecond))
+	}
+	slices.Sort(got)
+	for i, d := range got {
+		if want := time.Duration(i) * time.Minute; d != want {
+			t.Errorf("Reservation %d: expected a wait of %s, got %s", i, want, d)
+		}
+	}
+
+	// Cancelling gives the capacity back
+	r := limiter.Reserve(10)
+	r.Cancel()
+	r.Cancel()
+	if d := limiter.Reserve(10).Delay().Round(time.Millisecond); d != n*time.Minute {
+		t.Errorf("Expected a cancelled reservation not to hold capacity, got a wait of %s", d)
+	}
+}
+
+func TestReservationWaitCancelled(t *testing.T) {
+	limiter := NewRateLimiter(1, 0)
+	limiter.Reserve(0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Reserve(0).Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to end with the context, got %v", err)
+	}
+
+	// The abandoned reservation is returned, so the next waits one slot only
+	if d := limiter.Reserve(0).Delay(); d > time.Minute || d < 59*time.Second {
+		t.Errorf("Expected one queued slot of about a minute, got %s", d)
+	}
+}
+
+func TestOrchestratorWaitsForShortRateLimit(t *testing.T) {
+	provider := &fakeProvider{name: "primary", content: "hello"}
+	orchestrator := newTestOrchestrator(t, provider, 0)
+	orchestrator.maxRateLimitWait = time.Second
+
+	// A short client-side wait is taken instead of falling back
+	orchestrator.limiter("primary").PauseFor(50 * time.Millisecond)
+
+	start := time.Now()
+	resp, err := orchestrator.Generate(context.Background(), &GenerateRequest{UserPrompt: "Hi"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Content != "hello" {
+		t.Errorf("Unexpected response %q", resp.Content)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Expected to wait for the rate limit, returned after %s", elapsed)
+	}
+}
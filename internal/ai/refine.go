@@ -0,0 +1,373 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DomainDelta is the change the AI proposes to an existing domain spec.
+// Entities and value objects list only what is new or changed: a listed
+// entity carries just its added or changed fields and methods. Remove holds
+// spec paths such as "entities.Customer.fields.Points", as reported by
+// DiffDomainSpecs.
+type DomainDelta struct {
+	Entities          []EntitySpec      `json:"entities,omitempty"`
+	ValueObjects      []ValueObjectSpec `json:"value_objects,omitempty"`
+	RepositoryMethods []InterfaceMethod `json:"repository_methods,omitempty"`
+	ServiceMethods    []InterfaceMethod `json:"service_methods,omitempty"`
+	Remove            []string          `json:"remove,omitempty"`
+}
+
+// DomainDeltaSchema is the JSON schema of DomainDelta. Unknown top-level
+// keys are rejected, so a full spec sent in place of a delta goes back for
+// repair instead of being applied in part.
+var DomainDeltaSchema = func() *JSONSchema {
+	schema := SchemaFor(DomainDelta{}, "domain_delta")
+	closed := false
+	schema.AdditionalProperties = &closed
+	return schema
+}()
+
+// ParseDomainDelta parses the AI response into a DomainDelta
+func ParseDomainDelta(content string) (*DomainDelta, error) {
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+	content = strings.TrimSpace(content)
+
+	if err := DomainDeltaSchema.ValidateJSON([]byte(content)); err != nil {
+		var schemaErrs SchemaErrors
+		if errors.As(err, &schemaErrs) {
+			return nil, schemaErrs
+		}
+		return nil, err
+	}
+
+	var delta DomainDelta
+	if err := json.Unmarshal([]byte(content), &delta); err != nil {
+		return nil, fmt.Errorf("parse JSON: %w", err)
+	}
+
+	return &delta, nil
+}
+
+// Apply returns a copy of spec with the delta applied. Elements are matched
+// by name, case-insensitively; a path in Remove that names nothing is an
+// error so that a misunderstood removal is not silently ignored.
+func (d *DomainDelta) Apply(spec *DomainSpec) (*DomainSpec, error) {
+	refined := cloneSpec(spec)
+
+	for _, e := range d.Entities {
+		i := indexByName(refined.Entities, e.Name, func(e EntitySpec) string { return e.Name })
+		if i < 0 {
+			refined.Entities = append(refined.Entities, e)
+			continue
+		}
+
+		current := &refined.Entities[i]
+		current.IsAggregateRoot = current.IsAggregateRoot || e.IsAggregateRoot
+		current.Fields = upsert(current.Fields, e.Fields, func(f FieldSpec) string { return f.Name })
+		current.Methods = upsert(current.Methods, e.Methods, func(m MethodSpec) string { return m.Name })
+	}
+
+	for _, vo := range d.ValueObjects {
+		i := indexByName(refined.ValueObjects, vo.Name, func(v ValueObjectSpec) string { return v.Name })
+		if i < 0 {
+			refined.ValueObjects = append(refined.ValueObjects, vo)
+			continue
+		}
+
+		current := &refined.ValueObjects[i]
+		current.Fields = upsert(current.Fields, vo.Fields, func(f FieldSpec) string { return f.Name })
+		if vo.Validation != "" {
+			current.Validation = vo.Validation
+		}
+	}
+
+	methodName := func(m InterfaceMethod) string { return m.Name }
+	refined.RepositoryInterface.Methods = upsert(refined.RepositoryInterface.Methods, d.RepositoryMethods, methodName)
+	refined.ServiceInterface.Methods = upsert(refined.ServiceInterface.Methods, d.ServiceMethods, methodName)
+
+	for _, path := range d.Remove {
+		if !removePath(refined, path) {
+			return nil, fmt.Errorf("remove %q: no such element in the current spec", path)
+		}
+	}
+
+	return refined, nil
+}
+
+// removedTypes returns the qualified names of the entities and value objects
+// the delta removes, e.g. entity.Customer
+func (d *DomainDelta) removedTypes() []string {
+	var names []string
+	for _, path := range d.Remove {
+		parts := strings.Split(path, ".")
+		if len(parts) != 2 {
+			continue
+		}
+		switch parts[0] {
+		case "entities":
+			names = append(names, "entity."+parts[1])
+		case "value_objects":
+			names = append(names, "valueobject."+parts[1])
+		}
+	}
+	return names
+}
+
+// removePath deletes the element at a spec path, reporting whether it existed
+func removePath(spec *DomainSpec, path string) bool {
+	parts := strings.Split(path, ".")
+	entityName := func(e EntitySpec) string { return e.Name }
+	voName := func(v ValueObjectSpec) string { return v.Name }
+	fieldName := func(f FieldSpec) string { return f.Name }
+	methodName := func(m MethodSpec) string { return m.Name }
+	interfaceMethodName := func(m InterfaceMethod) string { return m.Name }
+
+	switch {
+	case len(parts) == 2 && parts[0] == "entities":
+		return removeNamed(&spec.Entities, parts[1], entityName)
+
+	case len(parts) == 4 && parts[0] == "entities":
+		i := indexByName(spec.Entities, parts[1], entityName)
+		if i < 0 {
+			return false
+		}
+		switch parts[2] {
+		case "fields":
+			return removeNamed(&spec.Entities[i].Fields, parts[3], fieldName)
+		case "methods":
+			return removeNamed(&spec.Entities[i].Methods, parts[3], methodName)
+		}
+
+	case len(parts) == 2 && parts[0] == "value_objects":
+		return removeNamed(&spec.ValueObjects, parts[1], voName)
+
+	case len(parts) == 4 && parts[0] == "value_objects" && parts[2] == "fields":
+		i := indexByName(spec.ValueObjects, parts[1], voName)
+		return i >= 0 && removeNamed(&spec.ValueObjects[i].Fields, parts[3], fieldName)
+
+	case len(parts) == 3 && parts[0] == "repository_interface" && parts[1] == "methods":
+		return removeNamed(&spec.RepositoryInterface.Methods, parts[2], interfaceMethodName)
+
+	case len(parts) == 3 && parts[0] == "service_interface" && parts[1] == "methods":
+		return removeNamed(&spec.ServiceInterface.Methods, parts[2], interfaceMethodName)
+	}
+
+	return false
+}
+
+// indexByName finds an element by case-insensitive name, or returns -1
+func indexByName[T any](elements []T, name string, nameOf func(T) string) int {
+	for i, e := range elements {
+		if strings.EqualFold(nameOf(e), name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// upsert replaces elements with the same name and appends new ones
+func upsert[T any](elements, changes []T, nameOf func(T) string) []T {
+	for _, change := range changes {
+		if i := indexByName(elements, nameOf(change), nameOf); i >= 0 {
+			elements[i] = change
+		} else {
+			elements = append(elements, change)
+		}
+	}
+	return elements
+}
+
+// removeNamed deletes the named element, reporting whether it existed
+func removeNamed[T any](elements *[]T, name string, nameOf func(T) string) bool {
+	i := indexByName(*elements, name, nameOf)
+	if i < 0 {
+		return false
+	}
+	*elements = append((*elements)[:i], (*elements)[i+1:]...)
+	return true
+}
+
+// cloneSpec deep-copies a spec so a delta never modifies the original
+func cloneSpec(spec *DomainSpec) *DomainSpec {
+	data, _ := json.Marshal(spec)
+	var clone DomainSpec
+	json.Unmarshal(data, &clone)
+	return &clone
+}
+
+// Spec change kinds
+const (
+	ChangeAdded   = "added"
+	ChangeChanged = "changed"
+	ChangeRemoved = "removed"
+)
+
+// SpecChange is one element added, changed or removed between two specs
+type SpecChange struct {
+	Kind string
+	Path string // e.g. "entities.Customer.fields.Points"
+	Old  string // Type, signature or name before the change, empty if added
+	New  string // Type, signature or name after the change, empty if removed
+}
+
+// CompareDomainSpecs lists what changed from before to after, removals
+// first and then additions and changes in document order. The fields and
+// methods of an added or removed entity or value object are not listed
+// separately.
+func CompareDomainSpecs(before, after *DomainSpec) []SpecChange {
+	beforeItems, afterItems := flattenSpec(before), flattenSpec(after)
+
+	index := func(items []specItem) map[string]specItem {
+		m := make(map[string]specItem)
+		for _, item := range items {
+			m[item.key] = item
+		}
+		return m
+	}
+	old, current := index(beforeItems), index(afterItems)
+
+	// whole holds the elements added or removed as a unit
+	var whole []string
+	partOfWhole := func(key string) bool {
+		for _, w := range whole {
+			if strings.HasPrefix(key, w+".") {
+				return true
+			}
+		}
+		return false
+	}
+
+	var removed, changed []SpecChange
+	for _, item := range beforeItems {
+		if _, ok := current[item.key]; !ok && !partOfWhole(item.key) {
+			removed = append(removed, SpecChange{Kind: ChangeRemoved, Path: item.path, Old: item.value})
+			whole = append(whole, item.key)
+		}
+	}
+
+	for _, item := range afterItems {
+		prev, ok := old[item.key]
+		switch {
+		case partOfWhole(item.key):
+		case !ok:
+			changed = append(changed, SpecChange{Kind: ChangeAdded, Path: item.path, New: item.value})
+			whole = append(whole, item.key)
+		case !allEqual([]string{prev.value, item.value}):
+			changed = append(changed, SpecChange{Kind: ChangeChanged, Path: item.path, Old: prev.value, New: item.value})
+		}
+	}
+
+	return append(removed, changed...)
+}
+
+// RefineDomain asks the AI how to change an existing domain spec and
+// returns the refined spec with the changes it makes. Deltas that do not
+// parse, do not apply, or produce an invalid spec are sent back for repair.
+func RefineDomain(ctx context.Context, orchestrator *Orchestrator, current *DomainSpec, change string) (*DomainSpec, []SpecChange, error) {
+	req, err := refineRequest(ctx, orchestrator.prompts, current, change)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Deltas that need repair are not cached
+	ctx = WithResponseCheck(ctx, func(resp *GenerateResponse) error {
+		_, err := applyDelta(ctx, current, resp.Content)
+		return err
+	})
+
+	for attempt := 0; ; attempt++ {
+		resp, err := orchestrator.Generate(ctx, req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("generate: %w", err)
+		}
+
+		refined, err := applyDelta(ctx, current, resp.Content)
+		if err == nil {
+			return refined, CompareDomainSpecs(current, refined), nil
+		}
+
+		if attempt >= orchestrator.maxRepairs {
+			return nil, nil, fmt.Errorf("parse delta: %w", err)
+		}
+
+		orchestrator.logger.Warn("domain delta invalid, requesting repair",
+			"attempt", attempt+1,
+			"max_repairs", orchestrator.maxRepairs,
+			"provider", resp.Provider,
+			"error", err,
+		)
+
+		req.Metadata[MetadataRepair] = strconv.Itoa(attempt + 1)
+		req.Messages = repairTurns(resp.Content, err,
+			"Return the corrected delta only, with the keys described above. Do not return the full spec.")
+	}
+}
+
+// applyDelta parses a delta response and validates the spec it produces
+func applyDelta(ctx context.Context, current *DomainSpec, content string) (*DomainSpec, error) {
+	delta, err := ParseDomainDelta(content)
+	if err != nil {
+		return nil, err
+	}
+
+	refined, err := delta.Apply(current)
+	if err != nil {
+		return nil, err
+	}
+
+	// Types the delta removes still exist in the code it was summarized
+	// from, so they must not satisfy references in the refined spec
+	existing := coreSummaryFrom(ctx).Without(delta.removedTypes()...)
+	if err := ValidateDomainSpec(refined, existing); err != nil {
+		return nil, err
+	}
+	return refined, nil
+}
+
+// refineRequest builds the request for a delta to the current spec
+func refineRequest(ctx context.Context, prompts *PromptLoader, current *DomainSpec, change string) (*GenerateRequest, error) {
+	specJSON, err := json.MarshalIndent(current, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal spec: %w", err)
+	}
+
+	data := RefinePromptData{
+		Change:         change,
+		Spec:           string(specJSON),
+		ProjectContext: projectContextFrom(ctx),
+	}
+
+	system, err := prompts.Load(PromptRefineSystem)
+	if err != nil {
+		return nil, err
+	}
+	user, err := prompts.Load(PromptDomainRefine)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &GenerateRequest{
+		PromptVersion:  system.CacheVersion() + "," + user.CacheVersion(),
+		Temperature:    0.3,
+		MaxTokens:      4000,
+		TopP:           0.9,
+		Metadata:       map[string]string{MetadataTask: TaskRefine},
+		ResponseSchema: DomainDeltaSchema,
+	}
+	if req.SystemPrompt, err = system.Render(data); err != nil {
+		return nil, err
+	}
+	if req.UserPrompt, err = user.Render(data); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
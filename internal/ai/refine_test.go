@@ -0,0 +1,158 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func refineBaseSpec(t *testing.T) *DomainSpec {
+	t.Helper()
+	spec, err := ParseDomainSpec(validSpecJSON)
+	if err != nil {
+		t.Fatalf("ParseDomainSpec failed: %v", err)
+	}
+	return spec
+}
+
+func TestDomainDeltaApply(t *testing.T) {
+	current := refineBaseSpec(t)
+
+	delta := &DomainDelta{
+		Entities: []EntitySpec{{
+			Name:    "order",
+			Fields:  []FieldSpec{{Name: "ID", Type: "string"}, {Name: "Points", Type: "int"}},
+			Methods: []MethodSpec{{Name: "Upgrade", Signature: "func (o *Order) Upgrade() error"}},
+		}},
+		ValueObjects:   []ValueObjectSpec{{Name: "Tier", Fields: []FieldSpec{{Name: "Level", Type: "int"}}}},
+		ServiceMethods: []InterfaceMethod{{Name: "Upgrade", Signature: "Upgrade(ctx context.Context, id uuid.UUID) error"}},
+		Remove:         []string{"entities.Order.methods.Cancel", "repository_interface.methods.Save"},
+	}
+
+	refined, err := delta.Apply(current)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	order := refined.Entities[0]
+	if len(order.Fields) != 2 || order.Fields[0].Type != "string" {
+		t.Errorf("Expected ID to change and Points to be added, got %+v", order.Fields)
+	}
+	if len(order.Methods) != 1 || order.Methods[0].Name != "Upgrade" {
+		t.Errorf("Expected Cancel to be replaced by Upgrade, got %+v", order.Methods)
+	}
+	if len(refined.RepositoryInterface.Methods) != 0 || len(refined.ServiceInterface.Methods) != 1 {
+		t.Errorf("Unexpected interfaces %+v / %+v", refined.RepositoryInterface, refined.ServiceInterface)
+	}
+	if current.Entities[0].Fields[0].Type != "uuid.UUID" || len(current.Entities[0].Methods) != 1 {
+		t.Error("Expected the current spec to be left unchanged")
+	}
+
+	changes := CompareDomainSpecs(current, refined)
+	expected := []SpecChange{
+		{Kind: ChangeRemoved, Path: "entities.Order.methods.Cancel", Old: "func (o *Order) Cancel() error"},
+		{Kind: ChangeRemoved, Path: "repository_interface.methods.Save", Old: "Save(ctx context.Context, order *entity.Order) error"},
+		{Kind: ChangeChanged, Path: "entities.Order.fields.ID", Old: "uuid.UUID", New: "string"},
+		{Kind: ChangeAdded, Path: "entities.Order.fields.Points", New: "int"},
+		{Kind: ChangeAdded, Path: "entities.Order.methods.Upgrade", New: "func (o *Order) Upgrade() error"},
+		{Kind: ChangeAdded, Path: "value_objects.Tier", New: "Tier"},
+		{Kind: ChangeAdded, Path: "service_interface.methods.Upgrade", New: "Upgrade(ctx context.Context, id uuid.UUID) error"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %+v", len(expected), changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("Change %d: expected %+v, got %+v", i, expected[i], changes[i])
+		}
+	}
+}
+
+func TestDomainDeltaApplyUnknownRemoval(t *testing.T) {
+	delta := &DomainDelta{Remove: []string{"entities.Order.fields.Missing"}}
+	if _, err := delta.Apply(refineBaseSpec(t)); err == nil || !strings.Contains(err.Error(), "Missing") {
+		t.Errorf("Expected an error naming the missing element, got %v", err)
+	}
+}
+
+func TestRefineDomainRepairsInvalidDelta(t *testing.T) {
+	provider := &scriptedProvider{
+		fakeProvider: fakeProvider{name: "scripted"},
+		replies: []string{
+			`{"remove": ["entities.Order.fields.Nope"]}`,
+			`{"entities": [{"name": "Order", "fields": [{"name": "Points", "type": "int"}]}]}`,
+		},
+	}
+
+	orchestrator := newTestOrchestrator(t, provider, 1)
+	orchestrator.cache = NewCache(t.TempDir(), time.Hour, 0, true)
+
+	refined, changes, err := RefineDomain(context.Background(), orchestrator, refineBaseSpec(t), "add loyalty points")
+	if err != nil {
+		t.Fatalf("RefineDomain failed: %v", err)
+	}
+
+	if len(refined.Entities[0].Fields) != 2 {
+		t.Errorf("Expected Points to be added, got %+v", refined.Entities[0].Fields)
+	}
+	if len(changes) != 1 || changes[0].Path != "entities.Order.fields.Points" {
+		t.Errorf("Unexpected changes %+v", changes)
+	}
+
+	if len(provider.prompts) != 2 {
+		t.Fatalf("Expected one repair, got %d prompts", len(provider.prompts))
+	}
+	if !strings.Contains(provider.prompts[0], "add loyalty points") || !strings.Contains(provider.prompts[0], `"domain_name": "order"`) {
+		t.Errorf("Expected the change and current spec in the prompt, got %q", provider.prompts[0])
+	}
+	if !strings.Contains(provider.prompts[1], "entities.Order.fields.Nope") || !strings.Contains(provider.prompts[1], "corrected delta") {
+		t.Errorf("Expected the repair prompt to quote the error and ask for a delta, got %q", provider.prompts[1])
+	}
+	if system := provider.requests[0].SystemPrompt; !strings.Contains(system, "JSON delta") || strings.Contains(system, "EXACT structure") {
+		t.Errorf("Expected the refine system prompt to ask for a delta, got %q", system)
+	}
+
+	// The rejected delta is not cached
+	if entries, _ := orchestrator.cache.Entries(); len(entries) != 1 {
+		t.Errorf("Expected only the accepted delta to be cached, got %d entries", len(entries))
+	}
+}
+
+func TestParseDomainDeltaRejectsFullSpec(t *testing.T) {
+	_, err := ParseDomainDelta(validSpecJSON)
+
+	var schemaErrs SchemaErrors
+	if !errors.As(err, &schemaErrs) {
+		t.Fatalf("Expected a full spec to be rejected as a delta, got %v", err)
+	}
+	for _, key := range []string{"domain_name", "repository_interface", "service_interface"} {
+		if !strings.Contains(err.Error(), key+": unknown field") {
+			t.Errorf("Expected %s to be reported, got %v", key, err)
+		}
+	}
+}
+
+func TestRefineDomainRejectsReferenceToRemovedType(t *testing.T) {
+	current := refineBaseSpec(t)
+	current.Entities = append(current.Entities, EntitySpec{Name: "Customer", Fields: []FieldSpec{{Name: "ID", Type: "uuid.UUID"}}})
+	current.Entities[0].Fields = append(current.Entities[0].Fields, FieldSpec{Name: "Buyer", Type: "*entity.Customer"})
+
+	// The project code was generated from the current spec, so Customer
+	// is in the summary until the refinement is applied
+	summary := &CoreSummary{Types: []CoreType{
+		{Package: "entity", Name: "Order", Kind: "struct"},
+		{Package: "entity", Name: "Customer", Kind: "struct"},
+	}}
+	ctx := WithProjectContext(context.Background(), summary)
+
+	_, err := applyDelta(ctx, current, `{"remove": ["entities.Customer"]}`)
+	if err == nil || !strings.Contains(err.Error(), "entity.Customer") {
+		t.Errorf("Expected the dangling reference to entity.Customer to be reported, got %v", err)
+	}
+
+	if _, err := applyDelta(ctx, current, `{"remove": ["entities.Customer", "entities.Order.fields.Buyer"]}`); err != nil {
+		t.Errorf("Expected the removal with its reference to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// DefaultMaxRepairs is the number of repair rounds used when not configured
+const DefaultMaxRepairs = 2
+
+// MetadataRepair is the GenerateRequest metadata key holding the repair
+// round (1, 2, ...) of a follow-up request. It is recorded in the usage
+// ledger so repair frequency can be reported later.
+const MetadataRepair = "repair"
+
+// RepairAttempt records one round of domain spec generation. Attempt 0 is the
+// original request; later attempts are follow-ups carrying the previous error.
+type RepairAttempt struct {
+	Attempt  int
+	Provider string
+	Model    string
+	Duration time.Duration
+	Error    string // Problem found in this attempt's output, empty if it was accepted
+}
+
+// Repaired reports whether the spec needed at least one follow-up
+func Repaired(attempts []RepairAttempt) bool {
+	return len(attempts) > 1 && attempts[len(attempts)-1].Error == ""
+}
+
+// GenerateDomainWithRepair generates a domain spec, feeding parse and
+// validation errors back to the provider up to the configured number of times
+func GenerateDomainWithRepair(ctx context.Context, orchestrator *Orchestrator, description string) (*DomainSpec, []RepairAttempt, error) {
+	ctx = withDomainSpecCheck(ctx)
+	req, err := domainRequest(ctx, orchestrator.prompts, description)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var resp *GenerateResponse
+	if orchestrator.hedgeDelay > 0 {
+		// Race providers; only a response that parses can win
+		resp, err = orchestrator.GenerateHedged(ctx, req, func(r *GenerateResponse) error {
+			_, err := ParseDomainSpec(r.Content)
+			return err
+		})
+	} else {
+		resp, err = orchestrator.Generate(ctx, req)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate: %w", err)
+	}
+
+	return RepairDomainSpec(ctx, orchestrator, description, resp)
+}
+
+// RepairDomainSpec checks a generated response and, if it does not parse or
+// fails ValidateDomainSpec, asks the provider to fix it. Every attempt,
+// including the original response, is returned.
+func RepairDomainSpec(ctx context.Context, orchestrator *Orchestrator, description string, resp *GenerateResponse) (*DomainSpec, []RepairAttempt, error) {
+	ctx = withDomainSpecCheck(ctx)
+	var attempts []RepairAttempt
+
+	for attempt := 0; ; attempt++ {
+		spec, err := checkDomainSpec(ctx, resp.Content)
+
+		record := RepairAttempt{
+			Attempt:  attempt,
+			Provider: resp.Provider,
+			Model:    resp.Model,
+			Duration: resp.Duration,
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		attempts = append(attempts, record)
+
+		if err == nil {
+			if attempt > 0 {
+				orchestrator.logger.Info("domain spec repaired",
+					"attempts", attempt,
+					"provider", resp.Provider,
+				)
+			}
+			return spec, attempts, nil
+		}
+
+		if attempt >= orchestrator.maxRepairs {
+			return nil, attempts, fmt.Errorf("parse spec: %w", err)
+		}
+
+		orchestrator.logger.Warn("domain spec invalid, requesting repair",
+			"attempt", attempt+1,
+			"max_repairs", orchestrator.maxRepairs,
+			"provider", resp.Provider,
+			"error", err,
+		)
+
+		req, err := repairRequest(ctx, orchestrator.prompts, description, resp.Content, err, attempt+1)
+		if err != nil {
+			return nil, attempts, err
+		}
+
+		resp, err = orchestrator.Generate(ctx, req)
+		if err != nil {
+			return nil, attempts, fmt.Errorf("repair: %w", err)
+		}
+	}
+}
+
+// checkDomainSpec parses a domain spec response and validates it against
+// the core types attached to ctx
+func checkDomainSpec(ctx context.Context, content string) (*DomainSpec, error) {
+	spec, err := ParseDomainSpec(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateDomainSpec(spec, coreSummaryFrom(ctx)); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// withDomainSpecCheck keeps domain specs that need repair out of the cache
+func withDomainSpecCheck(ctx context.Context) context.Context {
+	return WithResponseCheck(ctx, func(resp *GenerateResponse) error {
+		_, err := checkDomainSpec(ctx, resp.Content)
+		return err
+	})
+}
+
+// repairRequest builds the follow-up asking the provider to fix its output
+func repairRequest(ctx context.Context, prompts *PromptLoader, description, previous string, problem error, round int) (*GenerateRequest, error) {
+	req, err := domainRequest(ctx, prompts, description)
+	if err != nil {
+		return nil, err
+	}
+	req.Metadata[MetadataRepair] = strconv.Itoa(round)
+	req.Messages = repairTurns(previous, problem,
+		"Return the corrected, complete JSON document only. Fix the error without changing anything else.")
+	return req, nil
+}
+
+// repairTurns continues a request with the rejected response as the
+// assistant's turn and the error it was rejected with as the user's reply
+func repairTurns(previous string, problem error, instruction string) []Message {
+	var turns []Message
+	if previous != "" {
+		turns = append(turns, Message{Role: RoleAssistant, Content: previous})
+	}
+	return append(turns, Message{
+		Role:    RoleUser,
+		Content: fmt.Sprintf("Your response was rejected with this error:\n%s\n\n%s", problem, instruction),
+	})
+}
+
+// repairRound returns the repair round a request declares, 0 for a first
+// attempt
+func repairRound(req *GenerateRequest) int {
+	if req == nil {
+		return 0
+	}
+	round, _ := strconv.Atoi(req.Metadata[MetadataRepair])
+	return round
+}
@@ -0,0 +1,158 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedProvider replies with the given contents in order
+type scriptedProvider struct {
+	fakeProvider
+	replies  []string
+	prompts  []string // The last turn of each request
+	requests []*GenerateRequest
+}
+
+func (s *scriptedProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	conversation := req.Conversation()
+	s.prompts = append(s.prompts, conversation[len(conversation)-1].Content)
+	s.requests = append(s.requests, req)
+	reply := s.replies[0]
+	if len(s.replies) > 1 {
+		s.replies = s.replies[1:]
+	}
+	return &GenerateResponse{Content: reply, Provider: s.name}, nil
+}
+
+const validSpecJSON = `{
+	"domain_name": "order",
+	"entities": [{"name": "Order", "fields": [{"name": "ID", "type": "uuid.UUID"}],
+		"methods": [{"name": "Cancel", "signature": "func (o *Order) Cancel() error"}]}],
+	"repository_interface": {"name": "OrderRepository", "methods": [{"name": "Save", "signature": "Save(ctx context.Context, order *entity.Order) error"}]},
+	"service_interface": {"name": "OrderService", "methods": []}
+}`
+
+func newTestOrchestrator(t *testing.T, provider Provider, maxRepairs int) *Orchestrator {
+	return &Orchestrator{
+		providers:       map[string]Provider{provider.Name(): provider},
+		primaryProvider: provider.Name(),
+		cache:           NewCache(t.TempDir(), time.Hour, 0, false),
+		health:          NewHealthTracker(BreakerConfig{}),
+		logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+		maxRepairs:      maxRepairs,
+	}
+}
+
+func TestGenerateDomainWithRepair(t *testing.T) {
+	badSignature := strings.Replace(validSpecJSON, "Save(ctx context.Context, order *entity.Order) error", "Save(ctx context.Context, order *entity.Order error", 1)
+	provider := &scriptedProvider{
+		fakeProvider: fakeProvider{name: "scripted"},
+		replies:      []string{`{"domain_name": "order",`, badSignature, validSpecJSON},
+	}
+
+	orchestrator := newTestOrchestrator(t, provider, 2)
+	orchestrator.usage = NewUsageLedger(filepath.Join(t.TempDir(), "usage.jsonl"))
+
+	spec, attempts, err := GenerateDomainWithRepair(context.Background(), orchestrator, "Order")
+	if err != nil {
+		t.Fatalf("Expected repair to succeed, got %v", err)
+	}
+
+	if spec.DomainName != "order" {
+		t.Errorf("Expected domain 'order', got %q", spec.DomainName)
+	}
+	if len(attempts) != 3 || !Repaired(attempts) {
+		t.Fatalf("Expected 3 recorded attempts ending in success, got %+v", attempts)
+	}
+	if !strings.Contains(attempts[1].Error, "repository_interface.methods[0].signature") {
+		t.Errorf("Expected signature error in second attempt, got %q", attempts[1].Error)
+	}
+
+	// Follow-ups must carry the previous problem back to the provider
+	if !strings.Contains(provider.prompts[1], "parse JSON") {
+		t.Errorf("Expected parse error in first repair prompt, got %q", provider.prompts[1])
+	}
+	if !strings.Contains(provider.prompts[2], "invalid Go") {
+		t.Errorf("Expected signature error in second repair prompt, got %q", provider.prompts[2])
+	}
+
+	// The rejected output is sent back as the assistant's turn
+	repair := provider.requests[2]
+	if repair.UserPrompt != provider.requests[0].UserPrompt {
+		t.Error("Expected the repair to keep the original request")
+	}
+	if len(repair.Messages) != 2 || repair.Messages[0].Role != RoleAssistant || repair.Messages[0].Content != badSignature {
+		t.Errorf("Expected the rejected spec as an assistant turn, got %+v", repair.Messages)
+	}
+
+	// Repairs are marked in the usage ledger so their frequency can be reported
+	records, err := orchestrator.usage.Records(time.Time{})
+	if err != nil || len(records) != 3 {
+		t.Fatalf("Expected three usage records, got %v (%v)", records, err)
+	}
+	for i, r := range records {
+		if r.Repair != i {
+			t.Errorf("Expected record %d to be repair round %d, got %d", i, i, r.Repair)
+		}
+	}
+	groups, _ := SummarizeUsage(records, "provider")
+	if len(groups) != 1 || groups[0].Requests != 3 || groups[0].Repairs != 2 {
+		t.Errorf("Expected 3 requests with 2 repairs, got %+v", groups)
+	}
+}
+
+func TestGenerateDomainRepairLimit(t *testing.T) {
+	provider := &scriptedProvider{
+		fakeProvider: fakeProvider{name: "scripted"},
+		replies:      []string{"not json"},
+	}
+
+	_, attempts, err := GenerateDomainWithRepair(context.Background(), newTestOrchestrator(t, provider, 1), "Order")
+	if err == nil {
+		t.Fatal("Expected an error once repairs are exhausted")
+	}
+	if len(attempts) != 2 {
+		t.Errorf("Expected 2 attempts, got %d", len(attempts))
+	}
+	if Repaired(attempts) {
+		t.Error("Expected attempts not to be reported as repaired")
+	}
+}
+
+func TestGenerateDomainRepairSkipsCacheForRejectedOutput(t *testing.T) {
+	invalid := `{"domain_name": "order",`
+	provider := &scriptedProvider{
+		fakeProvider: fakeProvider{name: "scripted"},
+		replies:      []string{invalid, validSpecJSON},
+	}
+	orchestrator := newTestOrchestrator(t, provider, 2)
+	orchestrator.cache = NewCache(t.TempDir(), time.Hour, 0, true)
+
+	if _, _, err := GenerateDomainWithRepair(context.Background(), orchestrator, "Order"); err != nil {
+		t.Fatalf("Expected repair to succeed, got %v", err)
+	}
+
+	// The rejected response is sent back once, not repeated in the error
+	if strings.Count(provider.requests[1].Messages[1].Content, invalid) != 0 {
+		t.Errorf("Expected the repair prompt not to repeat the response, got %q", provider.requests[1].Messages[1].Content)
+	}
+
+	// Only the accepted repair is cached, so a rerun asks for a fresh spec
+	entries, err := orchestrator.cache.Entries()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected only the accepted response to be cached, got %d entries (%v)", len(entries), err)
+	}
+	provider.replies = []string{validSpecJSON}
+	_, attempts, err := GenerateDomainWithRepair(context.Background(), orchestrator, "Order")
+	if err != nil || len(attempts) != 1 {
+		t.Errorf("Expected a fresh valid spec without repairs, got %+v (%v)", attempts, err)
+	}
+	if len(provider.requests) != 3 {
+		t.Errorf("Expected the original request to reach the provider again, got %d requests", len(provider.requests))
+	}
+}
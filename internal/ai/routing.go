@@ -0,0 +1,69 @@
+package ai
+
+import (
+	"slices"
+	"strings"
+)
+
+// MetadataTask is the GenerateRequest metadata key naming what a request is
+// for. AIConfig.Routing picks the providers tried for each task.
+const MetadataTask = "task"
+
+// Tasks of the built-in AI calls
+const (
+	TaskDomain = "domain" // Domain spec generation and its repairs
+	TaskRefine = "refine" // Domain refinement deltas
+	TaskHealth = "health" // Provider health checks
+)
+
+// splitRoute splits a routing entry such as groq/llama-3.1-8b-instant into
+// the provider and the model, which is empty when the entry names only a
+// provider. Model names may contain slashes themselves.
+func splitRoute(entry string) (provider, model string) {
+	provider, model, _ = strings.Cut(entry, "/")
+	return provider, model
+}
+
+// baseProvider returns the provider a chain entry belongs to
+func baseProvider(entry string) string {
+	provider, _ := splitRoute(entry)
+	return provider
+}
+
+// taskOf returns the task a request declares, or an empty string
+func taskOf(req *GenerateRequest) string {
+	if req == nil {
+		return ""
+	}
+	return req.Metadata[MetadataTask]
+}
+
+// route returns the providers to try for a request: the routing rule of its
+// task, or the primary and fallback providers
+func (o *Orchestrator) route(req *GenerateRequest) []string {
+	task := taskOf(req)
+	if route := o.routes[task]; len(route) > 0 {
+		o.logger.Debug("routing request by task",
+			"task", task,
+			"chain", route,
+		)
+		return slices.Clone(route)
+	}
+
+	providerChain := []string{o.primaryProvider}
+	return append(providerChain, o.fallbackChain...)
+}
+
+// healthTarget returns the provider used to check name's health: the first
+// entry of the health route for that provider, or the provider itself
+func (o *Orchestrator) healthTarget(name string) Provider {
+	for _, entry := range o.routes[TaskHealth] {
+		if baseProvider(entry) != name {
+			continue
+		}
+		if provider, exists := o.providers[entry]; exists {
+			return provider
+		}
+	}
+	return o.providers[name]
+}
@@ -0,0 +1,112 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOrchestratorRoutesByTask(t *testing.T) {
+	primary := &fakeProvider{name: "primary", content: "from primary"}
+	cheap := &fakeProvider{name: "cheap", content: "from cheap"}
+	strong := &fakeProvider{name: "strong", content: "from strong"}
+
+	o := newTestOrchestrator(t, primary, 0)
+	o.providers["cheap"] = cheap
+	o.providers["strong"] = strong
+	o.routes = map[string][]string{
+		TaskDomain: {"strong", "cheap"},
+		TaskHealth: {"cheap"},
+	}
+
+	tests := []struct {
+		name string
+		task string
+		want string
+	}{
+		{"routed task", TaskDomain, "from strong"},
+		{"task without a rule", TaskRefine, "from primary"},
+		{"no task", "", "from primary"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &GenerateRequest{UserPrompt: "Order " + tt.name}
+			if tt.task != "" {
+				req.Metadata = map[string]string{MetadataTask: tt.task}
+			}
+
+			resp, err := o.Generate(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			if resp.Content != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, resp.Content)
+			}
+		})
+	}
+
+	// A failing entry falls back along the task's route, not the default chain
+	strong.err = context.DeadlineExceeded
+	req := &GenerateRequest{UserPrompt: "Cart", Metadata: map[string]string{MetadataTask: TaskDomain}}
+	resp, err := o.Generate(context.Background(), req)
+	if err != nil || resp.Content != "from cheap" {
+		t.Errorf("Expected the next entry of the route, got %v, %v", resp, err)
+	}
+}
+
+func TestNewOrchestratorRoutedModels(t *testing.T) {
+	cfg := &Config{
+		AI: AIConfig{
+			PrimaryProvider: "groq",
+			Providers: ProvidersConfig{
+				Groq:   ProviderConfig{Enabled: true, APIKey: "gsk-test", Model: "llama-large", Timeout: time.Second, RequestsPerMinute: 30},
+				Ollama: ProviderConfig{Enabled: true, Model: "qwen", BaseURL: "http://localhost:11434", Timeout: time.Second},
+			},
+			OpenAICompatible: []OpenAICompatibleConfig{{Name: "vllm", BaseURL: "http://localhost:8000/v1", Model: "default"}},
+			Routing: map[string][]string{
+				TaskDomain: {"groq/llama-small", "vllm/meta-llama/Llama-3-8B", "ollama", "mistral"},
+				TaskHealth: {"groq/llama-tiny"},
+			},
+		},
+		Cache: CacheConfig{Enabled: false, Directory: t.TempDir()},
+	}
+
+	o, err := NewOrchestrator(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewOrchestrator failed: %v", err)
+	}
+
+	models := map[string]string{
+		"groq":                       "llama-large",
+		"groq/llama-small":           "llama-small",
+		"vllm/meta-llama/Llama-3-8B": "meta-llama/Llama-3-8B",
+		"ollama":                     "qwen",
+	}
+	for name, want := range models {
+		provider, exists := o.providers[name]
+		if !exists {
+			t.Errorf("Expected provider %s", name)
+			continue
+		}
+		if provider.Model() != want {
+			t.Errorf("Expected %s to use model %q, got %q", name, want, provider.Model())
+		}
+	}
+
+	if o.limiters["groq/llama-small"] != o.limiters["groq"] {
+		t.Error("Expected a routed model to share its provider's rate limits")
+	}
+	if got := o.healthTarget("groq").Model(); got != "llama-tiny" {
+		t.Errorf("Expected health checks on llama-tiny, got %q", got)
+	}
+
+	req := &GenerateRequest{Metadata: map[string]string{MetadataTask: TaskDomain}}
+	want := []string{"groq/llama-small", "vllm/meta-llama/Llama-3-8B", "ollama", "mistral"}
+	if chain := o.chain(req); !reflect.DeepEqual(chain, want) {
+		t.Errorf("Expected chain %v, got %v", want, chain)
+	}
+}
@@ -0,0 +1,263 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// JSONSchema is the subset of JSON Schema used to describe structured output
+type JSONSchema struct {
+	Name                 string                 `json:"-"` // Identifier for providers that require one
+	Type                 string                 `json:"type"`
+	Description          string                 `json:"description,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+}
+
+// SchemaFor derives a JSON schema from a Go value using its json tags.
+// Fields tagged omitempty are optional, all other fields are required.
+func SchemaFor(v any, name string) *JSONSchema {
+	schema := schemaForType(reflect.TypeOf(v))
+	schema.Name = name
+	return schema
+}
+
+func schemaForType(t reflect.Type) *JSONSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &JSONSchema{Type: "array", Items: schemaForType(t.Elem())}
+	case reflect.Map:
+		return &JSONSchema{Type: "object"}
+	case reflect.Struct:
+		schema := &JSONSchema{Type: "object", Properties: make(map[string]*JSONSchema)}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name, optional := parseJSONTag(field)
+			if name == "-" {
+				continue
+			}
+
+			schema.Properties[name] = schemaForType(field.Type)
+			if !optional {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+		return schema
+	default:
+		return &JSONSchema{Type: "string"}
+	}
+}
+
+// parseJSONTag returns the JSON property name and whether it is optional
+func parseJSONTag(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "" {
+		return field.Name, false
+	}
+
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			return name, true
+		}
+	}
+	return name, false
+}
+
+// Strict returns a copy of the schema in the form required by OpenAI's strict
+// structured outputs: every property is required and no extras are allowed.
+// Optional strings then come back empty, which decodes the same way.
+func (s *JSONSchema) Strict() *JSONSchema {
+	if s == nil {
+		return nil
+	}
+
+	strict := *s
+	strict.Items = s.Items.Strict()
+	strict.Required = nil
+
+	if s.Type == "object" {
+		closed := false
+		strict.AdditionalProperties = &closed
+		strict.Properties = make(map[string]*JSONSchema, len(s.Properties))
+		for name, prop := range s.Properties {
+			strict.Properties[name] = prop.Strict()
+			strict.Required = append(strict.Required, name)
+		}
+		sort.Strings(strict.Required)
+	}
+
+	return &strict
+}
+
+// SchemaError describes a single schema violation
+type SchemaError struct {
+	Path    string // Location in the document, e.g. entities[0].fields[1].type
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// SchemaErrors collects every violation found in a document
+type SchemaErrors []SchemaError
+
+func (e SchemaErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "schema validation failed:\n  " + strings.Join(msgs, "\n  ")
+}
+
+// ValidateJSON checks a raw JSON document against the schema
+func (s *JSONSchema) ValidateJSON(data []byte) error {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse JSON: %w", err)
+	}
+
+	var errs SchemaErrors
+	s.validate(doc, "$", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (s *JSONSchema) validate(value any, path string, errs *SchemaErrors) {
+	if !s.matchesType(value) {
+		*errs = append(*errs, SchemaError{
+			Path:    path,
+			Message: fmt.Sprintf("expected %s, got %s", s.Type, jsonTypeOf(value)),
+		})
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, SchemaError{Path: joinPath(path, name), Message: "required field is missing"})
+			}
+		}
+
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					*errs = append(*errs, SchemaError{Path: joinPath(path, name), Message: "unknown field"})
+				}
+				continue
+			}
+			// Optional fields may be null, it decodes to the zero value
+			if v[name] == nil && !containsString(s.Required, name) {
+				continue
+			}
+			prop.validate(v[name], joinPath(path, name), errs)
+		}
+
+	case []any:
+		if s.Items == nil {
+			return
+		}
+		for i, item := range v {
+			s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+func (s *JSONSchema) matchesType(value any) bool {
+	switch s.Type {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == float64(int64(f))
+	default:
+		return true
+	}
+}
+
+// jsonTypeOf names the JSON type of a decoded value
+func jsonTypeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "$" {
+		return name
+	}
+	return path + "." + name
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
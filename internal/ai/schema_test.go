@@ -0,0 +1,109 @@
+package ai
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDomainSpecSchema(t *testing.T) {
+	schema := DomainSpecSchema
+
+	if schema.Type != "object" {
+		t.Fatalf("Expected object schema, got %q", schema.Type)
+	}
+
+	for _, name := range []string{"domain_name", "entities", "repository_interface", "service_interface"} {
+		if !containsString(schema.Required, name) {
+			t.Errorf("Expected %s to be required", name)
+		}
+	}
+	if containsString(schema.Required, "value_objects") {
+		t.Error("Expected value_objects to be optional")
+	}
+
+	fields := schema.Properties["entities"].Items.Properties["fields"]
+	if fields.Type != "array" || fields.Items.Properties["type"].Type != "string" {
+		t.Errorf("Unexpected entity fields schema: %+v", fields)
+	}
+}
+
+func TestJSONSchemaStrict(t *testing.T) {
+	strict := DomainSpecSchema.Strict()
+
+	field := strict.Properties["entities"].Items.Properties["fields"].Items
+	if len(field.Required) != len(field.Properties) {
+		t.Errorf("Expected every property to be required, got %v", field.Required)
+	}
+	if field.AdditionalProperties == nil || *field.AdditionalProperties {
+		t.Error("Expected additional properties to be disallowed")
+	}
+
+	// The original schema must be left untouched
+	if DomainSpecSchema.AdditionalProperties != nil {
+		t.Error("Strict modified the original schema")
+	}
+}
+
+func TestParseDomainSpecSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		paths   []string
+	}{
+		{
+			name:    "wrong field type",
+			content: `{"domain_name": "cart", "entities": [{"name": "Cart", "fields": [{"name": "ID", "type": 1}]}], "repository_interface": {"name": "R", "methods": []}, "service_interface": {"name": "S", "methods": []}}`,
+			paths:   []string{"entities[0].fields[0].type"},
+		},
+		{
+			name:    "missing required fields",
+			content: "```json\n{\"domain_name\": \"cart\", \"entities\": [{\"fields\": []}]}\n```",
+			paths:   []string{"repository_interface", "service_interface", "entities[0].name"},
+		},
+		{
+			name:    "entities not an array",
+			content: `{"domain_name": "cart", "entities": {}, "repository_interface": {"name": "R", "methods": []}, "service_interface": {"name": "S", "methods": []}}`,
+			paths:   []string{"entities"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDomainSpec(tt.content)
+
+			var schemaErrs SchemaErrors
+			if !errors.As(err, &schemaErrs) {
+				t.Fatalf("Expected schema errors, got %v", err)
+			}
+
+			for _, path := range tt.paths {
+				found := false
+				for _, e := range schemaErrs {
+					if e.Path == path {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("Expected an error at %s, got %v", path, schemaErrs)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDomainSpecOptionalFields(t *testing.T) {
+	content := `{
+		"domain_name": "cart",
+		"entities": [{"name": "Cart", "fields": [{"name": "ID", "type": "string", "validation": null}]}],
+		"repository_interface": {"name": "CartRepository", "methods": [{"name": "Save", "signature": "Save(ctx context.Context, c *Cart) error"}]},
+		"service_interface": {"name": "CartService", "methods": []}
+	}`
+
+	spec, err := ParseDomainSpec(content)
+	if err != nil {
+		t.Fatalf("Expected optional fields to be accepted, got %v", err)
+	}
+	if spec.Entities[0].Fields[0].Name != "ID" {
+		t.Errorf("Unexpected spec: %+v", spec)
+	}
+}
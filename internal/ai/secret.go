@@ -0,0 +1,176 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Secret reference prefixes
+const (
+	secretFilePrefix = "file:"
+	secretCmdPrefix  = "cmd:"
+)
+
+// secretCmdTimeout bounds a cmd: helper such as a password manager CLI
+const secretCmdTimeout = 10 * time.Second
+
+// envPlaceholder matches ${NAME} in a secret value
+var envPlaceholder = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ConfigError is a configuration value that cannot be used
+type ConfigError struct {
+	Key string // Dotted config key, e.g. ai.providers.gemini.api_key
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Key, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ResolveSecret turns a configured secret into its value:
+//
+//	file:~/.secrets/groq   the file's contents, without the trailing newline
+//	cmd:pass show groq     the command's output, run with sh -c
+//	${GROQ_API_KEY}        environment variables, also inside a longer value
+//
+// Anything else is returned as is. A placeholder whose variable is not set
+// is an error rather than an empty key.
+func ResolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, secretFilePrefix):
+		path := expandHome(strings.TrimSpace(strings.TrimPrefix(value, secretFilePrefix)))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read secret file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+
+	case strings.HasPrefix(value, secretCmdPrefix):
+		return runSecretCommand(strings.TrimSpace(strings.TrimPrefix(value, secretCmdPrefix)))
+	}
+
+	var missing []string
+	resolved := envPlaceholder.ReplaceAllStringFunc(value, func(placeholder string) string {
+		name := envPlaceholder.FindStringSubmatch(placeholder)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok || v == "" {
+			missing = append(missing, placeholder)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%s is not set", strings.Join(missing, ", "))
+	}
+	return resolved, nil
+}
+
+// runSecretCommand runs a cmd: helper and returns its trimmed output
+func runSecretCommand(command string) (string, error) {
+	if command == "" {
+		return "", errors.New("cmd: needs a command")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretCmdTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("run %q: %w: %s", command, err, msg)
+		}
+		return "", fmt.Errorf("run %q: %w", command, err)
+	}
+
+	secret := strings.TrimSpace(stdout.String())
+	if secret == "" {
+		return "", fmt.Errorf("run %q: no output", command)
+	}
+	return secret, nil
+}
+
+// ResolveSecrets resolves the API keys of the enabled providers and of the
+// OpenAI-compatible endpoints in place. References in disabled providers are
+// dropped without being looked up, so a placeholder for a key that is not in
+// use is no error. Every key that cannot be resolved is reported as a
+// ConfigError.
+func (c *Config) ResolveSecrets() error {
+	var errs []error
+	resolve := func(key string, value *string) {
+		resolved, err := ResolveSecret(*value)
+		if err != nil {
+			errs = append(errs, &ConfigError{Key: key, Err: err})
+			return
+		}
+		*value = resolved
+	}
+
+	providers := []struct {
+		name   string
+		config *ProviderConfig
+	}{
+		{"gemini", &c.AI.Providers.Gemini},
+		{"groq", &c.AI.Providers.Groq},
+		{"openai", &c.AI.Providers.OpenAI},
+		{"claude", &c.AI.Providers.Claude},
+		{"ollama", &c.AI.Providers.Ollama},
+	}
+	for _, p := range providers {
+		switch {
+		case p.config.Enabled:
+			resolve("ai.providers."+p.name+".api_key", &p.config.APIKey)
+		case isSecretReference(p.config.APIKey):
+			p.config.APIKey = "" // Not in use, so not looked up either
+		}
+	}
+
+	for i := range c.AI.OpenAICompatible {
+		compat := &c.AI.OpenAICompatible[i]
+		resolve(fmt.Sprintf("ai.openai_compatible[%s].api_key", compat.Name), &compat.APIKey)
+	}
+
+	return errors.Join(errs...)
+}
+
+// isSecretReference reports whether a value needs ResolveSecret
+func isSecretReference(value string) bool {
+	return strings.HasPrefix(value, secretFilePrefix) ||
+		strings.HasPrefix(value, secretCmdPrefix) ||
+		envPlaceholder.MatchString(value)
+}
+
+// RedactSecret hides a secret for display, keeping the last four characters
+// of long values so keys can still be told apart
+func RedactSecret(secret string) string {
+	switch {
+	case secret == "":
+		return "(not set)"
+	case len(secret) < 12:
+		return "****"
+	default:
+		return "****" + secret[len(secret)-4:]
+	}
+}
+
+// expandHome replaces a leading ~/ with the home directory
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		homeDir, _ := os.UserHomeDir()
+		return filepath.Join(homeDir, path[2:])
+	}
+	return path
+}
@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "groq")
+	if err := os.WriteFile(keyFile, []byte("gsk-from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ANAPHASE_TEST_KEY", "sk-from-env")
+	t.Setenv("ANAPHASE_TEST_EMPTY", "")
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr string
+	}{
+		{"literal", "sk-literal", "sk-literal", ""},
+		{"empty", "", "", ""},
+		{"env", "${ANAPHASE_TEST_KEY}", "sk-from-env", ""},
+		{"env inside a value", "Bearer ${ANAPHASE_TEST_KEY}", "Bearer sk-from-env", ""},
+		{"unset env", "${ANAPHASE_TEST_UNSET}", "", "${ANAPHASE_TEST_UNSET} is not set"},
+		{"empty env", "${ANAPHASE_TEST_EMPTY}", "", "${ANAPHASE_TEST_EMPTY} is not set"},
+		{"file", "file:" + keyFile, "gsk-from-file", ""},
+		{"missing file", "file:" + filepath.Join(dir, "missing"), "", "read secret file"},
+		{"command", "cmd:echo sk-from-cmd", "sk-from-cmd", ""},
+		{"failing command", "cmd:echo denied >&2; exit 1", "", "denied"},
+		{"silent command", "cmd:true", "", "no output"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveSecret(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %q (%v)", tt.wantErr, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ResolveSecret(%q) = %q, %v; want %q", tt.value, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigResolveSecrets(t *testing.T) {
+	t.Setenv("ANAPHASE_TEST_KEY", "sk-from-env")
+
+	cfg := &Config{AI: AIConfig{
+		Providers: ProvidersConfig{
+			Gemini: ProviderConfig{Enabled: true, APIKey: "${ANAPHASE_TEST_KEY}"},
+			Groq:   ProviderConfig{Enabled: true, APIKey: "${ANAPHASE_TEST_UNSET}"},
+			OpenAI: ProviderConfig{Enabled: false, APIKey: "${ANAPHASE_TEST_UNSET}"},
+		},
+		OpenAICompatible: []OpenAICompatibleConfig{{Name: "gateway", APIKey: "cmd:exit 3"}},
+	}}
+
+	err := cfg.ResolveSecrets()
+
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("Expected a ConfigError, got %v", err)
+	}
+	for _, key := range []string{"ai.providers.groq.api_key", "ai.openai_compatible[gateway].api_key"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Expected %s to be reported, got %v", key, err)
+		}
+	}
+	if strings.Contains(err.Error(), "openai.api_key") {
+		t.Errorf("Expected the disabled provider to be ignored, got %v", err)
+	}
+
+	if cfg.AI.Providers.Gemini.APIKey != "sk-from-env" {
+		t.Errorf("Expected the Gemini key to be resolved, got %q", cfg.AI.Providers.Gemini.APIKey)
+	}
+	if cfg.AI.Providers.OpenAI.APIKey != "" {
+		t.Errorf("Expected the unused placeholder to be dropped, got %q", cfg.AI.Providers.OpenAI.APIKey)
+	}
+}
+
+func TestRedactSecret(t *testing.T) {
+	tests := map[string]string{
+		"":                    "(not set)",
+		"short":               "****",
+		"sk-proj-abcdef12345": "****2345",
+	}
+	for secret, want := range tests {
+		if got := RedactSecret(secret); got != want {
+			t.Errorf("RedactSecret(%q) = %q, want %q", secret, got, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package ai
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultSpecDir is where generated domain specs are kept, relative to the
+// project root
+const DefaultSpecDir = ".anaphase/specs"
+
+// specFileHeader explains the file to a reviewer who meets it in a PR
+const specFileHeader = `# Domain spec generated by anaphase. Review and edit it like code, then
+# regenerate with: anaphase gen domain --from-spec %s
+`
+
+// SpecPath returns the spec file of a domain in dir
+func SpecPath(dir, domain string) string {
+	return filepath.Join(dir, strings.ToLower(domain)+".yaml")
+}
+
+// SaveDomainSpec writes spec to dir as <domain>.yaml and returns its path
+func SaveDomainSpec(dir string, spec *DomainSpec) (string, error) {
+	if spec.DomainName == "" {
+		return "", fmt.Errorf("domain_name is required")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("create spec directory: %w", err)
+	}
+
+	var buf bytes.Buffer
+	path := SpecPath(dir, spec.DomainName)
+	fmt.Fprintf(&buf, specFileHeader, path)
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(spec); err != nil {
+		return "", fmt.Errorf("encode spec: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return "", fmt.Errorf("encode spec: %w", err)
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("write spec: %w", err)
+	}
+	return path, nil
+}
+
+// LoadDomainSpecFile reads a spec written by SaveDomainSpec or edited by
+// hand. Unknown keys are rejected so a misspelled one is not silently
+// dropped, and the spec gets the same checks as one returned by the AI
+// (see ValidateDomainSpec). It may refer to the types in existing, which
+// may be nil.
+func LoadDomainSpecFile(path string, existing *CoreSummary) (*DomainSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec DomainSpec
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	if err := ValidateDomainSpec(&spec, existing); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &spec, nil
+}
@@ -0,0 +1,75 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSaveAndLoadDomainSpecFile(t *testing.T) {
+	spec, err := ParseDomainSpec(validSpecJSON)
+	if err != nil {
+		t.Fatalf("ParseDomainSpec failed: %v", err)
+	}
+	spec.Entities[0].Fields[0].Description = "Unique identifier: never reused"
+
+	dir := filepath.Join(t.TempDir(), "specs")
+	path, err := SaveDomainSpec(dir, spec)
+	if err != nil {
+		t.Fatalf("SaveDomainSpec failed: %v", err)
+	}
+	if path != filepath.Join(dir, "order.yaml") {
+		t.Errorf("Unexpected spec path %s", path)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "# Domain spec generated by anaphase") || !strings.Contains(string(data), "domain_name: order") {
+		t.Errorf("Unexpected spec file:\n%s", data)
+	}
+
+	loaded, err := LoadDomainSpecFile(path, nil)
+	if err != nil {
+		t.Fatalf("LoadDomainSpecFile failed: %v", err)
+	}
+	if !reflect.DeepEqual(spec, loaded) {
+		t.Errorf("Expected the spec to round-trip, got %+v", loaded)
+	}
+}
+
+func TestLoadDomainSpecFileRejectsInvalidSpecs(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"unknown key", "domain_name: order\nentitys: []\n", "entitys"},
+		{"no entities", "domain_name: order\n", "at least one entity"},
+		{"bad signature", `domain_name: order
+entities:
+  - name: Order
+    fields: [{name: ID, type: uuid.UUID}]
+    methods: [{name: Cancel, signature: "func (o *Order) Cancel( error"}]
+`, "entities[0].methods[0]"},
+		{"unknown type", `domain_name: order
+entities:
+  - name: Order
+    fields: [{name: Total, type: Money}]
+`, `unknown type "Money"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "order.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := LoadDomainSpecFile(path, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error mentioning %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// StreamChunk is a single piece of a streamed generation
+type StreamChunk struct {
+	Content  string            // Incremental text since the previous chunk
+	Done     bool              // Set on the final chunk
+	Response *GenerateResponse // Complete response, only set on the final chunk
+	Err      error             // Set if the stream failed; no chunks follow
+}
+
+// StreamingProvider is implemented by providers that can stream tokens as
+// they are generated. Providers without it are streamed as a single chunk.
+type StreamingProvider interface {
+	Provider
+
+	// GenerateStream starts a generation and returns a channel of chunks.
+	// The channel is closed after the final (Done or Err) chunk.
+	GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan StreamChunk, error)
+}
+
+// streamFrom streams from any provider, falling back to a blocking Generate
+// call for providers that do not implement StreamingProvider
+func streamFrom(ctx context.Context, provider Provider, req *GenerateRequest) (<-chan StreamChunk, error) {
+	if sp, ok := provider.(StreamingProvider); ok {
+		return sp.GenerateStream(ctx, req)
+	}
+
+	out := make(chan StreamChunk, 2)
+	go func() {
+		defer close(out)
+
+		resp, err := provider.Generate(ctx, req)
+		if err != nil {
+			out <- StreamChunk{Err: err}
+			return
+		}
+
+		out <- StreamChunk{Content: resp.Content}
+		out <- StreamChunk{Done: true, Response: resp}
+	}()
+
+	return out, nil
+}
+
+// sendChunk delivers a chunk unless the consumer has gone away
+func sendChunk(ctx context.Context, out chan<- StreamChunk, chunk StreamChunk) bool {
+	select {
+	case out <- chunk:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// streamingClient returns an HTTP client for long-lived streams. The provider
+// timeout bounds the wait for response headers rather than the whole body,
+// since an 8000-token stream can legitimately take longer than that. Each
+// provider builds one when it is created and reuses it, so streams share
+// the transport's connection pool.
+func streamingClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			ResponseHeaderTimeout: timeout,
+		},
+	}
+}
+
+// openStream POSTs body to url and returns the response once a 200 status
+// has been received, retrying connection failures with exponential backoff
+func openStream(ctx context.Context, name, url string, headers map[string]string, body any, client *http.Client, retries int) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(retryBackoff(attempt)):
+			}
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		for key, value := range headers {
+			httpReq.Header.Set(key, value)
+		}
+
+		httpResp, err := client.Do(httpReq)
+		if err != nil {
+			lastErr = fmt.Errorf("http request: %w", err)
+			continue
+		}
+
+		if httpResp.StatusCode != http.StatusOK {
+			respBody, _ := io.ReadAll(httpResp.Body)
+			httpResp.Body.Close()
+			lastErr = apiError(name, httpResp, streamErrorMessage(respBody))
+			if isRateLimited(lastErr) {
+				return nil, lastErr
+			}
+			continue
+		}
+
+		return httpResp, nil
+	}
+
+	return nil, fmt.Errorf("%s stream failed after %d retries: %w", name, retries, lastErr)
+}
+
+// streamErrorMessage extracts the message from the common error envelopes
+func streamErrorMessage(body []byte) string {
+	var envelope struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err == nil && len(envelope.Error) > 0 {
+		var nested struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(envelope.Error, &nested); err == nil && nested.Message != "" {
+			return nested.Message
+		}
+		var plain string
+		if err := json.Unmarshal(envelope.Error, &plain); err == nil && plain != "" {
+			return plain
+		}
+	}
+	return string(body)
+}
+
+// scanLines returns a scanner that tolerates long server-sent event lines
+func scanLines(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return scanner
+}
+
+// openAIStreamChunk is a single server-sent event from the chat completions API
+type openAIStreamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+	XGroq *struct {
+		Usage *struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			TotalTokens      int `json:"total_tokens"`
+		} `json:"usage"`
+	} `json:"x_groq"`
+}
+
+// readOpenAIStream forwards deltas from an OpenAI-style event stream and
+// finishes with a Done chunk carrying the aggregated response
+func readOpenAIStream(ctx context.Context, body io.ReadCloser, out chan<- StreamChunk, final *GenerateResponse, startTime time.Time, cost func(promptTokens, completionTokens int) float64) {
+	defer close(out)
+	defer body.Close()
+
+	var content strings.Builder
+	scanner := scanLines(body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			sendChunk(ctx, out, StreamChunk{Err: fmt.Errorf("unmarshal stream chunk: %w", err)})
+			return
+		}
+
+		if chunk.Model != "" {
+			final.Model = chunk.Model
+		}
+
+		if chunk.Usage != nil {
+			final.TokensUsed = TokenUsage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+				TotalTokens:      chunk.Usage.TotalTokens,
+			}
+		} else if chunk.XGroq != nil && chunk.XGroq.Usage != nil {
+			final.TokensUsed = TokenUsage{
+				PromptTokens:     chunk.XGroq.Usage.PromptTokens,
+				CompletionTokens: chunk.XGroq.Usage.CompletionTokens,
+				TotalTokens:      chunk.XGroq.Usage.TotalTokens,
+			}
+		}
+
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != nil {
+				final.FinishReason = *choice.FinishReason
+			}
+			if choice.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(choice.Delta.Content)
+			if !sendChunk(ctx, out, StreamChunk{Content: choice.Delta.Content}) {
+				return
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		sendChunk(ctx, out, StreamChunk{Err: fmt.Errorf("read stream: %w", err)})
+		return
+	}
+
+	final.Content = content.String()
+	final.Duration = time.Since(startTime)
+	if cost != nil {
+		final.Cost = cost(final.TokensUsed.PromptTokens, final.TokensUsed.CompletionTokens)
+	}
+
+	sendChunk(ctx, out, StreamChunk{Done: true, Response: final})
+}
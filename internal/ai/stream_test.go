@@ -0,0 +1,159 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeProvider is a non-streaming provider returning a fixed reply or error
+type fakeProvider struct {
+	name    string
+	content string
+	err     error
+	calls   int
+}
+
+func (f *fakeProvider) Name() string  { return f.name }
+func (f *fakeProvider) Model() string { return "fake-model" }
+
+func (f *fakeProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &GenerateResponse{Content: f.content, Provider: f.name, FinishReason: "stop"}, nil
+}
+
+func (f *fakeProvider) Validate() error                                    { return nil }
+func (f *fakeProvider) Health(ctx context.Context) error                   { return nil }
+func (f *fakeProvider) EstimateCost(req *GenerateRequest) (float64, error) { return 0, nil }
+
+// collectStream drains a stream and returns the text, final response and error
+func collectStream(chunks <-chan StreamChunk) (string, *GenerateResponse, error) {
+	var text strings.Builder
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			return text.String(), nil, chunk.Err
+		}
+		if chunk.Done {
+			return text.String(), chunk.Response, nil
+		}
+		text.WriteString(chunk.Content)
+	}
+	return text.String(), nil, fmt.Errorf("stream closed without a final chunk")
+}
+
+func TestOpenAIProviderGenerateStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"model":"gpt-4o-mini","choices":[{"delta":{"content":"{\"domain"}}]}`,
+			`{"model":"gpt-4o-mini","choices":[{"delta":{"content":"_name\": \"cart\"}"},"finish_reason":"stop"}]}`,
+			`{"model":"gpt-4o-mini","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`,
+		}
+		for _, event := range events {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider("test-key", "gpt-4o-mini", server.URL, 5*time.Second, 0)
+	chunks, err := provider.GenerateStream(context.Background(), &GenerateRequest{UserPrompt: "Hello"})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+
+	text, resp, err := collectStream(chunks)
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+
+	expected := `{"domain_name": "cart"}`
+	if text != expected {
+		t.Errorf("Expected streamed text %q, got %q", expected, text)
+	}
+	if resp.Content != expected {
+		t.Errorf("Expected final content %q, got %q", expected, resp.Content)
+	}
+	if resp.TokensUsed.TotalTokens != 15 {
+		t.Errorf("Expected total tokens 15, got %d", resp.TokensUsed.TotalTokens)
+	}
+	if resp.FinishReason != "stop" {
+		t.Errorf("Expected finish reason 'stop', got %q", resp.FinishReason)
+	}
+}
+
+func TestStreamsReuseConnections(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"ok\"},\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	var connections atomic.Int32
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	provider := NewOpenAIProvider("test-key", "gpt-4o-mini", server.URL, 5*time.Second, 0)
+	for range 3 {
+		chunks, err := provider.GenerateStream(context.Background(), &GenerateRequest{UserPrompt: "Hello"})
+		if err != nil {
+			t.Fatalf("GenerateStream failed: %v", err)
+		}
+		if _, _, err := collectStream(chunks); err != nil {
+			t.Fatalf("Stream failed: %v", err)
+		}
+	}
+
+	if got := connections.Load(); got != 1 {
+		t.Errorf("Expected the streams to share one connection, got %d", got)
+	}
+}
+
+func TestOrchestratorGenerateStreamFallback(t *testing.T) {
+	failing := &fakeProvider{name: "primary", err: fmt.Errorf("unavailable")}
+	working := &fakeProvider{name: "backup", content: "hello"}
+
+	orchestrator := &Orchestrator{
+		providers:       map[string]Provider{"primary": failing, "backup": working},
+		primaryProvider: "primary",
+		fallbackChain:   []string{"backup"},
+		cache:           NewCache(t.TempDir(), time.Hour, 0, false),
+		health:          NewHealthTracker(BreakerConfig{}),
+		logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	chunks, err := orchestrator.GenerateStream(context.Background(), &GenerateRequest{UserPrompt: "Hi"})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+
+	text, resp, err := collectStream(chunks)
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+
+	if text != "hello" {
+		t.Errorf("Expected streamed text 'hello', got %q", text)
+	}
+	if resp.Provider != "backup" {
+		t.Errorf("Expected provider 'backup', got %q", resp.Provider)
+	}
+	if failing.calls != 1 || working.calls != 1 {
+		t.Errorf("Expected one call per provider, got %d and %d", failing.calls, working.calls)
+	}
+}
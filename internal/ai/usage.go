@@ -0,0 +1,259 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// UsageRecord is one generation in the usage ledger
+type UsageRecord struct {
+	Time             time.Time     `json:"time"`
+	Command          string        `json:"command,omitempty"`
+	Provider         string        `json:"provider"`
+	Model            string        `json:"model"`
+	PromptTokens     int           `json:"prompt_tokens"`
+	CompletionTokens int           `json:"completion_tokens"`
+	TotalTokens      int           `json:"total_tokens"`
+	Cost             float64       `json:"cost"`
+	Duration         time.Duration `json:"duration"`
+	Repair           int           `json:"repair,omitempty"` // Repair round of a follow-up request, 0 for a first attempt
+}
+
+// UsageLedger appends every generation to a JSON Lines file so token usage
+// and spending can be reported and capped across runs. A nil ledger records
+// nothing.
+type UsageLedger struct {
+	mu   sync.Mutex
+	path string
+	now  func() time.Time
+}
+
+// NewUsageLedger creates a ledger backed by the given file
+func NewUsageLedger(path string) *UsageLedger {
+	return &UsageLedger{path: path, now: time.Now}
+}
+
+// Path returns the ledger file
+func (l *UsageLedger) Path() string {
+	return l.path
+}
+
+// Append adds a record to the ledger
+func (l *UsageLedger) Append(record UsageRecord) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if record.Time.IsZero() {
+		record.Time = l.now()
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshal usage record: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("create usage directory: %w", err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open usage ledger: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write usage record: %w", err)
+	}
+
+	return nil
+}
+
+// Records returns the records made at or after since, oldest first.
+// Unreadable lines are skipped so one bad write does not lose the history.
+func (l *UsageLedger) Records(since time.Time) ([]UsageRecord, error) {
+	if l == nil {
+		return nil, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open usage ledger: %w", err)
+	}
+	defer f.Close()
+
+	var records []UsageRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record UsageRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if !record.Time.Before(since) {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read usage ledger: %w", err)
+	}
+
+	return records, nil
+}
+
+// Spent returns the total cost recorded at or after since
+func (l *UsageLedger) Spent(since time.Time) (float64, error) {
+	records, err := l.Records(since)
+	if err != nil {
+		return 0, err
+	}
+
+	var total float64
+	for _, r := range records {
+		total += r.Cost
+	}
+	return total, nil
+}
+
+// UsageGroup aggregates the records sharing one key
+type UsageGroup struct {
+	Key      string
+	Requests int
+	Repairs  int // Requests that were repair follow-ups
+	Tokens   int
+	Cost     float64
+	Duration time.Duration
+}
+
+// UsageGroupings lists the keys a usage report can be grouped by
+var UsageGroupings = []string{"day", "provider", "model", "command"}
+
+// SummarizeUsage groups records by day, provider, model or command. Days
+// are listed in order, other groupings by descending cost.
+func SummarizeUsage(records []UsageRecord, by string) ([]UsageGroup, error) {
+	var keyOf func(UsageRecord) string
+	switch by {
+	case "day":
+		keyOf = func(r UsageRecord) string { return r.Time.Local().Format("2006-01-02") }
+	case "provider":
+		keyOf = func(r UsageRecord) string { return r.Provider }
+	case "model":
+		keyOf = func(r UsageRecord) string { return r.Provider + "/" + r.Model }
+	case "command":
+		keyOf = func(r UsageRecord) string {
+			if r.Command == "" {
+				return "(unknown)"
+			}
+			return r.Command
+		}
+	default:
+		return nil, fmt.Errorf("unknown grouping %q (expected one of %v)", by, UsageGroupings)
+	}
+
+	index := make(map[string]int)
+	var groups []UsageGroup
+	for _, r := range records {
+		key := keyOf(r)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, UsageGroup{Key: key})
+		}
+		groups[i].Requests++
+		if r.Repair > 0 {
+			groups[i].Repairs++
+		}
+		groups[i].Tokens += r.TotalTokens
+		groups[i].Cost += r.Cost
+		groups[i].Duration += r.Duration
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if by == "day" {
+			return groups[i].Key < groups[j].Key
+		}
+		return groups[i].Cost > groups[j].Cost
+	})
+
+	return groups, nil
+}
+
+// BudgetExceededError is returned when a request would push spending past
+// a configured cap
+type BudgetExceededError struct {
+	Period   string // "daily" or "monthly"
+	Limit    float64
+	Spent    float64
+	Estimate float64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("%s budget of $%.2f would be exceeded ($%.4f spent, request estimated at $%.4f); raise usage.%s_limit or run: anaphase usage",
+		e.Period, e.Limit, e.Spent, e.Estimate, e.Period)
+}
+
+// CheckBudget returns a BudgetExceededError if spending estimate more would
+// exceed the daily or monthly limit. Zero limits are not enforced, and free
+// requests (such as local models) are always allowed.
+func (l *UsageLedger) CheckBudget(cfg UsageConfig, estimate float64) error {
+	if l == nil || estimate <= 0 || (cfg.DailyLimit <= 0 && cfg.MonthlyLimit <= 0) {
+		return nil
+	}
+
+	now := l.now()
+	periods := []struct {
+		name  string
+		limit float64
+		since time.Time
+	}{
+		{"daily", cfg.DailyLimit, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())},
+		{"monthly", cfg.MonthlyLimit, time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())},
+	}
+
+	for _, p := range periods {
+		if p.limit <= 0 {
+			continue
+		}
+
+		spent, err := l.Spent(p.since)
+		if err != nil {
+			return err
+		}
+		if spent+estimate > p.limit {
+			return &BudgetExceededError{Period: p.name, Limit: p.limit, Spent: spent, Estimate: estimate}
+		}
+	}
+
+	return nil
+}
+
+type commandKey struct{}
+
+// WithCommand tags generations made with ctx with the CLI command that
+// issued them, for the usage report
+func WithCommand(ctx context.Context, command string) context.Context {
+	return context.WithValue(ctx, commandKey{}, command)
+}
+
+// commandFromContext returns the command set by WithCommand
+func commandFromContext(ctx context.Context) string {
+	command, _ := ctx.Value(commandKey{}).(string)
+	return command
+}
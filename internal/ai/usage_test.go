@@ -0,0 +1,172 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// pricedProvider is a fake provider with a fixed cost per request
+type pricedProvider struct {
+	fakeProvider
+	cost float64
+}
+
+func (p *pricedProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	resp, err := p.fakeProvider.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Model = p.Model()
+	resp.Cost = p.cost
+	resp.TokensUsed = TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}
+	return resp, nil
+}
+
+func (p *pricedProvider) EstimateCost(req *GenerateRequest) (float64, error) { return p.cost, nil }
+
+func TestUsageLedgerSummaries(t *testing.T) {
+	ledger := NewUsageLedger(filepath.Join(t.TempDir(), "usage.jsonl"))
+
+	day1 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	records := []UsageRecord{
+		{Time: day1, Command: "gen domain", Provider: "openai", Model: "gpt-4o-mini", TotalTokens: 100, Cost: 0.25},
+		{Time: day2, Command: "gen domain", Provider: "groq", Model: "llama", TotalTokens: 50, Cost: 0.05},
+		{Time: day2, Provider: "openai", Model: "gpt-4o-mini", TotalTokens: 10, Cost: 0.5},
+	}
+	for _, r := range records {
+		if err := ledger.Append(r); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	// A torn write must not hide the rest of the history
+	f, _ := os.OpenFile(ledger.Path(), os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString("{\"time\":\n")
+	f.Close()
+
+	spent, err := ledger.Spent(day2)
+	if err != nil {
+		t.Fatalf("Spent failed: %v", err)
+	}
+	if spent != 0.55 {
+		t.Errorf("Expected $0.55 spent since day two, got %v", spent)
+	}
+
+	all, _ := ledger.Records(time.Time{})
+	tests := []struct {
+		by    string
+		keys  []string
+		first float64
+	}{
+		{"day", []string{"2025-03-01", "2025-03-02"}, 0.25},
+		{"provider", []string{"openai", "groq"}, 0.75},
+		{"model", []string{"openai/gpt-4o-mini", "groq/llama"}, 0.75},
+		{"command", []string{"(unknown)", "gen domain"}, 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			groups, err := SummarizeUsage(all, tt.by)
+			if err != nil {
+				t.Fatalf("SummarizeUsage failed: %v", err)
+			}
+			if len(groups) != len(tt.keys) {
+				t.Fatalf("Expected %d groups, got %+v", len(tt.keys), groups)
+			}
+			for i, key := range tt.keys {
+				if groups[i].Key != key {
+					t.Errorf("Expected group %d to be %q, got %q", i, key, groups[i].Key)
+				}
+			}
+			if groups[0].Cost != tt.first {
+				t.Errorf("Expected first group cost %v, got %v", tt.first, groups[0].Cost)
+			}
+		})
+	}
+
+	if _, err := SummarizeUsage(all, "week"); err == nil {
+		t.Error("Expected an error for an unknown grouping")
+	}
+}
+
+func TestUsageLedgerCheckBudget(t *testing.T) {
+	now := time.Date(2025, 3, 15, 12, 0, 0, 0, time.Local)
+	ledger := NewUsageLedger(filepath.Join(t.TempDir(), "usage.jsonl"))
+	ledger.now = func() time.Time { return now }
+
+	ledger.Append(UsageRecord{Time: now.AddDate(0, 0, -3), Cost: 4})
+	ledger.Append(UsageRecord{Time: now.Add(-time.Hour), Cost: 0.9})
+
+	tests := []struct {
+		name     string
+		cfg      UsageConfig
+		estimate float64
+		period   string
+	}{
+		{"no caps", UsageConfig{}, 100, ""},
+		{"within daily cap", UsageConfig{DailyLimit: 1}, 0.05, ""},
+		{"over daily cap", UsageConfig{DailyLimit: 1}, 0.2, "daily"},
+		{"over monthly cap", UsageConfig{DailyLimit: 10, MonthlyLimit: 5}, 0.2, "monthly"},
+		{"free request over cap", UsageConfig{DailyLimit: 0.5}, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ledger.CheckBudget(tt.cfg, tt.estimate)
+
+			var budgetErr *BudgetExceededError
+			switch {
+			case tt.period == "" && err != nil:
+				t.Errorf("Expected request to be allowed, got %v", err)
+			case tt.period != "" && !errors.As(err, &budgetErr):
+				t.Errorf("Expected a budget error, got %v", err)
+			case tt.period != "" && budgetErr.Period != tt.period:
+				t.Errorf("Expected %s cap to trip, got %s", tt.period, budgetErr.Period)
+			}
+		})
+	}
+}
+
+func TestOrchestratorRecordsUsageAndEnforcesBudget(t *testing.T) {
+	expensive := &pricedProvider{fakeProvider: fakeProvider{name: "openai", content: "hello"}, cost: 0.6}
+
+	orchestrator := newTestOrchestrator(t, expensive, 0)
+	orchestrator.usage = NewUsageLedger(filepath.Join(t.TempDir(), "usage.jsonl"))
+	orchestrator.budget = UsageConfig{DailyLimit: 1}
+
+	ctx := WithCommand(context.Background(), "gen domain")
+	if _, err := orchestrator.Generate(ctx, &GenerateRequest{UserPrompt: "first"}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	records, err := orchestrator.usage.Records(time.Time{})
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Expected one usage record, got %v (%v)", records, err)
+	}
+	if r := records[0]; r.Command != "gen domain" || r.Provider != "openai" || r.Cost != 0.6 || r.TotalTokens != 15 {
+		t.Errorf("Unexpected usage record %+v", r)
+	}
+
+	// A second request would exceed the cap and is refused before sending
+	_, err = orchestrator.Generate(ctx, &GenerateRequest{UserPrompt: "second"})
+	var budgetErr *BudgetExceededError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("Expected a budget error, got %v", err)
+	}
+	if expensive.calls != 1 {
+		t.Errorf("Expected the provider not to be called over budget, got %d calls", expensive.calls)
+	}
+
+	// A free fallback still runs
+	orchestrator.providers["ollama"] = &fakeProvider{name: "ollama", content: "local"}
+	orchestrator.fallbackChain = []string{"ollama"}
+	resp, err := orchestrator.Generate(ctx, &GenerateRequest{UserPrompt: "second"})
+	if err != nil || resp.Provider != "ollama" {
+		t.Errorf("Expected free fallback to run, got %v (%v)", resp, err)
+	}
+}
@@ -0,0 +1,342 @@
+package ai
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// specPackages are the packages generated code may use besides the core
+// packages; any exported name in them is accepted
+var specPackages = map[string]bool{
+	"context": true,
+	"time":    true,
+	"uuid":    true,
+}
+
+// SpecError is one problem found in a domain spec
+type SpecError struct {
+	Path    string // e.g. entities[0].fields[1].type
+	Message string
+}
+
+func (e SpecError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// SpecErrors collects every problem found in a domain spec
+type SpecErrors []SpecError
+
+func (e SpecErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "invalid domain spec:\n  " + strings.Join(msgs, "\n  ")
+}
+
+// ValidateDomainSpec checks that a spec generates code that compiles: names
+// are valid Go identifiers that do not clash, every field type resolves to a
+// builtin, a known package or a type of the spec, and every signature parses
+// and refers to types that exist. Types in existing, the project's current
+// core packages, may be referenced too; existing may be nil. All problems
+// are returned together as SpecErrors.
+func ValidateDomainSpec(spec *DomainSpec, existing *CoreSummary) error {
+	v := &specValidator{known: make(map[string]bool)}
+	for _, e := range spec.Entities {
+		v.known["entity."+e.Name] = true
+	}
+	for _, vo := range spec.ValueObjects {
+		v.known["valueobject."+vo.Name] = true
+	}
+	if existing != nil {
+		for _, t := range existing.Types {
+			v.known[t.QualifiedName()] = true
+		}
+	}
+
+	if strings.TrimSpace(spec.DomainName) == "" {
+		v.addf("domain_name", "is required")
+	}
+	if len(spec.Entities) == 0 {
+		v.addf("entities", "at least one entity is required")
+	}
+
+	v.entities(spec.Entities)
+	v.valueObjects(spec.ValueObjects)
+
+	v.typeName("repository_interface.name", spec.RepositoryInterface.Name)
+	v.typeName("service_interface.name", spec.ServiceInterface.Name)
+	if spec.RepositoryInterface.Name != "" && spec.RepositoryInterface.Name == spec.ServiceInterface.Name {
+		v.addf("service_interface.name", "%q is also the repository interface", spec.ServiceInterface.Name)
+	}
+	v.interfaceMethods("repository_interface.methods", spec.RepositoryInterface.Methods)
+	v.interfaceMethods("service_interface.methods", spec.ServiceInterface.Methods)
+
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+type specValidator struct {
+	known map[string]bool // Qualified names such as entity.Order
+	errs  SpecErrors
+}
+
+func (v *specValidator) addf(path, format string, args ...any) {
+	v.errs = append(v.errs, SpecError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *specValidator) entities(entities []EntitySpec) {
+	names := make(map[string]bool)
+	for i, e := range entities {
+		path := fmt.Sprintf("entities[%d]", i)
+		if v.typeName(path+".name", e.Name) && names[e.Name] {
+			v.addf(path+".name", "duplicate entity %q", e.Name)
+		}
+		names[e.Name] = true
+
+		fields := v.fields(path+".fields", e.Fields, "entity")
+
+		methods := make(map[string]bool)
+		for j, m := range e.Methods {
+			mpath := fmt.Sprintf("%s.methods[%d]", path, j)
+			switch {
+			case !v.identifier(mpath+".name", m.Name):
+			case m.Name == "Validate":
+				v.addf(mpath+".name", "Validate is generated for every entity")
+			case methods[m.Name]:
+				v.addf(mpath+".name", "duplicate method %q", m.Name)
+			case fields[m.Name]:
+				v.addf(mpath+".name", "%q is also a field", m.Name)
+			}
+			methods[m.Name] = true
+
+			v.entityMethod(mpath+".signature", e.Name, m)
+		}
+	}
+}
+
+func (v *specValidator) valueObjects(vos []ValueObjectSpec) {
+	names := make(map[string]bool)
+	for i, vo := range vos {
+		path := fmt.Sprintf("value_objects[%d]", i)
+		if v.typeName(path+".name", vo.Name) && names[vo.Name] {
+			v.addf(path+".name", "duplicate value object %q", vo.Name)
+		}
+		names[vo.Name] = true
+
+		v.fields(path+".fields", vo.Fields, "valueobject")
+
+		// The constructor takes each field as a lowercased parameter
+		for j, f := range vo.Fields {
+			if lower := strings.ToLower(f.Name); token.IsKeyword(lower) {
+				v.addf(fmt.Sprintf("%s.fields[%d].name", path, j), "%q becomes the constructor parameter %q, a reserved word", f.Name, lower)
+			}
+		}
+	}
+}
+
+// fields checks a struct's fields and returns their names
+func (v *specValidator) fields(path string, fields []FieldSpec, pkg string) map[string]bool {
+	names := make(map[string]bool)
+	for i, f := range fields {
+		fpath := fmt.Sprintf("%s[%d]", path, i)
+		if v.identifier(fpath+".name", f.Name) && names[f.Name] {
+			v.addf(fpath+".name", "duplicate field %q", f.Name)
+		}
+		names[f.Name] = true
+
+		if strings.TrimSpace(f.Type) == "" {
+			v.addf(fpath+".type", "is required")
+			continue
+		}
+		expr, err := parser.ParseExpr(f.Type)
+		if err != nil {
+			v.addf(fpath+".type", "%q is not a Go type", f.Type)
+			continue
+		}
+		// The generator qualifies a bare type name on an entity field as a
+		// value object, which another entity is not
+		if ident, ok := expr.(*ast.Ident); ok && pkg == "entity" && v.known["entity."+ident.Name] && !v.known["valueobject."+ident.Name] {
+			v.addf(fpath+".type", "%s is an entity, refer to it as *%s", ident.Name, ident.Name)
+			continue
+		}
+		v.resolve(fpath+".type", expr, pkg, pkg == "entity")
+	}
+	return names
+}
+
+// entityMethod parses an entity method's signature and checks its receiver,
+// name and types
+func (v *specValidator) entityMethod(path, entity string, m MethodSpec) {
+	if strings.TrimSpace(m.Signature) == "" {
+		v.addf(path, "is required")
+		return
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package p\n\n"+m.Signature+" {}\n", 0)
+	if err != nil {
+		v.addf(path, "invalid Go in %q: %s", m.Signature, syntaxMessage(err))
+		return
+	}
+	// A comment-only signature parses to no declarations at all
+	if len(file.Decls) != 1 {
+		v.addf(path, "%q is not a valid Go method declaration", m.Signature)
+		return
+	}
+	fn, ok := file.Decls[0].(*ast.FuncDecl)
+	if !ok {
+		v.addf(path, "%q is not a valid Go method declaration", m.Signature)
+		return
+	}
+
+	if fn.Recv == nil || len(fn.Recv.List) != 1 || receiverName(fn.Recv.List[0].Type) != entity {
+		v.addf(path, "must be a method on %s, e.g. func (x *%s) %s()", entity, entity, m.Name)
+	}
+	if fn.Name.Name != m.Name {
+		v.addf(path, "declares %s but the method is named %s", fn.Name.Name, m.Name)
+	}
+	v.resolveFunc(path, fn.Type, "entity")
+}
+
+// interfaceMethods checks the methods of a port interface. Types they use
+// must be builtins, known packages, or entities and value objects that exist.
+func (v *specValidator) interfaceMethods(path string, methods []InterfaceMethod) {
+	names := make(map[string]bool)
+	for i, m := range methods {
+		mpath := fmt.Sprintf("%s[%d]", path, i)
+		if v.identifier(mpath+".name", m.Name) && names[m.Name] {
+			v.addf(mpath+".name", "duplicate method %q", m.Name)
+		}
+		names[m.Name] = true
+
+		if strings.TrimSpace(m.Signature) == "" {
+			v.addf(mpath+".signature", "is required")
+			continue
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), "", "package p\n\ntype _ interface {\n"+m.Signature+"\n}\n", 0)
+		if err != nil {
+			v.addf(mpath+".signature", "invalid Go in %q: %s", m.Signature, syntaxMessage(err))
+			continue
+		}
+		// Braces in the signature can close the interface and declare more
+		iface := file.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(*ast.InterfaceType)
+		if len(file.Decls) != 1 || len(iface.Methods.List) != 1 || len(iface.Methods.List[0].Names) != 1 {
+			v.addf(mpath+".signature", "%q must declare exactly one method", m.Signature)
+			continue
+		}
+
+		method := iface.Methods.List[0]
+		if method.Names[0].Name != m.Name {
+			v.addf(mpath+".signature", "declares %s but the method is named %s", method.Names[0].Name, m.Name)
+		}
+		v.resolveFunc(mpath+".signature", method.Type.(*ast.FuncType), "port")
+	}
+}
+
+func (v *specValidator) resolveFunc(path string, fn *ast.FuncType, pkg string) {
+	for _, list := range []*ast.FieldList{fn.Params, fn.Results} {
+		if list == nil {
+			continue
+		}
+		for _, field := range list.List {
+			v.resolve(path, field.Type, pkg, false)
+		}
+	}
+}
+
+// resolve reports the types in expr that do not exist for code in pkg.
+// bareValueObject allows an unqualified value object name at the top level.
+func (v *specValidator) resolve(path string, expr ast.Expr, pkg string, bareValueObject bool) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if obj, ok := types.Universe.Lookup(e.Name).(*types.TypeName); ok && obj != nil {
+			return
+		}
+		if v.known[pkg+"."+e.Name] || (bareValueObject && v.known["valueobject."+e.Name]) {
+			return
+		}
+		if v.known["entity."+e.Name] || v.known["valueobject."+e.Name] {
+			qualified := "valueobject." + e.Name
+			if v.known["entity."+e.Name] {
+				qualified = "entity." + e.Name
+			}
+			v.addf(path, "%s must be written %s here", e.Name, qualified)
+			return
+		}
+		v.addf(path, "unknown type %q", e.Name)
+
+	case *ast.SelectorExpr:
+		x, ok := e.X.(*ast.Ident)
+		switch {
+		case !ok:
+			v.addf(path, "unknown type %q", types.ExprString(e))
+		case specPackages[x.Name]:
+		case x.Name == "entity" || x.Name == "valueobject":
+			if !v.known[x.Name+"."+e.Sel.Name] {
+				v.addf(path, "unknown type %q", types.ExprString(e))
+			}
+		default:
+			v.addf(path, "unknown package %q in %s", x.Name, types.ExprString(e))
+		}
+
+	case *ast.StarExpr:
+		v.resolve(path, e.X, pkg, false)
+	case *ast.ArrayType:
+		v.resolve(path, e.Elt, pkg, false)
+	case *ast.Ellipsis:
+		v.resolve(path, e.Elt, pkg, false)
+	case *ast.MapType:
+		v.resolve(path, e.Key, pkg, false)
+		v.resolve(path, e.Value, pkg, false)
+	case *ast.ChanType:
+		v.resolve(path, e.Value, pkg, false)
+	case *ast.FuncType:
+		v.resolveFunc(path, e, pkg)
+	case *ast.InterfaceType, *ast.StructType:
+	default:
+		v.addf(path, "%q is not a supported type", types.ExprString(expr))
+	}
+}
+
+// typeName checks the name of a generated type, reporting whether it is valid
+func (v *specValidator) typeName(path, name string) bool {
+	if !v.identifier(path, name) {
+		return false
+	}
+	if !token.IsExported(name) {
+		v.addf(path, "%q must be exported (start with an upper-case letter)", name)
+		return false
+	}
+	return true
+}
+
+// identifier checks a Go identifier, reporting whether it is valid
+func (v *specValidator) identifier(path, name string) bool {
+	switch {
+	case name == "":
+		v.addf(path, "is required")
+	case token.IsKeyword(name):
+		v.addf(path, "%q is a reserved word", name)
+	case !token.IsIdentifier(name):
+		v.addf(path, "%q is not a valid Go identifier", name)
+	default:
+		return true
+	}
+	return false
+}
+
+// syntaxMessage strips the position from a parse error; positions refer to
+// the wrapper source, so the message alone is clearer
+func syntaxMessage(err error) string {
+	msg := err.Error()
+	if idx := strings.Index(msg, ": "); idx >= 0 {
+		msg = msg[idx+2:]
+	}
+	return msg
+}
@@ -0,0 +1,125 @@
+package ai
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateDomainSpec(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(spec *DomainSpec)
+		wantPath string
+		wantMsg  string
+	}{
+		{"valid", func(spec *DomainSpec) {}, "", ""},
+		{"reserved field name", func(spec *DomainSpec) {
+			spec.Entities[0].Fields[0].Name = "type"
+		}, "entities[0].fields[0].name", "reserved word"},
+		{"invalid entity name", func(spec *DomainSpec) {
+			spec.Entities[0].Name = "order-item"
+		}, "entities[0].name", "not a valid Go identifier"},
+		{"unexported interface", func(spec *DomainSpec) {
+			spec.ServiceInterface.Name = "orderService"
+		}, "service_interface.name", "must be exported"},
+		{"duplicate field", func(spec *DomainSpec) {
+			spec.Entities[0].Fields = append(spec.Entities[0].Fields, FieldSpec{Name: "ID", Type: "string"})
+		}, "entities[0].fields[1].name", `duplicate field "ID"`},
+		{"method named like a field", func(spec *DomainSpec) {
+			spec.Entities[0].Methods[0] = MethodSpec{Name: "ID", Signature: "func (o *Order) ID() uuid.UUID"}
+		}, "entities[0].methods[0].name", "also a field"},
+		{"unknown type", func(spec *DomainSpec) {
+			spec.Entities[0].Fields[0].Type = "Money"
+		}, "entities[0].fields[0].type", `unknown type "Money"`},
+		{"unknown package", func(spec *DomainSpec) {
+			spec.Entities[0].Fields[0].Type = "[]decimal.Decimal"
+		}, "entities[0].fields[0].type", `unknown package "decimal"`},
+		{"unqualified value object in a slice", func(spec *DomainSpec) {
+			spec.ValueObjects = []ValueObjectSpec{{Name: "Line", Fields: []FieldSpec{{Name: "Qty", Type: "int"}}}}
+			spec.Entities[0].Fields[0].Type = "[]Line"
+		}, "entities[0].fields[0].type", "must be written valueobject.Line"},
+		{"receiver of another type", func(spec *DomainSpec) {
+			spec.Entities[0].Methods[0].Signature = "func (c *Cart) Cancel() error"
+		}, "entities[0].methods[0].signature", "must be a method on Order"},
+		{"signature name mismatch", func(spec *DomainSpec) {
+			spec.RepositoryInterface.Methods[0].Signature = "Store(ctx context.Context, order *entity.Order) error"
+		}, "repository_interface.methods[0].signature", "declares Store but the method is named Save"},
+		{"unknown entity in a port", func(spec *DomainSpec) {
+			spec.RepositoryInterface.Methods[0].Signature = "Save(ctx context.Context, cart *entity.Cart) error"
+		}, "repository_interface.methods[0].signature", `unknown type "entity.Cart"`},
+		{"unqualified entity in a port", func(spec *DomainSpec) {
+			spec.RepositoryInterface.Methods[0].Signature = "Save(ctx context.Context, order *Order) error"
+		}, "repository_interface.methods[0].signature", "must be written entity.Order"},
+		{"comment-only method", func(spec *DomainSpec) {
+			spec.Entities[0].Methods[0].Signature = "// nothing"
+		}, "entities[0].methods[0].signature", "not a valid Go method declaration"},
+		{"whitespace and comment method", func(spec *DomainSpec) {
+			spec.Entities[0].Methods[0].Signature = "  // TODO: cancel the order  "
+		}, "entities[0].methods[0].signature", "not a valid Go method declaration"},
+		{"several method declarations", func(spec *DomainSpec) {
+			spec.Entities[0].Methods[0].Signature = "func (o *Order) Cancel() {}; func (o *Order) Cancel() error"
+		}, "entities[0].methods[0].signature", "not a valid Go method declaration"},
+		{"signature closing the interface", func(spec *DomainSpec) {
+			spec.RepositoryInterface.Methods[0].Signature = "Save(ctx context.Context, order *entity.Order) error\n}\ntype X interface {"
+		}, "repository_interface.methods[0].signature", "must declare exactly one method"},
+		{"value object constructor parameter", func(spec *DomainSpec) {
+			spec.ValueObjects = []ValueObjectSpec{{Name: "Kind", Fields: []FieldSpec{{Name: "Type", Type: "string"}}}}
+		}, "value_objects[0].fields[0].name", `constructor parameter "type"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := refineBaseSpec(t)
+			tt.mutate(spec)
+
+			err := ValidateDomainSpec(spec, nil)
+			if tt.wantPath == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			var specErrs SpecErrors
+			if !errors.As(err, &specErrs) {
+				t.Fatalf("Expected SpecErrors, got %v", err)
+			}
+			for _, e := range specErrs {
+				if e.Path == tt.wantPath && strings.Contains(e.Message, tt.wantMsg) {
+					return
+				}
+			}
+			t.Errorf("Expected %s: ...%s..., got %v", tt.wantPath, tt.wantMsg, err)
+		})
+	}
+}
+
+func TestValidateDomainSpecReportsAllProblems(t *testing.T) {
+	spec := refineBaseSpec(t)
+	spec.Entities[0].Fields[0].Type = "Money"
+	spec.Entities[0].Methods[0].Signature = "func (o *Order) Cancel( error"
+	spec.RepositoryInterface.Methods[0].Signature = "Save(ctx context.Context, p *entity.Payment) error"
+
+	var specErrs SpecErrors
+	if err := ValidateDomainSpec(spec, nil); !errors.As(err, &specErrs) || len(specErrs) != 3 {
+		t.Fatalf("Expected three problems, got %v", err)
+	}
+}
+
+func TestValidateDomainSpecAcceptsExistingTypes(t *testing.T) {
+	spec := refineBaseSpec(t)
+	spec.Entities[0].Fields[0].Type = "valueobject.Money"
+	spec.RepositoryInterface.Methods[0].Signature = "Save(ctx context.Context, order *entity.Order, by *entity.Customer) error"
+
+	existing := &CoreSummary{Types: []CoreType{
+		{Package: "valueobject", Name: "Money", Kind: "struct"},
+		{Package: "entity", Name: "Customer", Kind: "struct"},
+	}}
+	if err := ValidateDomainSpec(spec, existing); err != nil {
+		t.Errorf("Expected existing types to resolve, got %v", err)
+	}
+	if err := ValidateDomainSpec(spec, nil); err == nil {
+		t.Error("Expected unknown types without the project summary")
+	}
+}
@@ -0,0 +1,246 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lisvindanu/anaphase-cli/internal/ai"
+	"github.com/lisvindanu/anaphase-cli/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the AI response cache",
+	Long: `Inspect and maintain the AI response cache (~/.anaphase/cache by default).
+
+Available subcommands:
+  stats   - Show cache size and entry counts
+  list    - List cached entries, most recently used first
+  prune   - Remove expired entries and enforce max_size
+  clear   - Remove all cached entries
+  show    - Show a cached request and response`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show cache size and entry counts",
+	RunE:  runCacheStats,
+}
+
+var cacheListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List cached entries",
+	Long:  "List cached entries, most recently used first. Expired entries are marked.",
+	RunE:  runCacheList,
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove expired entries and enforce max_size",
+	Long: `Remove expired or unreadable entries, then evict the least recently used
+entries until the cache fits within cache.max_size.`,
+	RunE: runCachePrune,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached entries",
+	RunE:  runCacheClear,
+}
+
+var cacheShowCmd = &cobra.Command{
+	Use:   "show <hash>",
+	Short: "Show a cached request and response",
+	Long: `Show a cached request and response. A unique prefix of the hash is enough.
+
+Example:
+  anaphase cache show 3f2a9c`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCacheShow,
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheListCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cacheShowCmd)
+}
+
+// loadCache builds the cache described by the user's configuration
+func loadCache() (*ai.Cache, error) {
+	cfg, err := ai.LoadConfig(configFlags()...)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
+		return nil, err
+	}
+
+	cache, err := ai.NewCacheFromConfig(cfg.Cache)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Invalid cache configuration: %v", err))
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+func runCacheStats(cmd *cobra.Command, args []string) error {
+	fmt.Println(ui.RenderTitle("Cache Statistics"))
+
+	cache, err := loadCache()
+	if err != nil {
+		return err
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to read cache: %v", err))
+		return err
+	}
+
+	limit := "unlimited"
+	if stats.MaxSize > 0 {
+		limit = ai.FormatSize(stats.MaxSize)
+	}
+
+	fmt.Println()
+	fmt.Printf("  Directory: %s\n", stats.Directory)
+	fmt.Printf("  Entries: %d (%d expired)\n", stats.Entries, stats.Expired)
+	fmt.Printf("  Size: %s / %s\n", ai.FormatSize(stats.TotalSize), limit)
+	if stats.Entries > 0 {
+		fmt.Printf("  Oldest: %s\n", stats.Oldest.Format(time.RFC3339))
+		fmt.Printf("  Newest: %s\n", stats.Newest.Format(time.RFC3339))
+	}
+	fmt.Println()
+
+	return nil
+}
+
+func runCacheList(cmd *cobra.Command, args []string) error {
+	cache, err := loadCache()
+	if err != nil {
+		return err
+	}
+
+	entries, err := cache.Entries()
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to read cache: %v", err))
+		return err
+	}
+
+	if len(entries) == 0 {
+		ui.PrintInfo("Cache is empty")
+		return nil
+	}
+
+	for _, entry := range entries {
+		status := ui.CheckmarkStyle.Render()
+		if entry.Expired() {
+			status = ui.CrossStyle.Render()
+		}
+
+		source := fmt.Sprintf("%s/%s", entry.Provider, entry.Model)
+		if entry.Corrupt {
+			source = "unreadable"
+		}
+
+		fmt.Printf("  %s %s  %-8s  %s  %s\n",
+			status,
+			shortHash(entry.Hash),
+			ai.FormatSize(entry.Size),
+			entry.LastAccess.Format("2006-01-02 15:04"),
+			ui.RenderSubtle(source))
+	}
+
+	return nil
+}
+
+func runCachePrune(cmd *cobra.Command, args []string) error {
+	cache, err := loadCache()
+	if err != nil {
+		return err
+	}
+
+	expired, expiredBytes, err := cache.Prune()
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to prune cache: %v", err))
+		return err
+	}
+
+	evicted, evictedBytes, err := cache.Evict()
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to evict cache entries: %v", err))
+		return err
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Removed %d expired and %d least recently used entries (%s freed)",
+		expired, evicted, ai.FormatSize(expiredBytes+evictedBytes)))
+
+	return nil
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	cache, err := loadCache()
+	if err != nil {
+		return err
+	}
+
+	if err := cache.Clear(); err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to clear cache: %v", err))
+		return err
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Cleared %s", cache.Directory()))
+	return nil
+}
+
+func runCacheShow(cmd *cobra.Command, args []string) error {
+	cache, err := loadCache()
+	if err != nil {
+		return err
+	}
+
+	entry, err := cache.Show(args[0])
+	if err != nil {
+		ui.PrintError(err.Error())
+		return err
+	}
+
+	fmt.Println(ui.RenderTitle("Cache Entry " + shortHash(entry.PromptHash)))
+
+	fmt.Println(ui.InfoStyle.Render("\n📋 Metadata:"))
+	fmt.Printf("  Hash: %s\n", entry.PromptHash)
+	fmt.Printf("  Cached: %s\n", entry.CachedAt.Format(time.RFC3339))
+	fmt.Printf("  Expires: %s\n", entry.ExpiresAt.Format(time.RFC3339))
+	if entry.Response != nil {
+		fmt.Printf("  Provider: %s (%s)\n", entry.Response.Provider, entry.Response.Model)
+		fmt.Printf("  Tokens: %d\n", entry.Response.TokensUsed.TotalTokens)
+		fmt.Printf("  Cost: $%.6f\n", entry.Response.Cost)
+	}
+
+	if entry.Request != nil {
+		fmt.Println(ui.InfoStyle.Render("\n💬 Prompt:"))
+		fmt.Println(entry.Request.UserPrompt)
+		for _, m := range entry.Request.Messages {
+			fmt.Println(ui.RenderSubtle("\n[" + m.Role + "]"))
+			fmt.Println(m.Content)
+		}
+	}
+
+	if entry.Response != nil {
+		fmt.Println(ui.InfoStyle.Render("\n🧠 Response:"))
+		fmt.Println(entry.Response.Content)
+	}
+
+	return nil
+}
+
+// shortHash abbreviates a cache hash for display
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
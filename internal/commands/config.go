@@ -1,14 +1,20 @@
 package commands
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/lisvindanu/anaphase-cli/internal/ai"
 	"github.com/lisvindanu/anaphase-cli/internal/ui"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var configCmd = &cobra.Command{
@@ -16,18 +22,85 @@ var configCmd = &cobra.Command{
 	Short: "Manage Anaphase configuration",
 	Long: `Manage Anaphase configuration including AI providers, cache settings, and more.
 
+Settings are layered, each overriding the ones before it:
+  1. built-in defaults
+  2. global config    ~/.anaphase/config.yaml
+  3. project config   .anaphase.yaml in the project (or a parent directory)
+  4. profile          the selected entry of profiles (see config profile)
+  5. environment      ANAPHASE_<KEY>, e.g. ANAPHASE_AI_PRIMARY_PROVIDER
+  6. flags            e.g. --provider
+
 Available subcommands:
+  get             - Show a setting
+  set             - Change a setting
+  unset           - Remove a setting from a config file
   list            - Show current configuration
+  profile         - Manage named profiles
   set-provider    - Set default AI provider
-  check           - Health check all providers
+  check           - Health check all providers and show circuit breakers
   show-providers  - List available providers`,
 }
 
+var (
+	configGlobal     bool
+	configLocal      bool
+	configShowOrigin bool
+)
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Show a setting",
+	Long: `Show the effective value of a setting after all layers are applied.
+Keys are dotted paths into the config file. A key with nested settings
+prints them as YAML. API keys are redacted unless they are references
+such as ${GROQ_API_KEY}.
+
+Example:
+  anaphase config get ai.primary_provider
+  anaphase config get ai.providers.groq --show-origin`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting",
+	Long: `Write a setting to the global config (~/.anaphase/config.yaml), or with
+--local to the project's .anaphase.yaml so it applies to everyone working
+on the repository. Only the edited lines change, so comments and blank
+lines are kept.
+
+Lists may be given comma-separated or as YAML.
+
+Example:
+  anaphase config set ai.primary_provider groq
+  anaphase config set --local ai.providers.groq.model llama-3.3-70b-versatile
+  anaphase config set --local ai.fallback_providers openai,claude
+  anaphase config set ai.providers.groq.api_key '${GROQ_API_KEY}'`,
+	Args: cobra.ExactArgs(2),
+	RunE: runConfigSet,
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a setting from a config file",
+	Long: `Remove a setting from the global config, or with --local from the
+project's .anaphase.yaml, so the value from a lower layer applies again.
+
+Example:
+  anaphase config unset --local ai.primary_provider`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigUnset,
+}
+
 var configListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "Show current configuration",
-	Long:  "Display the current Anaphase configuration including AI providers, cache settings, and more.",
-	RunE:  runConfigList,
+	Long: `Display the current Anaphase configuration including AI providers, cache settings, and more.
+
+With --show-origin every setting is listed with the layer that set it:
+default, global, project, profile, env or flag.`,
+	RunE: runConfigList,
 }
 
 var configSetProviderCmd = &cobra.Command{
@@ -35,7 +108,8 @@ var configSetProviderCmd = &cobra.Command{
 	Short: "Set default AI provider",
 	Long: `Set the default AI provider for code generation.
 
-Available providers: gemini, groq, openai, claude, ollama
+Available providers: gemini, groq, openai, claude, ollama, plus any
+openai_compatible endpoints declared in config.yaml
 
 Example:
   anaphase config set-provider groq
@@ -47,8 +121,11 @@ Example:
 var configCheckCmd = &cobra.Command{
 	Use:   "check",
 	Short: "Health check all providers",
-	Long:  "Check the health and availability of all configured AI providers.",
-	RunE:  runConfigCheck,
+	Long: `Check the health and availability of all configured AI providers, and show
+each provider's circuit breaker state with its recent error rate and latency.
+
+A passing health check closes an open circuit breaker.`,
+	RunE: runConfigCheck,
 }
 
 var configShowProvidersCmd = &cobra.Command{
@@ -60,16 +137,203 @@ var configShowProvidersCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
 	configCmd.AddCommand(configListCmd)
 	configCmd.AddCommand(configSetProviderCmd)
 	configCmd.AddCommand(configCheckCmd)
 	configCmd.AddCommand(configShowProvidersCmd)
+
+	for _, cmd := range []*cobra.Command{configSetCmd, configUnsetCmd} {
+		cmd.Flags().BoolVar(&configGlobal, "global", false, "Use the global config (default)")
+		cmd.Flags().BoolVar(&configLocal, "local", false, "Use the project's .anaphase.yaml")
+		cmd.MarkFlagsMutuallyExclusive("global", "local")
+	}
+	configGetCmd.Flags().BoolVar(&configShowOrigin, "show-origin", false, "Show which layer set the value")
+	configListCmd.Flags().BoolVar(&configShowOrigin, "show-origin", false, "List every setting with the layer that set it")
+}
+
+// configScope returns the config file that set and unset write to along
+// with its layer name
+func configScope() (string, string, error) {
+	if !configLocal {
+		path, err := ai.GlobalConfigPath()
+		return path, ai.LayerGlobal, err
+	}
+	if path, ok := ai.FindProjectConfig(); ok {
+		return path, ai.LayerProject, nil
+	}
+	path, err := filepath.Abs(ai.ProjectConfigFile)
+	return path, ai.LayerProject, err
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	key := args[0]
+
+	layers, err := ai.ReadConfigLayers(configFlags()...)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
+		return err
+	}
+
+	value, ok := layers.Lookup(key)
+	if !ok {
+		ui.PrintError(fmt.Sprintf("%s is not set", key))
+		return fmt.Errorf("%s is not set", key)
+	}
+
+	if _, nested := value.(map[string]any); nested && configShowOrigin {
+		printConfigOrigins(layers, key+".")
+		return nil
+	}
+
+	text, err := formatConfigValue(key, value)
+	if err != nil {
+		return err
+	}
+	if configShowOrigin {
+		fmt.Printf("%s\t%s\n", layers.Origins[key], text)
+		return nil
+	}
+	fmt.Println(text)
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	key, raw := args[0], args[1]
+
+	value, err := ai.ParseConfigValue(key, raw)
+	if err != nil {
+		ui.PrintError(err.Error())
+		return err
+	}
+
+	path, layer, err := configScope()
+	if err != nil {
+		ui.PrintError(err.Error())
+		return err
+	}
+	if layer == ai.LayerProject {
+		if err := ai.CheckProjectSetting(key, value); err != nil {
+			ui.PrintError(err.Error())
+			return err
+		}
+	}
+	if err := ai.SetConfigValue(path, key, value); err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to update %s: %v", path, err))
+		return err
+	}
+
+	text, _ := formatConfigValue(key, value)
+	ui.PrintSuccess(fmt.Sprintf("Set %s = %s in %s", key, text, path))
+	warnIfOverridden(key, layer)
+	return nil
+}
+
+func runConfigUnset(cmd *cobra.Command, args []string) error {
+	key := args[0]
+
+	path, layer, err := configScope()
+	if err != nil {
+		ui.PrintError(err.Error())
+		return err
+	}
+	removed, err := ai.UnsetConfigValue(path, key)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to update %s: %v", path, err))
+		return err
+	}
+	if !removed {
+		ui.PrintInfo(fmt.Sprintf("%s is not set in %s", key, path))
+		return nil
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Removed %s from %s", key, path))
+	warnIfOverridden(key, layer)
+	return nil
+}
+
+// configLayerOrder lists the config layers from lowest to highest precedence
+var configLayerOrder = []string{ai.LayerDefault, ai.LayerGlobal, ai.LayerProject, ai.LayerProfile, ai.LayerEnv, ai.LayerFlag}
+
+// warnIfOverridden tells the user when a value just written to one layer is
+// still overridden by a higher one
+func warnIfOverridden(key, layer string) {
+	layers, err := ai.ReadConfigLayers(configFlags()...)
+	if err != nil {
+		return
+	}
+	origin, ok := layers.Origins[key]
+	if !ok || slices.Index(configLayerOrder, origin.Layer) <= slices.Index(configLayerOrder, layer) {
+		return
+	}
+	ui.PrintWarning(fmt.Sprintf("%s is still set by %s, which takes precedence", key, origin))
+}
+
+// printConfigOrigins lists every setting under prefix with its origin
+func printConfigOrigins(layers *ai.ConfigLayers, prefix string) {
+	var keys []string
+	width := 0
+	for _, key := range layers.Keys() {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+			width = max(width, len(layers.Origins[key].String()))
+		}
+	}
+
+	for _, key := range keys {
+		value, _ := layers.Lookup(key)
+		text, err := formatConfigValue(key, value)
+		if err != nil {
+			text = fmt.Sprint(value)
+		}
+		fmt.Printf("%-*s  %s=%s\n", width, layers.Origins[key], key, text)
+	}
+}
+
+// formatConfigValue renders a value for display: scalars as is, lists on
+// one line and nested settings as YAML, with literal API keys redacted
+func formatConfigValue(key string, value any) (string, error) {
+	value = ai.RedactConfigValue(key, value)
+	switch v := value.(type) {
+	case map[string]any:
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("encode %s: %w", key, err)
+		}
+		return strings.TrimRight(string(data), "\n"), nil
+	case []any:
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		node := &yaml.Node{}
+		if err := node.Encode(v); err != nil {
+			return "", fmt.Errorf("encode %s: %w", key, err)
+		}
+		node.Style = yaml.FlowStyle
+		if err := enc.Encode(node); err != nil {
+			return "", fmt.Errorf("encode %s: %w", key, err)
+		}
+		return strings.TrimRight(buf.String(), "\n"), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
 }
 
 func runConfigList(cmd *cobra.Command, args []string) error {
+	if configShowOrigin {
+		layers, err := ai.ReadConfigLayers(configFlags()...)
+		if err != nil {
+			ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
+			return err
+		}
+		printConfigOrigins(layers, "")
+		return nil
+	}
+
 	fmt.Println(ui.RenderTitle("Anaphase Configuration"))
 
-	cfg, err := ai.LoadConfig()
+	cfg, err := ai.LoadConfig(configFlags()...)
 	if err != nil {
 		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
 		return err
@@ -77,8 +341,13 @@ func runConfigList(cmd *cobra.Command, args []string) error {
 
 	// AI Configuration
 	fmt.Println(ui.InfoStyle.Render("\n🤖 AI Configuration:"))
+	if cfg.Profile != "" {
+		fmt.Printf("  Profile: %s\n", ui.SuccessStyle.Render(cfg.Profile))
+	}
 	fmt.Printf("  Primary Provider: %s\n", ui.SuccessStyle.Render(cfg.AI.PrimaryProvider))
 	fmt.Printf("  Fallback Providers: %v\n", cfg.AI.FallbackProviders)
+	fmt.Printf("  Max Rate Limit Wait: %s\n", cfg.AI.MaxRateLimitWait)
+	fmt.Printf("  Fixtures: %s (record: %v)\n", cfg.AI.Fixtures.Directory, cfg.AI.Fixtures.Record)
 	fmt.Println()
 
 	// Provider Details
@@ -88,6 +357,7 @@ func runConfigList(cmd *cobra.Command, args []string) error {
 	if cfg.AI.Providers.Gemini.Enabled || cfg.AI.Providers.Gemini.APIKey != "" {
 		fmt.Printf("  %s Gemini\n", ui.CheckmarkStyle.Render())
 		fmt.Printf("    Model: %s\n", cfg.AI.Providers.Gemini.Model)
+		fmt.Printf("    API Key: %s\n", ai.RedactSecret(cfg.AI.Providers.Gemini.APIKey))
 		fmt.Printf("    Timeout: %s\n", cfg.AI.Providers.Gemini.Timeout)
 		fmt.Printf("    Max Retries: %d\n", cfg.AI.Providers.Gemini.MaxRetries)
 	}
@@ -96,6 +366,7 @@ func runConfigList(cmd *cobra.Command, args []string) error {
 	if cfg.AI.Providers.Groq.Enabled || cfg.AI.Providers.Groq.APIKey != "" {
 		fmt.Printf("  %s Groq\n", ui.CheckmarkStyle.Render())
 		fmt.Printf("    Model: %s\n", cfg.AI.Providers.Groq.Model)
+		fmt.Printf("    API Key: %s\n", ai.RedactSecret(cfg.AI.Providers.Groq.APIKey))
 		fmt.Printf("    Timeout: %s\n", cfg.AI.Providers.Groq.Timeout)
 		fmt.Printf("    Max Retries: %d\n", cfg.AI.Providers.Groq.MaxRetries)
 	}
@@ -104,6 +375,31 @@ func runConfigList(cmd *cobra.Command, args []string) error {
 	if cfg.AI.Providers.OpenAI.Enabled || cfg.AI.Providers.OpenAI.APIKey != "" {
 		fmt.Printf("  %s OpenAI\n", ui.CheckmarkStyle.Render())
 		fmt.Printf("    Model: %s\n", cfg.AI.Providers.OpenAI.Model)
+		fmt.Printf("    API Key: %s\n", ai.RedactSecret(cfg.AI.Providers.OpenAI.APIKey))
+	}
+
+	// Claude
+	if cfg.AI.Providers.Claude.Enabled || cfg.AI.Providers.Claude.APIKey != "" {
+		fmt.Printf("  %s Claude\n", ui.CheckmarkStyle.Render())
+		fmt.Printf("    Model: %s\n", cfg.AI.Providers.Claude.Model)
+		fmt.Printf("    API Key: %s\n", ai.RedactSecret(cfg.AI.Providers.Claude.APIKey))
+	}
+
+	// Ollama
+	if cfg.AI.Providers.Ollama.Enabled {
+		fmt.Printf("  %s Ollama\n", ui.CheckmarkStyle.Render())
+		fmt.Printf("    Model: %s\n", cfg.AI.Providers.Ollama.Model)
+		fmt.Printf("    Base URL: %s\n", cfg.AI.Providers.Ollama.BaseURL)
+	}
+
+	// OpenAI-compatible endpoints
+	for _, compat := range cfg.AI.OpenAICompatible {
+		fmt.Printf("  %s %s %s\n", ui.CheckmarkStyle.Render(), compat.Name, ui.RenderSubtle("(openai-compatible)"))
+		fmt.Printf("    Model: %s\n", compat.Model)
+		if compat.APIKey != "" {
+			fmt.Printf("    API Key: %s\n", ai.RedactSecret(compat.APIKey))
+		}
+		fmt.Printf("    Base URL: %s\n", compat.BaseURL)
 	}
 
 	// Cache Configuration
@@ -111,6 +407,8 @@ func runConfigList(cmd *cobra.Command, args []string) error {
 	fmt.Printf("  Enabled: %v\n", cfg.Cache.Enabled)
 	fmt.Printf("  Directory: %s\n", cfg.Cache.Directory)
 	fmt.Printf("  TTL: %s\n", cfg.Cache.TTL)
+	fmt.Printf("  Max Size: %s\n", cfg.Cache.MaxSize)
+	fmt.Printf("  Any Provider: %v\n", cfg.Cache.AnyProvider)
 	fmt.Println()
 
 	// Generator Configuration
@@ -124,8 +422,17 @@ func runConfigList(cmd *cobra.Command, args []string) error {
 func runConfigSetProvider(cmd *cobra.Command, args []string) error {
 	provider := args[0]
 
+	cfg, err := ai.LoadConfig(configFlags()...)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
+		return err
+	}
+
 	// Validate provider
-	validProviders := []string{"gemini", "groq", "openai", "claude", "ollama"}
+	validProviders := append([]string{}, ai.BuiltinProviders...)
+	for _, compat := range cfg.AI.OpenAICompatible {
+		validProviders = append(validProviders, compat.Name)
+	}
 	valid := false
 	for _, p := range validProviders {
 		if p == provider {
@@ -140,17 +447,19 @@ func runConfigSetProvider(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid provider")
 	}
 
-	cfg, err := ai.LoadConfig()
+	// Update primary provider in the global config
+	path, err := ai.GlobalConfigPath()
 	if err != nil {
-		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
+		ui.PrintError(err.Error())
+		return err
+	}
+	if err := ai.SetConfigValue(path, "ai.primary_provider", provider); err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to update %s: %v", path, err))
 		return err
 	}
-
-	// Update primary provider
-	cfg.AI.PrimaryProvider = provider
 
 	ui.PrintSuccess(fmt.Sprintf("Default provider set to: %s", provider))
-	ui.PrintInfo("Note: Config changes are temporary. To persist, edit ~/.anaphase/config.yaml")
+	warnIfOverridden("ai.primary_provider", ai.LayerGlobal)
 
 	return nil
 }
@@ -158,7 +467,7 @@ func runConfigSetProvider(cmd *cobra.Command, args []string) error {
 func runConfigCheck(cmd *cobra.Command, args []string) error {
 	fmt.Println(ui.RenderTitle("Provider Health Check"))
 
-	cfg, err := ai.LoadConfig()
+	cfg, err := ai.LoadConfig(configFlags()...)
 	if err != nil {
 		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
 		return err
@@ -197,10 +506,45 @@ func runConfigCheck(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Circuit breakers and rolling statistics (includes this check)
+	fmt.Println(ui.InfoStyle.Render("\n⚡ Circuit Breakers:"))
+	stats := orchestrator.ProviderStats()
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		s := stats[name]
+		fmt.Printf("  %s %s\n", renderBreakerState(s.State), name)
+		fmt.Printf("    Calls: %d (%.0f%% errors, %d consecutive failures)\n",
+			s.Requests, s.ErrorRate*100, s.ConsecutiveFailures)
+		if s.AvgLatency > 0 {
+			fmt.Printf("    Latency: avg %s, p95 %s\n",
+				s.AvgLatency.Round(time.Millisecond), s.P95Latency.Round(time.Millisecond))
+		}
+		if s.State == ai.BreakerOpen {
+			fmt.Printf("    Retry after: %s\n", s.RetryAt.Format(time.Kitchen))
+		}
+	}
+
 	fmt.Println()
 	return nil
 }
 
+// renderBreakerState colors a circuit breaker state for display
+func renderBreakerState(state ai.BreakerState) string {
+	switch state {
+	case ai.BreakerOpen:
+		return ui.ErrorStyle.Render("[open]")
+	case ai.BreakerHalfOpen:
+		return ui.WarningStyle.Render("[half-open]")
+	default:
+		return ui.SuccessStyle.Render("[closed]")
+	}
+}
+
 func runConfigShowProviders(cmd *cobra.Command, args []string) error {
 	fmt.Println(ui.RenderTitle("Available AI Providers"))
 
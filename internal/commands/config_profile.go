@@ -0,0 +1,245 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/lisvindanu/anaphase-cli/internal/ai"
+	"github.com/lisvindanu/anaphase-cli/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	profileCreateProvider  string
+	profileCreateFallbacks []string
+	profileCreateUse       bool
+)
+
+// profileName keeps profile names usable in dotted config keys
+var profileName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+var configProfileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage named configuration profiles",
+	Long: `Profiles are named AI setups in the profiles section of a config file,
+such as a personal free-tier provider and a company gateway. Each profile
+lists only the ai settings it changes:
+
+  profiles:
+    personal:
+      primary_provider: groq
+    work:
+      primary_provider: gateway
+      openai_compatible:
+        - name: gateway
+          base_url: https://llm.internal.example.com/v1
+          api_key: ${GATEWAY_API_KEY}
+
+The selected profile is applied over the config files. Select one for a
+single run with --profile or ANAPHASE_PROFILE, or persistently with
+"anaphase config profile use".`,
+}
+
+var configProfileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles",
+	Long:  "List the profiles defined in the global and project config, marking the selected one.",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigProfileList,
+}
+
+var configProfileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Select a profile",
+	Long: `Select a profile for every following run by writing profile: <name> to
+the global config, or with --local to the project's .anaphase.yaml.
+
+Run "anaphase config unset profile" to go back to no profile.
+
+Example:
+  anaphase config profile use work`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigProfileUse,
+}
+
+var configProfileCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a profile",
+	Long: `Create a profile in the global config, or with --local in the project's
+.anaphase.yaml. Further settings are added with config set, using keys
+under profiles.<name>.
+
+Example:
+  anaphase config profile create personal --provider groq --use
+  anaphase config profile create work --provider gateway --fallback openai
+  anaphase config set profiles.work.providers.openai.model gpt-4o`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigProfileCreate,
+}
+
+func init() {
+	configCmd.AddCommand(configProfileCmd)
+	configProfileCmd.AddCommand(configProfileListCmd)
+	configProfileCmd.AddCommand(configProfileUseCmd)
+	configProfileCmd.AddCommand(configProfileCreateCmd)
+
+	for _, cmd := range []*cobra.Command{configProfileUseCmd, configProfileCreateCmd} {
+		cmd.Flags().BoolVar(&configGlobal, "global", false, "Use the global config (default)")
+		cmd.Flags().BoolVar(&configLocal, "local", false, "Use the project's .anaphase.yaml")
+		cmd.MarkFlagsMutuallyExclusive("global", "local")
+	}
+
+	configProfileCreateCmd.Flags().StringVar(&profileCreateProvider, "provider", "", "Primary provider of the profile")
+	configProfileCreateCmd.Flags().StringSliceVar(&profileCreateFallbacks, "fallback", nil, "Fallback providers of the profile (e.g. openai,claude)")
+	configProfileCreateCmd.Flags().BoolVar(&profileCreateUse, "use", false, "Select the profile once it is created")
+}
+
+func runConfigProfileList(cmd *cobra.Command, args []string) error {
+	layers, err := ai.ReadConfigLayers(configFlags()...)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
+		return err
+	}
+
+	names := profileNames(layers)
+	if len(names) == 0 {
+		ui.PrintInfo("No profiles defined")
+		fmt.Println(ui.RenderSubtle("  anaphase config profile create <name> --provider <provider>"))
+		return nil
+	}
+
+	fmt.Println(ui.RenderTitle("Configuration Profiles"))
+	fmt.Println()
+	width := 0
+	for _, name := range names {
+		width = max(width, len(name))
+	}
+	for _, name := range names {
+		provider, _ := layers.Lookup("profiles." + name + ".primary_provider")
+		if provider == nil {
+			provider = "-"
+		}
+		line := fmt.Sprintf("%-*s  %-12v %s", width, name, provider,
+			ui.RenderSubtle("("+strings.Join(profileLayers(layers, name), ", ")+")"))
+		fmt.Println(ui.RenderListItem(line, name == layers.Profile))
+	}
+
+	if layers.Profile != "" && !slices.Contains(names, layers.Profile) {
+		fmt.Println()
+		ui.PrintWarning(fmt.Sprintf("Selected profile %q is not defined (set by %s)", layers.Profile, layers.Origins["profile"]))
+	}
+	fmt.Println()
+	return nil
+}
+
+func runConfigProfileUse(cmd *cobra.Command, args []string) error {
+	name := strings.ToLower(args[0])
+
+	layers, err := ai.ReadConfigLayers()
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
+		return err
+	}
+	if names := profileNames(layers); !slices.Contains(names, name) {
+		ui.PrintError(fmt.Sprintf("Profile %q is not defined", name))
+		if len(names) > 0 {
+			fmt.Println("\nDefined profiles:", names)
+		}
+		return fmt.Errorf("unknown profile %q", name)
+	}
+
+	path, layer, err := configScope()
+	if err != nil {
+		ui.PrintError(err.Error())
+		return err
+	}
+	if err := ai.SetConfigValue(path, "profile", name); err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to update %s: %v", path, err))
+		return err
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Using profile %s (saved in %s)", name, path))
+	warnIfOverridden("profile", layer)
+	return nil
+}
+
+func runConfigProfileCreate(cmd *cobra.Command, args []string) error {
+	name := strings.ToLower(args[0])
+	if !profileName.MatchString(name) {
+		ui.PrintError(fmt.Sprintf("Invalid profile name %q: use letters, digits, - and _", args[0]))
+		return fmt.Errorf("invalid profile name %q", args[0])
+	}
+
+	layers, err := ai.ReadConfigLayers()
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
+		return err
+	}
+	if slices.Contains(profileNames(layers), name) {
+		ui.PrintError(fmt.Sprintf("Profile %q already exists in %s", name, strings.Join(profileLayers(layers, name), ", ")))
+		return fmt.Errorf("profile %q already exists", name)
+	}
+
+	// An empty mapping would read back as no profile at all
+	settings := map[string]any{"primary_provider": profileCreateProvider}
+	if profileCreateProvider == "" {
+		current, _ := layers.Lookup("ai.primary_provider")
+		settings["primary_provider"] = current
+	}
+	if len(profileCreateFallbacks) > 0 {
+		settings["fallback_providers"] = profileCreateFallbacks
+	}
+
+	path, layer, err := configScope()
+	if err != nil {
+		ui.PrintError(err.Error())
+		return err
+	}
+	if err := ai.SetConfigValue(path, "profiles."+name, settings); err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to update %s: %v", path, err))
+		return err
+	}
+	ui.PrintSuccess(fmt.Sprintf("Created profile %s in %s", name, path))
+
+	if profileCreateUse {
+		if err := ai.SetConfigValue(path, "profile", name); err != nil {
+			ui.PrintError(fmt.Sprintf("Failed to update %s: %v", path, err))
+			return err
+		}
+		ui.PrintSuccess(fmt.Sprintf("Using profile %s", name))
+		warnIfOverridden("profile", layer)
+	}
+
+	fmt.Println()
+	fmt.Println(ui.RenderSubtle("Add settings with:"))
+	fmt.Println(ui.RenderSubtle(fmt.Sprintf("  anaphase config set profiles.%s.<key> <value>", name)))
+	return nil
+}
+
+// profileNames returns the names of the defined profiles, sorted
+func profileNames(layers *ai.ConfigLayers) []string {
+	profiles, _ := layers.Lookup("profiles")
+	m, _ := profiles.(map[string]any)
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// profileLayers returns the layers that define settings of a profile
+func profileLayers(layers *ai.ConfigLayers, name string) []string {
+	var found []string
+	for _, key := range layers.Keys() {
+		if strings.HasPrefix(key, "profiles."+name+".") {
+			if layer := layers.Origins[key].Layer; !slices.Contains(found, layer) {
+				found = append(found, layer)
+			}
+		}
+	}
+	return found
+}
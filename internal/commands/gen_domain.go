@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/lisvindanu/anaphase-cli/internal/ai"
 	"github.com/lisvindanu/anaphase-cli/internal/generator"
 	"github.com/lisvindanu/anaphase-cli/internal/ui"
@@ -18,6 +21,14 @@ var (
 	genDomainOutput      string
 	genDomainProvider    string
 	genDomainInteractive bool
+	genDomainNoStream    bool
+	genDomainHedgeDelay  time.Duration
+	genDomainEnsemble    []string
+	genDomainMerge       string
+	genDomainRecord      bool
+	genDomainRefine      string
+	genDomainYes         bool
+	genDomainFromSpec    string
 )
 
 var genDomainCmd = &cobra.Command{
@@ -37,6 +48,14 @@ Example:
   anaphase gen domain "Order has ID, Total, Status. Can be cancelled if pending"
   anaphase gen domain "User with email" --provider groq
   anaphase gen domain "Product catalog" --provider gemini
+  anaphase gen domain "Invoice with lines" --provider ollama
+  anaphase gen domain "Invoice with lines" --no-stream
+  anaphase gen domain "Invoice with lines" --hedge-delay 3s
+  anaphase gen domain "Invoice with lines" --ensemble gemini,groq,openai
+  anaphase gen domain "Invoice with lines" --record
+  anaphase gen domain "Invoice with lines" --provider replay
+  anaphase gen domain --refine customer "add loyalty points and a tier upgrade rule"
+  anaphase gen domain --from-spec .anaphase/specs/customer.yaml
   anaphase gen domain --interactive`,
 	RunE: runGenDomain,
 }
@@ -45,8 +64,16 @@ func init() {
 	genCmd.AddCommand(genDomainCmd)
 
 	genDomainCmd.Flags().StringVar(&genDomainOutput, "output", "internal/core", "Output directory for generated files")
-	genDomainCmd.Flags().StringVar(&genDomainProvider, "provider", "", "AI provider to use (gemini, groq, openai, claude)")
+	genDomainCmd.Flags().StringVar(&genDomainProvider, "provider", "", "AI provider to use (gemini, groq, openai, claude, ollama, replay)")
 	genDomainCmd.Flags().BoolVarP(&genDomainInteractive, "interactive", "i", false, "Run in interactive mode")
+	genDomainCmd.Flags().BoolVar(&genDomainNoStream, "no-stream", false, "Wait for the full AI response instead of showing it live")
+	genDomainCmd.Flags().StringSliceVar(&genDomainEnsemble, "ensemble", nil, "Ask several providers in parallel and merge their specs (e.g. gemini,groq,openai)")
+	genDomainCmd.Flags().StringVar(&genDomainMerge, "merge", "majority", "Ensemble spec to use without prompting: majority, union, or a provider name")
+	genDomainCmd.Flags().DurationVar(&genDomainHedgeDelay, "hedge-delay", 0, "Also ask the next provider if no response arrives within this delay (overrides ai.hedge_delay)")
+	genDomainCmd.Flags().BoolVar(&genDomainRecord, "record", false, "Save the AI responses as fixtures for --provider replay")
+	genDomainCmd.Flags().StringVar(&genDomainRefine, "refine", "", "Change an existing domain instead of generating a new one")
+	genDomainCmd.Flags().StringVar(&genDomainFromSpec, "from-spec", "", "Generate from a saved spec file without calling an AI provider")
+	genDomainCmd.Flags().BoolVarP(&genDomainYes, "yes", "y", false, "Apply a refinement without asking for confirmation")
 }
 
 // promptInput prompts the user for input with a message
@@ -102,6 +129,10 @@ func promptChoice(message string, options []string, defaultIdx int) string {
 }
 
 func runGenDomain(cmd *cobra.Command, args []string) error {
+	if genDomainFromSpec != "" {
+		return runDomainFromSpec(genDomainFromSpec, genDomainOutput)
+	}
+
 	var description string
 	var provider string
 	var output string
@@ -120,7 +151,7 @@ func runGenDomain(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 
 		// Prompt for AI provider
-		providers := []string{"gemini", "groq", "openai", "claude"}
+		providers := []string{"gemini", "groq", "openai", "claude", "ollama"}
 		provider = promptChoice("Select AI provider:", providers, 0)
 		fmt.Println()
 
@@ -153,28 +184,59 @@ func runGenDomain(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 
 	// Setup logger
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(slog.LevelInfo)
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
+		Level: logLevel,
 	}))
 
-	// Load AI configuration
+	// Load AI configuration, with this command's flags as the top layer
 	fmt.Println("⚙️  Step 1/3: Loading configuration...")
-	cfg, err := ai.LoadConfig()
+	task := ai.TaskDomain
+	if genDomainRefine != "" {
+		task = ai.TaskRefine
+	}
+	flags := configFlags()
+	if provider != "" {
+		// An explicit provider also wins over a routing rule for this task
+		flags = append(flags,
+			ai.FlagValue{Flag: "--provider", Key: "ai.primary_provider", Value: provider},
+			ai.FlagValue{Flag: "--provider", Key: "ai.routing." + task, Value: []string{provider}},
+		)
+	}
+	// Local models need no key, so asking for one is enough to enable it
+	if provider == "ollama" {
+		flags = append(flags, ai.FlagValue{Flag: "--provider", Key: "ai.providers.ollama.enabled", Value: true})
+	} else if slices.Contains(genDomainEnsemble, "ollama") {
+		flags = append(flags, ai.FlagValue{Flag: "--ensemble", Key: "ai.providers.ollama.enabled", Value: true})
+	}
+	if genDomainRecord {
+		flags = append(flags, ai.FlagValue{Flag: "--record", Key: "ai.fixtures.record", Value: true})
+	}
+	if cmd.Flags().Changed("hedge-delay") {
+		flags = append(flags, ai.FlagValue{Flag: "--hedge-delay", Key: "ai.hedge_delay", Value: genDomainHedgeDelay.String()})
+	}
+
+	cfg, err := ai.LoadConfig(flags...)
 	if err != nil {
 		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
 		return fmt.Errorf("load config: %w", err)
 	}
-
-	// Override provider if specified
-	if provider != "" {
-		cfg.AI.PrimaryProvider = provider
-		ui.PrintInfo(fmt.Sprintf("Using provider: %s", provider))
+	if route := cfg.AI.Routing[task]; len(route) > 0 {
+		ui.PrintInfo(fmt.Sprintf("Using provider: %s (routed for %s)", route[0], task))
 	} else {
 		ui.PrintInfo(fmt.Sprintf("Using provider: %s", cfg.AI.PrimaryProvider))
 	}
+	if genDomainRecord {
+		ui.PrintInfo(fmt.Sprintf("Recording fixtures to %s", cfg.AI.Fixtures.Directory))
+	}
 
 	// Create orchestrator
 	orchestrator, err := ai.NewOrchestrator(cfg, logger)
+	if err != nil && genDomainRefine != "" {
+		ui.PrintError(fmt.Sprintf("Refining requires an AI provider: %v", err))
+		return fmt.Errorf("create orchestrator: %w", err)
+	}
 	if err != nil {
 		// AI not configured - offer template mode
 		ui.PrintWarning("AI provider not configured")
@@ -187,14 +249,49 @@ func runGenDomain(cmd *cobra.Command, args []string) error {
 
 	// Generate domain spec using AI
 	fmt.Println("\n🧠 Step 2/3: Analyzing with AI...")
-	ctx := context.Background()
-	spec, err := ai.GenerateDomain(ctx, orchestrator, description)
+	ctx := ai.WithCommand(context.Background(), "gen domain")
+
+	// Offer the existing core types so the spec reuses them
+	summary, err := ai.SummarizeCore(output)
+	if err != nil {
+		ui.PrintWarning(fmt.Sprintf("Could not read existing types: %v", err))
+	} else if len(summary.Types) > 0 {
+		ui.PrintInfo(fmt.Sprintf("Including %d existing type(s) from %s as context", len(summary.Types), output))
+		ctx = ai.WithProjectContext(ctx, summary)
+	}
+
+	if genDomainRefine != "" {
+		return refineDomain(ctx, orchestrator, output, genDomainRefine, description)
+	}
+
+	var spec *ai.DomainSpec
+	var attempts []ai.RepairAttempt
+	if len(genDomainEnsemble) > 0 {
+		logLevel.Set(slog.LevelWarn)
+		spec, err = ensembleDomainSpec(ctx, orchestrator, description, summary, cmd.Flags().Changed("merge"))
+		logLevel.Set(slog.LevelInfo)
+	} else if genDomainNoStream || cfg.AI.HedgeDelay > 0 {
+		// Racing providers needs complete responses, so hedging skips the live view
+		if cfg.AI.HedgeDelay > 0 {
+			ui.PrintInfo(fmt.Sprintf("Hedging: the next provider is also asked after %s without a response", cfg.AI.HedgeDelay))
+		}
+		spec, attempts, err = ai.GenerateDomainWithRepair(ctx, orchestrator, description)
+	} else {
+		// Keep info logs from tearing through the live view
+		logLevel.Set(slog.LevelWarn)
+		spec, attempts, err = streamDomainSpec(ctx, orchestrator, description)
+		logLevel.Set(slog.LevelInfo)
+	}
 
 	if err != nil {
 		ui.PrintError(fmt.Sprintf("AI generation failed: %v", err))
 		return fmt.Errorf("generate domain: %w", err)
 	}
 
+	if ai.Repaired(attempts) {
+		ui.PrintInfo(fmt.Sprintf("Invalid output was repaired after %d follow-up(s)", len(attempts)-1))
+	}
+
 	ui.PrintSuccess("AI Analysis Complete!")
 	fmt.Println()
 
@@ -221,6 +318,7 @@ func runGenDomain(cmd *cobra.Command, args []string) error {
 	for _, file := range files {
 		fmt.Println(ui.RenderListItem(file, true))
 	}
+	saveDomainSpec(spec)
 
 	fmt.Println()
 	ui.PrintSuccess("Domain generation complete! 🚀")
@@ -233,6 +331,288 @@ func runGenDomain(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// refineDomain asks the AI for a change to an existing domain, shows what
+// it adds, changes and removes, and applies it to the domain's files. The
+// saved spec is the starting point when there is one; otherwise the spec is
+// read back from the code.
+func refineDomain(ctx context.Context, orchestrator *ai.Orchestrator, output, domain, change string) error {
+	current, err := loadCurrentSpec(output, domain)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to load domain: %v", err))
+		return fmt.Errorf("load domain: %w", err)
+	}
+	ui.PrintInfo(fmt.Sprintf("Refining %s: %d entities, %d value objects", domain, len(current.Entities), len(current.ValueObjects)))
+
+	refined, changes, err := ai.RefineDomain(ctx, orchestrator, current, change)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("AI refinement failed: %v", err))
+		return fmt.Errorf("refine domain: %w", err)
+	}
+
+	if len(changes) == 0 {
+		ui.PrintInfo("The AI proposed no changes")
+		return nil
+	}
+
+	fmt.Println(ui.InfoStyle.Render("\nProposed Changes:"))
+	printSpecChanges(changes)
+	fmt.Println()
+
+	if !genDomainYes && promptChoice("Apply these changes?", []string{"yes", "no"}, 0) != "yes" {
+		ui.PrintInfo("No files were changed")
+		return nil
+	}
+
+	fmt.Println("📂 Step 3/3: Updating code files...")
+	files, err := generator.ApplyRefinement(output, current, refined)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Applying changes failed: %v", err))
+		return fmt.Errorf("apply refinement: %w", err)
+	}
+
+	fmt.Println(ui.SuccessStyle.Render("\nChanged Files:"))
+	for _, file := range files {
+		fmt.Printf("  %s (%s)\n", file.Path, file.Action)
+	}
+	saveDomainSpec(refined)
+
+	fmt.Println()
+	ui.PrintSuccess("Domain refinement complete! 🚀")
+	fmt.Println(ui.RenderSubtle("\nReview the changed files and run: go build ./..."))
+
+	return nil
+}
+
+// loadCurrentSpec returns the saved spec of a domain, or the spec read back
+// from its code when none was saved
+func loadCurrentSpec(output, domain string) (*ai.DomainSpec, error) {
+	path := ai.SpecPath(ai.DefaultSpecDir, domain)
+	if _, err := os.Stat(path); err == nil {
+		ui.PrintInfo(fmt.Sprintf("Using saved spec %s", path))
+		return loadSpecFile(path, output)
+	}
+	return generator.LoadDomainSpec(output, domain)
+}
+
+// loadSpecFile reads a spec file, which may use the types already in output
+func loadSpecFile(path, output string) (*ai.DomainSpec, error) {
+	existing, err := ai.SummarizeCore(output)
+	if err != nil {
+		return nil, fmt.Errorf("read existing types: %w", err)
+	}
+	return ai.LoadDomainSpecFile(path, existing)
+}
+
+// saveDomainSpec keeps the spec next to the code as the domain's reviewable
+// source of truth. Failing to save does not undo the generated files.
+func saveDomainSpec(spec *ai.DomainSpec) {
+	path, err := ai.SaveDomainSpec(ai.DefaultSpecDir, spec)
+	if err != nil {
+		ui.PrintWarning(fmt.Sprintf("Could not save spec: %v", err))
+		return
+	}
+	fmt.Println(ui.RenderListItem(path, true))
+}
+
+// runDomainFromSpec generates a domain from a saved spec file, without
+// loading AI configuration or calling a provider
+func runDomainFromSpec(path, output string) error {
+	fmt.Println(ui.RenderTitle("Domain Generation from Spec"))
+	ui.PrintInfo(fmt.Sprintf("Spec: %s", path))
+	fmt.Println()
+
+	spec, err := loadSpecFile(path, output)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Invalid spec: %v", err))
+		return fmt.Errorf("load spec: %w", err)
+	}
+
+	domainGen := generator.NewDomainGenerator(spec, output)
+	files, err := domainGen.Generate()
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Code generation failed: %v", err))
+		return fmt.Errorf("generate files: %w", err)
+	}
+
+	fmt.Println(ui.SuccessStyle.Render("Generated Files:"))
+	for _, file := range files {
+		fmt.Println(ui.RenderListItem(file, true))
+	}
+
+	fmt.Println()
+	ui.PrintSuccess("Domain generation complete! 🚀")
+
+	return nil
+}
+
+// printSpecChanges lists spec changes with +, ~ and - markers
+func printSpecChanges(changes []ai.SpecChange) {
+	for _, c := range changes {
+		switch c.Kind {
+		case ai.ChangeAdded:
+			fmt.Printf("  %s %s: %s\n", ui.SuccessStyle.Render("+"), c.Path, c.New)
+		case ai.ChangeChanged:
+			fmt.Printf("  %s %s: %s → %s\n", ui.WarningStyle.Render("~"), c.Path, c.Old, c.New)
+		case ai.ChangeRemoved:
+			fmt.Printf("  %s %s: %s\n", ui.ErrorStyle.Render("-"), c.Path, c.Old)
+		}
+	}
+}
+
+// ensembleDomainSpec collects specs from every ensemble provider, shows where
+// they disagree and lets the user pick the spec to generate from. A choice
+// that does not validate against existing is reported and asked again. With
+// skipPrompt the --merge choice is used as is.
+func ensembleDomainSpec(ctx context.Context, orchestrator *ai.Orchestrator, description string, existing *ai.CoreSummary, skipPrompt bool) (*ai.DomainSpec, error) {
+	ui.PrintInfo(fmt.Sprintf("Ensemble: %s", strings.Join(genDomainEnsemble, ", ")))
+
+	results, err := ai.GenerateEnsemble(ctx, orchestrator, description, genDomainEnsemble)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	var specs []*ai.DomainSpec
+	for _, r := range results {
+		if r.Err != nil {
+			ui.PrintWarning(fmt.Sprintf("%s failed: %v", r.Provider, r.Err))
+			continue
+		}
+		fmt.Printf("  %s %s: %d entities, %d value objects\n",
+			ui.CheckmarkStyle.Render(), r.Provider, len(r.Spec.Entities), len(r.Spec.ValueObjects))
+		names = append(names, r.Provider)
+		specs = append(specs, r.Spec)
+	}
+
+	printSpecDiff(names, specs)
+
+	if skipPrompt {
+		return selectEnsembleSpec(genDomainMerge, names, specs, existing)
+	}
+
+	options := append([]string{"majority", "union"}, names...)
+	options = append(options, "cancel")
+
+	defaultIdx := 0
+	for i, opt := range options {
+		if opt == genDomainMerge {
+			defaultIdx = i
+		}
+	}
+
+	for {
+		fmt.Println()
+		choice := promptChoice("Which spec should be generated? (majority and union merge all specs)", options, defaultIdx)
+
+		spec, err := selectEnsembleSpec(choice, names, specs, existing)
+		if err == nil || choice == "cancel" {
+			return spec, err
+		}
+		ui.PrintWarning(err.Error())
+	}
+}
+
+// selectEnsembleSpec resolves a --merge choice to a spec and validates it,
+// since a merge can keep a method whose types were outvoted
+func selectEnsembleSpec(choice string, names []string, specs []*ai.DomainSpec, existing *ai.CoreSummary) (*ai.DomainSpec, error) {
+	var spec *ai.DomainSpec
+	switch choice {
+	case "cancel":
+		return nil, fmt.Errorf("cancelled by user")
+	case "majority":
+		spec = ai.MergeDomainSpecs(specs, ai.MajorityVotes(len(specs)))
+	case "union":
+		spec = ai.MergeDomainSpecs(specs, 1)
+	default:
+		for i, name := range names {
+			if name == choice {
+				spec = specs[i]
+			}
+		}
+		if spec == nil {
+			return nil, fmt.Errorf("unknown merge choice %q (expected majority, union or one of %v)", choice, names)
+		}
+	}
+
+	if len(spec.Entities) == 0 {
+		return nil, fmt.Errorf("no entity was proposed by a majority of providers; use --merge union or pick one provider's spec")
+	}
+	if err := ai.ValidateDomainSpec(spec, existing); err != nil {
+		return nil, fmt.Errorf("the %s spec cannot be generated: %w", choice, err)
+	}
+
+	return spec, nil
+}
+
+// printSpecDiff shows the entities, fields and methods the specs disagree on
+func printSpecDiff(names []string, specs []*ai.DomainSpec) {
+	if len(specs) < 2 {
+		return
+	}
+
+	diffs := ai.DiffDomainSpecs(specs)
+	if len(diffs) == 0 {
+		ui.PrintSuccess("All providers produced the same spec")
+		return
+	}
+
+	fmt.Println(ui.InfoStyle.Render(fmt.Sprintf("\n📊 Differences (%d):", len(diffs))))
+	for _, diff := range diffs {
+		fmt.Printf("  %s\n", diff.Path)
+		for i, value := range diff.Values {
+			if value == "" {
+				value = ui.RenderSubtle("(missing)")
+			}
+			fmt.Printf("    %-10s %s\n", names[i]+":", value)
+		}
+	}
+}
+
+// streamDomainSpec generates the domain spec while showing the response live.
+// Invalid output is repaired with blocking follow-up requests.
+func streamDomainSpec(ctx context.Context, orchestrator *ai.Orchestrator, description string) (*ai.DomainSpec, []ai.RepairAttempt, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	chunks, err := ai.StreamDomain(ctx, orchestrator, description)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate: %w", err)
+	}
+
+	var resp *ai.GenerateResponse
+	next := func() (string, bool, error) {
+		for chunk := range chunks {
+			switch {
+			case chunk.Err != nil:
+				return "", false, chunk.Err
+			case chunk.Done:
+				resp = chunk.Response
+				return "", false, nil
+			case chunk.Content != "":
+				return chunk.Content, true, nil
+			}
+		}
+		return "", false, fmt.Errorf("stream closed unexpectedly")
+	}
+
+	model := ui.NewStreamModel("Waiting for AI response...", next)
+	if _, err := tea.NewProgram(model).Run(); err != nil {
+		return nil, nil, fmt.Errorf("run stream view: %w", err)
+	}
+
+	if model.Cancelled() {
+		return nil, nil, fmt.Errorf("cancelled by user")
+	}
+	if model.Err() != nil {
+		return nil, nil, fmt.Errorf("generate: %w", model.Err())
+	}
+	if resp == nil {
+		return nil, nil, fmt.Errorf("generate: stream ended without a response")
+	}
+
+	return ai.RepairDomainSpec(ctx, orchestrator, description, resp)
+}
+
 // runTemplateDomain generates domain using templates (no AI)
 func runTemplateDomain(description, output string) error {
 	fmt.Println(ui.RenderTitle("📝 Template Mode - Domain Generation"))
@@ -322,21 +702,21 @@ func runTemplateDomain(description, output string) error {
 		RepositoryInterface: ai.RepositorySpec{
 			Name: entityName + "Repository",
 			Methods: []ai.InterfaceMethod{
-				{Name: "Create", Signature: "Create(" + strings.ToLower(entityName) + " *" + entityName + ") error"},
-				{Name: "GetByID", Signature: "GetByID(id string) (*" + entityName + ", error)"},
-				{Name: "Update", Signature: "Update(" + strings.ToLower(entityName) + " *" + entityName + ") error"},
+				{Name: "Create", Signature: "Create(" + strings.ToLower(entityName) + " *entity." + entityName + ") error"},
+				{Name: "GetByID", Signature: "GetByID(id string) (*entity." + entityName + ", error)"},
+				{Name: "Update", Signature: "Update(" + strings.ToLower(entityName) + " *entity." + entityName + ") error"},
 				{Name: "Delete", Signature: "Delete(id string) error"},
-				{Name: "List", Signature: "List() ([]" + entityName + ", error)"},
+				{Name: "List", Signature: "List() ([]entity." + entityName + ", error)"},
 			},
 		},
 		ServiceInterface: ai.ServiceSpec{
 			Name: entityName + "Service",
 			Methods: []ai.InterfaceMethod{
-				{Name: "Create", Signature: "Create(" + strings.ToLower(entityName) + " *" + entityName + ") error"},
-				{Name: "Get", Signature: "Get(id string) (*" + entityName + ", error)"},
-				{Name: "Update", Signature: "Update(" + strings.ToLower(entityName) + " *" + entityName + ") error"},
+				{Name: "Create", Signature: "Create(" + strings.ToLower(entityName) + " *entity." + entityName + ") error"},
+				{Name: "Get", Signature: "Get(id string) (*entity." + entityName + ", error)"},
+				{Name: "Update", Signature: "Update(" + strings.ToLower(entityName) + " *entity." + entityName + ") error"},
 				{Name: "Delete", Signature: "Delete(id string) error"},
-				{Name: "ListAll", Signature: "ListAll() ([]" + entityName + ", error)"},
+				{Name: "ListAll", Signature: "ListAll() ([]entity." + entityName + ", error)"},
 			},
 		},
 	}
@@ -356,6 +736,7 @@ func runTemplateDomain(description, output string) error {
 	for _, file := range files {
 		fmt.Println(ui.RenderListItem(file, true))
 	}
+	saveDomainSpec(spec)
 
 	fmt.Println()
 	ui.PrintSuccess("✅ Template domain generation complete!")
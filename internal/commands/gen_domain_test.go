@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lisvindanu/anaphase-cli/internal/ai"
+)
+
+func TestSelectEnsembleSpecValidatesMerge(t *testing.T) {
+	issue := ai.InterfaceMethod{Name: "Issue", Signature: "Issue(ctx context.Context, invoice *entity.Invoice) error"}
+	spec := func(entities ...string) *ai.DomainSpec {
+		s := &ai.DomainSpec{
+			DomainName:          "order",
+			RepositoryInterface: ai.RepositorySpec{Name: "OrderRepository"},
+			ServiceInterface:    ai.ServiceSpec{Name: "OrderService", Methods: []ai.InterfaceMethod{issue}},
+		}
+		for _, name := range entities {
+			s.Entities = append(s.Entities, ai.EntitySpec{Name: name, Fields: []ai.FieldSpec{{Name: "ID", Type: "uuid.UUID"}}})
+		}
+		return s
+	}
+	names := []string{"gemini", "groq", "claude"}
+	specs := []*ai.DomainSpec{spec("Order", "Invoice"), spec("Order"), spec("Order")}
+
+	if _, err := selectEnsembleSpec("majority", names, specs, nil); err == nil || !strings.Contains(err.Error(), "entity.Invoice") {
+		t.Errorf("Expected the majority merge to be rejected for entity.Invoice, got %v", err)
+	}
+
+	// The type may also come from the project's existing code
+	existing := &ai.CoreSummary{Types: []ai.CoreType{{Package: "entity", Name: "Invoice", Kind: "struct"}}}
+	if _, err := selectEnsembleSpec("majority", names, specs, existing); err != nil {
+		t.Errorf("Expected the merge to validate against existing types, got %v", err)
+	}
+
+	if spec, err := selectEnsembleSpec("union", names, specs, nil); err != nil || len(spec.Entities) != 2 {
+		t.Errorf("Expected the union to keep Invoice and validate, got %v", err)
+	}
+}
@@ -168,7 +168,7 @@ func (m *testGeneratorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.err = msg.err
 		m.generatedFiles = msg.files
 		if m.err == nil {
-			m.progress.SetCurrent(m.progress.total)
+			m.progress.SetCurrent(m.progress.Total)
 		}
 		return m, tea.Quit
 	}
@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/lisvindanu/anaphase-cli/internal/ai"
+	"github.com/lisvindanu/anaphase-cli/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var promptsCmd = &cobra.Command{
+	Use:   "prompts",
+	Short: "Manage AI prompt templates",
+	Long: `Manage the text/template prompts sent to AI providers.
+
+Prompts are looked up in .anaphase/prompts/ of the current project, then in
+~/.anaphase/prompts/, then in the defaults built into anaphase. Eject a
+default to customize it.
+
+Available subcommands:
+  list    - List prompts with their version and source
+  show    - Print the template that is in effect
+  eject   - Copy a default prompt for editing`,
+}
+
+var promptsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List prompts with their version and source",
+	RunE:  runPromptsList,
+}
+
+var promptsShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Print the template that is in effect",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runPromptsShow,
+}
+
+var promptsEjectCmd = &cobra.Command{
+	Use:   "eject <name>",
+	Short: "Copy a default prompt for editing",
+	Long: `Copy a default prompt into .anaphase/prompts/ of the current project,
+or ~/.anaphase/prompts/ with --global, where it overrides the default.
+
+Keep the version comment on the first line and bump it when you change the
+prompt's meaning; cached responses are keyed by it and by the prompt's text.
+
+Example:
+  anaphase prompts eject domain_user
+  anaphase prompts eject domain_system --global`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPromptsEject,
+}
+
+var (
+	promptsEjectGlobal bool
+	promptsEjectForce  bool
+)
+
+func init() {
+	promptsEjectCmd.Flags().BoolVar(&promptsEjectGlobal, "global", false, "Eject to ~/.anaphase/prompts instead of the project")
+	promptsEjectCmd.Flags().BoolVar(&promptsEjectForce, "force", false, "Overwrite an existing prompt file")
+
+	rootCmd.AddCommand(promptsCmd)
+	promptsCmd.AddCommand(promptsListCmd)
+	promptsCmd.AddCommand(promptsShowCmd)
+	promptsCmd.AddCommand(promptsEjectCmd)
+}
+
+func runPromptsList(cmd *cobra.Command, args []string) error {
+	fmt.Println(ui.RenderTitle("AI Prompts"))
+
+	prompts, err := ai.DefaultPromptLoader().List()
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to load prompts: %v", err))
+		return err
+	}
+
+	fmt.Println()
+	fmt.Printf("  %-20s %-12s %-10s %s\n", "name", "version", "source", "path")
+	for _, p := range prompts {
+		path := p.Path
+		if path == "" {
+			path = ui.RenderSubtle("(built in)")
+		}
+		fmt.Printf("  %-20s %-12s %-10s %s\n", p.Name, p.Version, p.Source, path)
+	}
+
+	return nil
+}
+
+func runPromptsShow(cmd *cobra.Command, args []string) error {
+	prompt, err := ai.DefaultPromptLoader().Load(args[0])
+	if err != nil {
+		ui.PrintError(err.Error())
+		return err
+	}
+
+	source := prompt.Source
+	if prompt.Path != "" {
+		source = prompt.Path
+	}
+	fmt.Println(ui.RenderSubtle(fmt.Sprintf("# %s (version %s, %s)", prompt.Name, prompt.Version, source)))
+	fmt.Println(prompt.Text)
+
+	return nil
+}
+
+func runPromptsEject(cmd *cobra.Command, args []string) error {
+	path, err := ai.DefaultPromptLoader().Eject(args[0], promptsEjectGlobal, promptsEjectForce)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to eject prompt: %v", err))
+		return err
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Ejected %s to %s", args[0], path))
+	return nil
+}
@@ -7,12 +7,16 @@ import (
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/lisvindanu/anaphase-cli/internal/ai"
 	"github.com/lisvindanu/anaphase-cli/internal/ui"
 	"github.com/spf13/cobra"
 )
 
 var (
 	version = "0.5.0"
+
+	// rootProfile selects a named config profile for this run
+	rootProfile string
 )
 
 var rootCmd = &cobra.Command{
@@ -170,6 +174,16 @@ func init() {
 	// Global flags can be added here
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Enable verbose output")
 	rootCmd.PersistentFlags().BoolP("debug", "d", false, "Enable debug mode")
+	rootCmd.PersistentFlags().StringVar(&rootProfile, "profile", "", "Config profile to use (overrides ANAPHASE_PROFILE)")
+}
+
+// configFlags returns the config overrides of the global flags followed by
+// the given ones, for ai.LoadConfig and ai.ReadConfigLayers
+func configFlags(flags ...ai.FlagValue) []ai.FlagValue {
+	if rootProfile == "" {
+		return flags
+	}
+	return append([]ai.FlagValue{{Flag: "--profile", Key: "profile", Value: rootProfile}}, flags...)
 }
 
 // exitWithError prints an error message and exits with status 1
@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lisvindanu/anaphase-cli/internal/ai"
+	"github.com/lisvindanu/anaphase-cli/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show AI token usage and spending",
+	Long: `Show token usage and spending recorded in the usage ledger
+(~/.anaphase/usage.jsonl by default), along with the configured caps.
+
+Example:
+  anaphase usage                   # Last 30 days by day
+  anaphase usage --by provider     # Group by provider
+  anaphase usage --by command --days 7`,
+	RunE: runUsage,
+}
+
+var (
+	usageBy   string
+	usageDays int
+)
+
+func init() {
+	usageCmd.Flags().StringVar(&usageBy, "by", "day", "Group by: day, provider, model, or command")
+	usageCmd.Flags().IntVar(&usageDays, "days", 30, "Number of days to include")
+
+	rootCmd.AddCommand(usageCmd)
+}
+
+func runUsage(cmd *cobra.Command, args []string) error {
+	fmt.Println(ui.RenderTitle("AI Usage"))
+
+	cfg, err := ai.LoadConfig(configFlags()...)
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to load config: %v", err))
+		return err
+	}
+
+	ledger := ai.NewUsageLedger(cfg.Usage.File)
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	records, err := ledger.Records(today.AddDate(0, 0, 1-usageDays))
+	if err != nil {
+		ui.PrintError(fmt.Sprintf("Failed to read usage ledger: %v", err))
+		return err
+	}
+
+	groups, err := ai.SummarizeUsage(records, usageBy)
+	if err != nil {
+		ui.PrintError(err.Error())
+		return err
+	}
+
+	fmt.Println()
+	if len(groups) == 0 {
+		ui.PrintInfo(fmt.Sprintf("No usage recorded in the last %d days", usageDays))
+	} else {
+		fmt.Printf("  %-32s %8s %8s %10s %10s\n", usageBy, "requests", "repairs", "tokens", "cost")
+		var total ai.UsageGroup
+		for _, g := range groups {
+			fmt.Printf("  %-32s %8d %8d %10d %10s\n", g.Key, g.Requests, g.Repairs, g.Tokens, formatCost(g.Cost))
+			total.Requests += g.Requests
+			total.Repairs += g.Repairs
+			total.Tokens += g.Tokens
+			total.Cost += g.Cost
+		}
+		fmt.Println(ui.RenderSubtle(fmt.Sprintf("  %-32s %8d %8d %10d %10s", "total", total.Requests, total.Repairs, total.Tokens, formatCost(total.Cost))))
+	}
+
+	// Budget status
+	fmt.Println(ui.InfoStyle.Render("\n💰 Budgets:"))
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	printBudget(ledger, "Today", today, cfg.Usage.DailyLimit)
+	printBudget(ledger, "This month", monthStart, cfg.Usage.MonthlyLimit)
+	fmt.Println()
+	fmt.Println(ui.RenderSubtle("  Ledger: " + ledger.Path()))
+
+	return nil
+}
+
+// printBudget shows spending since a point in time against its cap
+func printBudget(ledger *ai.UsageLedger, label string, since time.Time, limit float64) {
+	spent, err := ledger.Spent(since)
+	if err != nil {
+		fmt.Printf("  %s %s: %v\n", ui.CrossStyle.Render(), label, err)
+		return
+	}
+
+	if limit <= 0 {
+		fmt.Printf("  %s: %s (no cap)\n", label, formatCost(spent))
+		return
+	}
+
+	status := ui.CheckmarkStyle.Render()
+	if spent >= limit {
+		status = ui.CrossStyle.Render()
+	}
+	fmt.Printf("  %s %s: %s of %s (%.0f%%)\n", status, label, formatCost(spent), formatCost(limit), spent/limit*100)
+}
+
+// formatCost renders a USD amount, keeping precision for small sums
+func formatCost(cost float64) string {
+	if cost > 0 && cost < 0.01 {
+		return fmt.Sprintf("$%.4f", cost)
+	}
+	return fmt.Sprintf("$%.2f", cost)
+}
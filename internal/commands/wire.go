@@ -36,7 +36,8 @@ func init() {
 }
 
 func runWire(cmd *cobra.Command, args []string) error {
-	fmt.Println("⚡ Auto-wiring dependencies...\n")
+	fmt.Println("⚡ Auto-wiring dependencies...")
+	fmt.Println()
 
 	// Create logger
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
@@ -24,10 +24,15 @@ func NewDomainGenerator(spec *ai.DomainSpec, outputDir string) *DomainGenerator
 	}
 }
 
-// Generate creates all domain files
+// Generate creates all domain files. The spec is validated first, so a spec
+// that would produce code that does not compile writes nothing.
 func (g *DomainGenerator) Generate() ([]string, error) {
 	var generatedFiles []string
 
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+
 	// Create directory structure
 	if err := g.createDirectories(); err != nil {
 		return nil, fmt.Errorf("create directories: %w", err)
@@ -68,6 +73,16 @@ func (g *DomainGenerator) Generate() ([]string, error) {
 	return generatedFiles, nil
 }
 
+// validate checks the spec, allowing references to the types already in
+// the output directory
+func (g *DomainGenerator) validate() error {
+	existing, err := ai.SummarizeCore(g.outputDir)
+	if err != nil {
+		return fmt.Errorf("read existing types: %w", err)
+	}
+	return ai.ValidateDomainSpec(g.spec, existing)
+}
+
 func (g *DomainGenerator) createDirectories() error {
 	dirs := []string{
 		filepath.Join(g.outputDir, "entity"),
@@ -133,17 +148,7 @@ func (g *DomainGenerator) generateEntity(entity ai.EntitySpec) (string, error) {
 	b.WriteString(fmt.Sprintf("type %s struct {\n", entity.Name))
 
 	for _, field := range entity.Fields {
-		fieldType := field.Type
-		// Add valueobject package prefix if it's a value object and not already qualified
-		if isValueObjectType(field.Type) && !strings.HasPrefix(field.Type, "valueobject.") {
-			fieldType = "valueobject." + field.Type
-		}
-
-		if field.Description != "" {
-			b.WriteString(fmt.Sprintf("\t%s %s // %s\n", field.Name, fieldType, field.Description))
-		} else {
-			b.WriteString(fmt.Sprintf("\t%s %s\n", field.Name, fieldType))
-		}
+		b.WriteString(entityFieldLine(field))
 	}
 
 	b.WriteString("}\n\n")
@@ -195,19 +200,7 @@ func (g *DomainGenerator) generateEntity(entity ai.EntitySpec) (string, error) {
 	// Methods
 	for _, method := range entity.Methods {
 		b.WriteString("\n")
-		if method.Description != "" {
-			b.WriteString(fmt.Sprintf("// %s %s\n", method.Name, method.Description))
-		}
-		b.WriteString(method.Signature + " {\n")
-		b.WriteString("\t// TODO: Implement business logic\n")
-
-		// Add return statement only if method has return type (doesn't end with just ")")
-		trimmedSig := strings.TrimSpace(method.Signature)
-		if !strings.HasSuffix(trimmedSig, ")") {
-			b.WriteString("\treturn nil\n")
-		}
-
-		b.WriteString("}\n")
+		writeMethodStub(&b, method)
 	}
 
 	// Write file
@@ -221,6 +214,16 @@ func (g *DomainGenerator) generateEntity(entity ai.EntitySpec) (string, error) {
 func (g *DomainGenerator) generateValueObject(vo ai.ValueObjectSpec) (string, error) {
 	filename := filepath.Join(g.outputDir, "valueobject", toSnakeCase(vo.Name)+".go")
 
+	// Write file
+	if err := os.WriteFile(filename, []byte(valueObjectSource(vo)), 0644); err != nil {
+		return "", err
+	}
+
+	return filename, nil
+}
+
+// valueObjectSource renders the file for a value object
+func valueObjectSource(vo ai.ValueObjectSpec) string {
 	var b strings.Builder
 
 	// Package
@@ -276,12 +279,7 @@ func (g *DomainGenerator) generateValueObject(vo ai.ValueObjectSpec) (string, er
 	b.WriteString("\treturn nil\n")
 	b.WriteString("}\n")
 
-	// Write file
-	if err := os.WriteFile(filename, []byte(b.String()), 0644); err != nil {
-		return "", err
-	}
-
-	return filename, nil
+	return b.String()
 }
 
 func (g *DomainGenerator) generateRepository() (string, error) {
@@ -304,10 +302,7 @@ func (g *DomainGenerator) generateRepository() (string, error) {
 	b.WriteString(fmt.Sprintf("type %s interface {\n", g.spec.RepositoryInterface.Name))
 
 	for _, method := range g.spec.RepositoryInterface.Methods {
-		if method.Description != "" {
-			b.WriteString(fmt.Sprintf("\t// %s %s\n", method.Name, method.Description))
-		}
-		b.WriteString(fmt.Sprintf("\t%s\n\n", method.Signature))
+		b.WriteString(interfaceMethodLines(method))
 	}
 
 	b.WriteString("}\n")
@@ -340,10 +335,7 @@ func (g *DomainGenerator) generateService() (string, error) {
 	b.WriteString(fmt.Sprintf("type %s interface {\n", g.spec.ServiceInterface.Name))
 
 	for _, method := range g.spec.ServiceInterface.Methods {
-		if method.Description != "" {
-			b.WriteString(fmt.Sprintf("\t// %s %s\n", method.Name, method.Description))
-		}
-		b.WriteString(fmt.Sprintf("\t%s\n\n", method.Signature))
+		b.WriteString(interfaceMethodLines(method))
 	}
 
 	b.WriteString("}\n")
@@ -356,6 +348,52 @@ func (g *DomainGenerator) generateService() (string, error) {
 	return filename, nil
 }
 
+// entityFieldLine renders a struct field of an entity, qualifying value
+// object types with their package
+func entityFieldLine(field ai.FieldSpec) string {
+	fieldType := qualifyFieldType(field.Type)
+	if field.Description != "" {
+		return fmt.Sprintf("\t%s %s // %s\n", field.Name, fieldType, field.Description)
+	}
+	return fmt.Sprintf("\t%s %s\n", field.Name, fieldType)
+}
+
+// qualifyFieldType adds the valueobject package prefix to value object
+// types that are not already qualified
+func qualifyFieldType(fieldType string) string {
+	if isValueObjectType(fieldType) && !strings.HasPrefix(fieldType, "valueobject.") {
+		return "valueobject." + fieldType
+	}
+	return fieldType
+}
+
+// writeMethodStub renders an entity method with a TODO body
+func writeMethodStub(b *strings.Builder, method ai.MethodSpec) {
+	if method.Description != "" {
+		b.WriteString(fmt.Sprintf("// %s %s\n", method.Name, method.Description))
+	}
+	b.WriteString(method.Signature + " {\n")
+	b.WriteString("\t// TODO: Implement business logic\n")
+
+	// Add return statement only if method has return type (doesn't end with just ")")
+	trimmedSig := strings.TrimSpace(method.Signature)
+	if !strings.HasSuffix(trimmedSig, ")") {
+		b.WriteString("\treturn nil\n")
+	}
+
+	b.WriteString("}\n")
+}
+
+// interfaceMethodLines renders an interface method followed by a blank line
+func interfaceMethodLines(method ai.InterfaceMethod) string {
+	var b strings.Builder
+	if method.Description != "" {
+		b.WriteString(fmt.Sprintf("\t// %s %s\n", method.Name, method.Description))
+	}
+	b.WriteString(fmt.Sprintf("\t%s\n\n", method.Signature))
+	return b.String()
+}
+
 // toSnakeCase converts PascalCase to snake_case
 func toSnakeCase(s string) string {
 	var result []rune
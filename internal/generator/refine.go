@@ -0,0 +1,989 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lisvindanu/anaphase-cli/internal/ai"
+)
+
+// knownImports are the packages refined files may start or stop using
+var knownImports = map[string]string{
+	"context":     "context",
+	"errors":      "errors",
+	"fmt":         "fmt",
+	"time":        "time",
+	"uuid":        "github.com/google/uuid",
+	"entity":      "github.com/lisvindanu/anaphase-cli/internal/core/entity",
+	"valueobject": "github.com/lisvindanu/anaphase-cli/internal/core/valueobject",
+}
+
+var (
+	entityRefPattern      = regexp.MustCompile(`\bentity\.([A-Z]\w*)`)
+	valueObjectRefPattern = regexp.MustCompile(`\bvalueobject\.([A-Z]\w*)`)
+)
+
+// FileChange is a file created, updated or removed by a refinement
+type FileChange struct {
+	Path   string
+	Action string // created, updated or removed
+}
+
+// LoadDomainSpec rebuilds the spec of a generated domain from its code. The
+// domain consists of its repository and service ports, the entity named
+// after it, and the entities and value objects those reference.
+func LoadDomainSpec(outputDir, domain string) (*ai.DomainSpec, error) {
+	name := domainTypeName(domain)
+	spec := &ai.DomainSpec{
+		DomainName:          strings.ToLower(domain),
+		RepositoryInterface: ai.RepositorySpec{Name: name + "Repository"},
+		ServiceInterface:    ai.ServiceSpec{Name: name + "Service"},
+	}
+
+	portDir := filepath.Join(outputDir, "port")
+	repo, err := findTypeFile(portDir, spec.RepositoryInterface.Name)
+	if err != nil {
+		return nil, err
+	}
+	service, err := findTypeFile(portDir, spec.ServiceInterface.Name)
+	if err != nil {
+		return nil, err
+	}
+	if repo != nil {
+		spec.RepositoryInterface.Methods = repo.interfaceMethods(spec.RepositoryInterface.Name)
+	}
+	if service != nil {
+		spec.ServiceInterface.Methods = service.interfaceMethods(spec.ServiceInterface.Name)
+	}
+
+	// Entities: the one named after the domain, then those the ports use
+	var signatures []string
+	for _, m := range append(spec.RepositoryInterface.Methods, spec.ServiceInterface.Methods...) {
+		signatures = append(signatures, m.Signature)
+	}
+	entityNames := []string{name}
+	for _, match := range entityRefPattern.FindAllStringSubmatch(strings.Join(signatures, "\n"), -1) {
+		entityNames = appendUnique(entityNames, match[1])
+	}
+
+	for _, entityName := range entityNames {
+		file, err := findTypeFile(filepath.Join(outputDir, "entity"), entityName)
+		if err != nil {
+			return nil, err
+		}
+		if file == nil {
+			continue
+		}
+		entity := file.entitySpec(entityName)
+		spec.Entities = append(spec.Entities, entity)
+
+		for _, f := range entity.Fields {
+			signatures = append(signatures, f.Type)
+		}
+		for _, m := range entity.Methods {
+			signatures = append(signatures, m.Signature)
+		}
+	}
+
+	if len(spec.Entities) == 0 && repo == nil && service == nil {
+		return nil, fmt.Errorf("domain %q not found in %s", domain, outputDir)
+	}
+
+	// Value objects used by the domain's entities and ports
+	var voNames []string
+	for _, match := range valueObjectRefPattern.FindAllStringSubmatch(strings.Join(signatures, "\n"), -1) {
+		voNames = appendUnique(voNames, match[1])
+	}
+	for _, voName := range voNames {
+		file, err := findTypeFile(filepath.Join(outputDir, "valueobject"), voName)
+		if err != nil {
+			return nil, err
+		}
+		if file != nil {
+			spec.ValueObjects = append(spec.ValueObjects, file.valueObjectSpec(voName))
+		}
+	}
+
+	return spec, nil
+}
+
+// ApplyRefinement updates the domain's files from current to refined. Only
+// the fields, methods and types that were added, changed or removed are
+// edited, so hand-written method bodies and comments survive.
+func ApplyRefinement(outputDir string, current, refined *ai.DomainSpec) ([]FileChange, error) {
+	g := NewDomainGenerator(refined, outputDir)
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+	if err := g.createDirectories(); err != nil {
+		return nil, fmt.Errorf("create directories: %w", err)
+	}
+
+	var changes []FileChange
+	record := func(path, action string) {
+		changes = append(changes, FileChange{Path: path, Action: action})
+	}
+
+	// Entities
+	entityDir := filepath.Join(outputDir, "entity")
+	for _, entity := range refined.Entities {
+		before, ok := findEntity(current.Entities, entity.Name)
+		file, err := findTypeFile(entityDir, before.Name)
+		if err != nil {
+			return changes, fmt.Errorf("locate entity %s: %w", entity.Name, err)
+		}
+		if !ok || file == nil {
+			path, err := g.generateEntity(entity)
+			if err != nil {
+				return changes, fmt.Errorf("generate entity %s: %w", entity.Name, err)
+			}
+			record(path, "created")
+			continue
+		}
+
+		updated, err := file.patchEntity(before.Name, entity)
+		if err != nil {
+			return changes, fmt.Errorf("update entity %s: %w", entity.Name, err)
+		}
+		if updated {
+			record(file.path, "updated")
+		}
+	}
+	for _, entity := range current.Entities {
+		if _, ok := findEntity(refined.Entities, entity.Name); !ok {
+			if err := removeType(entityDir, entity.Name, record); err != nil {
+				return changes, err
+			}
+		}
+	}
+
+	// Value objects are regenerated when they change; only their Validate
+	// body is carried over
+	voDir := filepath.Join(outputDir, "valueobject")
+	for _, vo := range refined.ValueObjects {
+		before, ok := findValueObject(current.ValueObjects, vo.Name)
+		file, err := findTypeFile(voDir, before.Name)
+		if err != nil {
+			return changes, fmt.Errorf("locate value object %s: %w", vo.Name, err)
+		}
+		if !ok || file == nil {
+			path, err := g.generateValueObject(vo)
+			if err != nil {
+				return changes, fmt.Errorf("generate value object %s: %w", vo.Name, err)
+			}
+			record(path, "created")
+			continue
+		}
+		if sameValueObject(before, vo) {
+			continue
+		}
+
+		if err := file.rewriteValueObject(before.Name, vo); err != nil {
+			return changes, fmt.Errorf("update value object %s: %w", vo.Name, err)
+		}
+		record(file.path, "updated")
+	}
+	for _, vo := range current.ValueObjects {
+		if _, ok := findValueObject(refined.ValueObjects, vo.Name); !ok {
+			if err := removeType(voDir, vo.Name, record); err != nil {
+				return changes, err
+			}
+		}
+	}
+
+	// Ports
+	portDir := filepath.Join(outputDir, "port")
+	ports := []struct {
+		name     string
+		after    []ai.InterfaceMethod
+		generate func() (string, error)
+	}{
+		{refined.RepositoryInterface.Name, refined.RepositoryInterface.Methods, g.generateRepository},
+		{refined.ServiceInterface.Name, refined.ServiceInterface.Methods, g.generateService},
+	}
+	for _, port := range ports {
+		file, err := findTypeFile(portDir, port.name)
+		if err != nil {
+			return changes, err
+		}
+		if file == nil {
+			path, err := port.generate()
+			if err != nil {
+				return changes, fmt.Errorf("generate %s: %w", port.name, err)
+			}
+			record(path, "created")
+			continue
+		}
+
+		updated, err := file.patchInterface(port.name, port.after)
+		if err != nil {
+			return changes, fmt.Errorf("update %s: %w", port.name, err)
+		}
+		if updated {
+			record(file.path, "updated")
+		}
+	}
+
+	return changes, nil
+}
+
+// sourceFile is a parsed Go file together with its text, for edits that
+// keep everything they do not touch byte for byte
+type sourceFile struct {
+	path string
+	src  []byte
+	fset *token.FileSet
+	file *ast.File
+}
+
+func parseSourceFile(path string) (*sourceFile, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	return &sourceFile{path: path, src: src, fset: fset, file: file}, nil
+}
+
+// findTypeFile returns the file in dir declaring the named type, or nil
+func findTypeFile(dir, name string) (*sourceFile, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") || strings.HasSuffix(entry.Name(), "_test.go") {
+			continue
+		}
+		file, err := parseSourceFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue // Files that do not parse cannot be refined
+		}
+		if file.typeSpec(name) != nil {
+			return file, nil
+		}
+	}
+	return nil, nil
+}
+
+// removeType deletes the named type from the file declaring it, together
+// with its methods, its constructor and the errors generated for it. Other
+// declarations in the file are kept; the file itself is deleted only when
+// nothing else is left in it.
+func removeType(dir, name string, record func(path, action string)) error {
+	file, err := findTypeFile(dir, name)
+	if err != nil || file == nil {
+		return err
+	}
+
+	edits, remaining := file.typeRemoval(name)
+	if remaining == 0 {
+		if err := os.Remove(file.path); err != nil {
+			return fmt.Errorf("remove %s: %w", file.path, err)
+		}
+		record(file.path, "removed")
+		return nil
+	}
+
+	if _, err := file.write(edits, filepath.Base(dir)); err != nil {
+		return fmt.Errorf("remove %s from %s: %w", name, file.path, err)
+	}
+	record(file.path, "updated")
+	return nil
+}
+
+// typeRemoval returns the edits deleting the named type's declarations and
+// the number of declarations, imports aside, that remain
+func (f *sourceFile) typeRemoval(name string) ([]edit, int) {
+	owned := func(ident string) bool {
+		return ident == name || ident == "New"+name || ident == "Err"+name+"NotFound" || ident == "ErrInvalid"+name
+	}
+
+	var edits []edit
+	remaining := 0
+	for _, decl := range f.file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			method := d.Recv != nil && len(d.Recv.List) > 0 && receiverTypeName(d.Recv.List[0].Type) == name
+			if method || (d.Recv == nil && owned(d.Name.Name)) {
+				edits = append(edits, f.deleteLines(d.Doc, d.Pos(), d.End()))
+			} else {
+				remaining++
+			}
+
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			var dropped []edit
+			for _, spec := range d.Specs {
+				if doc, ok := ownedSpec(spec, owned); ok {
+					dropped = append(dropped, f.deleteLines(doc, spec.Pos(), spec.End()))
+				}
+			}
+			if len(dropped) == len(d.Specs) {
+				edits = append(edits, f.deleteLines(d.Doc, d.Pos(), d.End()))
+				continue
+			}
+			edits = append(edits, dropped...)
+			remaining++
+		}
+	}
+	return edits, remaining
+}
+
+// ownedSpec reports whether every name a type, var or const spec declares
+// is owned, returning the spec's doc comment
+func ownedSpec(spec ast.Spec, owned func(string) bool) (*ast.CommentGroup, bool) {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return s.Doc, owned(s.Name.Name)
+	case *ast.ValueSpec:
+		for _, ident := range s.Names {
+			if !owned(ident.Name) {
+				return nil, false
+			}
+		}
+		return s.Doc, true
+	}
+	return nil, false
+}
+
+func (f *sourceFile) offset(pos token.Pos) int {
+	return f.fset.Position(pos).Offset
+}
+
+func (f *sourceFile) text(from, to token.Pos) string {
+	return string(f.src[f.offset(from):f.offset(to)])
+}
+
+// lineStart returns the offset of the start of pos's line
+func (f *sourceFile) lineStart(pos token.Pos) int {
+	offset := f.offset(pos)
+	for offset > 0 && f.src[offset-1] != '\n' {
+		offset--
+	}
+	return offset
+}
+
+// lineEnd returns the offset just past the end of pos's line
+func (f *sourceFile) lineEnd(pos token.Pos) int {
+	offset := f.offset(pos)
+	for offset < len(f.src) && f.src[offset] != '\n' {
+		offset++
+	}
+	if offset < len(f.src) {
+		offset++
+	}
+	return offset
+}
+
+func (f *sourceFile) typeSpec(name string) *ast.TypeSpec {
+	_, ts := f.lookupType(name)
+	return ts
+}
+
+// typeDecl returns the declaration, possibly grouped, of the named type
+func (f *sourceFile) typeDecl(name string) *ast.GenDecl {
+	gen, _ := f.lookupType(name)
+	return gen
+}
+
+func (f *sourceFile) lookupType(name string) (*ast.GenDecl, *ast.TypeSpec) {
+	for _, decl := range f.file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.TYPE {
+			for _, spec := range gen.Specs {
+				if ts := spec.(*ast.TypeSpec); ts.Name.Name == name {
+					return gen, ts
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// typeDoc returns the doc comment of the named type's declaration
+func (f *sourceFile) typeDoc(name string) string {
+	gen, ts := f.lookupType(name)
+	if ts == nil {
+		return ""
+	}
+	if ts.Doc != nil {
+		return ts.Doc.Text()
+	}
+	return gen.Doc.Text()
+}
+
+// methods returns the methods declared on the named type
+func (f *sourceFile) methods(typeName string) []*ast.FuncDecl {
+	var methods []*ast.FuncDecl
+	for _, decl := range f.file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if ok && fn.Recv != nil && len(fn.Recv.List) > 0 && receiverTypeName(fn.Recv.List[0].Type) == typeName {
+			methods = append(methods, fn)
+		}
+	}
+	return methods
+}
+
+// bodyComments returns the comments inside a function body
+func (f *sourceFile) bodyComments(fn *ast.FuncDecl) []*ast.Comment {
+	var comments []*ast.Comment
+	if fn == nil || fn.Body == nil {
+		return nil
+	}
+	for _, group := range f.file.Comments {
+		for _, c := range group.List {
+			if c.Pos() > fn.Body.Lbrace && c.End() < fn.Body.Rbrace {
+				comments = append(comments, c)
+			}
+		}
+	}
+	return comments
+}
+
+func receiverTypeName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverTypeName(e.X)
+	case *ast.IndexExpr:
+		return receiverTypeName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// entitySpec reads an entity back into its spec
+func (f *sourceFile) entitySpec(name string) ai.EntitySpec {
+	entity := ai.EntitySpec{
+		Name:            name,
+		IsAggregateRoot: strings.Contains(f.typeDoc(name), "aggregate root"),
+		Fields:          f.structFields(name),
+	}
+
+	for _, fn := range f.methods(name) {
+		if fn.Name.Name == "Validate" {
+			entity.Fields = withValidation(entity.Fields, f.bodyComments(fn))
+			continue
+		}
+		if fn.Body == nil {
+			continue
+		}
+		entity.Methods = append(entity.Methods, ai.MethodSpec{
+			Name:        fn.Name.Name,
+			Signature:   strings.TrimSpace(f.text(fn.Pos(), fn.Body.Lbrace)),
+			Description: docDescription(fn.Doc, fn.Name.Name),
+		})
+	}
+
+	return entity
+}
+
+// valueObjectSpec reads a value object back into its spec
+func (f *sourceFile) valueObjectSpec(name string) ai.ValueObjectSpec {
+	vo := ai.ValueObjectSpec{Name: name, Fields: f.structFields(name)}
+
+	for _, fn := range f.methods(name) {
+		if fn.Name.Name != "Validate" {
+			continue
+		}
+		for _, c := range f.bodyComments(fn) {
+			if text := commentText(c); !strings.HasPrefix(text, "TODO") {
+				vo.Validation = text
+				break
+			}
+		}
+	}
+
+	return vo
+}
+
+// structFields reads the fields of a struct type with their line comments
+func (f *sourceFile) structFields(name string) []ai.FieldSpec {
+	ts := f.typeSpec(name)
+	st, ok := ts.Type.(*ast.StructType)
+	if !ok {
+		return nil
+	}
+
+	var fields []ai.FieldSpec
+	for _, field := range st.Fields.List {
+		for _, ident := range field.Names {
+			fields = append(fields, ai.FieldSpec{
+				Name:        ident.Name,
+				Type:        types.ExprString(field.Type),
+				Description: strings.TrimSpace(field.Comment.Text()),
+			})
+		}
+	}
+	return fields
+}
+
+// interfaceMethods reads the methods of an interface type
+func (f *sourceFile) interfaceMethods(name string) []ai.InterfaceMethod {
+	it, ok := f.typeSpec(name).Type.(*ast.InterfaceType)
+	if !ok {
+		return nil
+	}
+
+	var methods []ai.InterfaceMethod
+	for _, m := range it.Methods.List {
+		if len(m.Names) == 0 {
+			continue // Embedded interface
+		}
+		methods = append(methods, ai.InterfaceMethod{
+			Name:        m.Names[0].Name,
+			Signature:   f.text(m.Pos(), m.End()),
+			Description: docDescription(m.Doc, m.Names[0].Name),
+		})
+	}
+	return methods
+}
+
+// withValidation attaches "Validate Field: rule" comments to their fields
+func withValidation(fields []ai.FieldSpec, comments []*ast.Comment) []ai.FieldSpec {
+	for _, c := range comments {
+		rest, ok := strings.CutPrefix(commentText(c), "Validate ")
+		if !ok {
+			continue
+		}
+		name, rule, ok := strings.Cut(rest, ":")
+		if !ok {
+			continue
+		}
+		for i := range fields {
+			if fields[i].Name == name {
+				fields[i].Validation = strings.TrimSpace(rule)
+			}
+		}
+	}
+	return fields
+}
+
+// hasValidation reports whether a "Validate Field:" comment exists for field
+func hasValidation(comments []*ast.Comment, field string) bool {
+	for _, c := range comments {
+		if strings.HasPrefix(commentText(c), "Validate "+field+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// docDescription strips the leading name the generator writes into docs
+func docDescription(doc *ast.CommentGroup, name string) string {
+	text := strings.TrimSpace(doc.Text())
+	return strings.TrimSpace(strings.TrimPrefix(text, name))
+}
+
+func commentText(c *ast.Comment) string {
+	return strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+}
+
+// edit replaces src[start:end] with text
+type edit struct {
+	start, end int
+	text       string
+}
+
+// patchEntity edits an entity's struct fields and methods to match the
+// refined spec, reporting whether anything changed
+func (f *sourceFile) patchEntity(name string, refined ai.EntitySpec) (bool, error) {
+	st, ok := f.typeSpec(name).Type.(*ast.StructType)
+	if !ok {
+		return false, fmt.Errorf("%s is not a struct", name)
+	}
+
+	var validate *ast.FuncDecl
+	methods := make(map[string]*ast.FuncDecl)
+	for _, fn := range f.methods(name) {
+		if fn.Name.Name == "Validate" {
+			validate = fn
+		} else if fn.Body != nil {
+			methods[strings.ToLower(fn.Name.Name)] = fn
+		}
+	}
+
+	var edits []edit
+
+	// Fields. A name that shares its declaration with others, as in
+	// "A, B int", keeps the shared type; if its own type changes it moves
+	// to a line of its own.
+	wanted := make(map[string]ai.FieldSpec)
+	for _, field := range refined.Fields {
+		wanted[strings.ToLower(field.Name)] = field
+	}
+	kept := make(map[string]bool)
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			continue // Embedded field
+		}
+
+		var names []string
+		for _, ident := range field.Names {
+			spec, ok := wanted[strings.ToLower(ident.Name)]
+			switch {
+			case !ok:
+				for _, c := range f.bodyComments(validate) {
+					if strings.HasPrefix(commentText(c), "Validate "+ident.Name+":") {
+						edits = append(edits, f.deleteLines(nil, c.Pos(), c.End()))
+					}
+				}
+			case len(field.Names) == 1:
+				names = append(names, ident.Name)
+				if compact(types.ExprString(field.Type)) != compact(qualifyFieldType(spec.Type)) {
+					edits = append(edits, edit{f.offset(field.Type.Pos()), f.offset(field.Type.End()), qualifyFieldType(spec.Type)})
+				}
+			case compact(types.ExprString(field.Type)) == compact(qualifyFieldType(spec.Type)):
+				names = append(names, ident.Name)
+			default:
+				continue // Added back below with its new type
+			}
+			kept[strings.ToLower(ident.Name)] = true
+		}
+
+		switch {
+		case len(names) == 0:
+			edits = append(edits, f.deleteLines(field.Doc, field.Pos(), field.End()))
+		case len(names) < len(field.Names):
+			first, last := field.Names[0], field.Names[len(field.Names)-1]
+			edits = append(edits, edit{f.offset(first.Pos()), f.offset(last.End()), strings.Join(names, ", ")})
+		}
+	}
+	for _, field := range refined.Fields {
+		if kept[strings.ToLower(field.Name)] {
+			continue
+		}
+		at := f.lineStart(st.Fields.Closing)
+		edits = append(edits, edit{at, at, entityFieldLine(field)})
+		if field.Validation != "" && validate != nil && !hasValidation(f.bodyComments(validate), field.Name) {
+			at := f.lineStart(lastReturn(validate))
+			edits = append(edits, edit{at, at, fmt.Sprintf("\t// Validate %s: %s\n", field.Name, field.Validation)})
+		}
+	}
+
+	// Methods. New ones go after the type's last method, or after the type
+	// itself when it has none, rather than after unrelated code.
+	insertAt := f.lineEnd(f.typeDecl(name).End())
+	if all := f.methods(name); len(all) > 0 {
+		insertAt = f.lineEnd(all[len(all)-1].End())
+	}
+	wantedMethods := make(map[string]bool)
+	for _, method := range refined.Methods {
+		wantedMethods[strings.ToLower(method.Name)] = true
+
+		current, ok := methods[strings.ToLower(method.Name)]
+		switch {
+		case !ok:
+			var b strings.Builder
+			b.WriteString("\n")
+			writeMethodStub(&b, method)
+			edits = append(edits, edit{insertAt, insertAt, b.String()})
+		case compact(f.text(current.Pos(), current.Body.Lbrace)) != compact(method.Signature):
+			edits = append(edits, edit{f.offset(current.Pos()), f.offset(current.Body.Lbrace), method.Signature + " "})
+		}
+	}
+	for _, fn := range f.methods(name) {
+		if fn.Name.Name != "Validate" && fn.Body != nil && !wantedMethods[strings.ToLower(fn.Name.Name)] {
+			edits = append(edits, f.deleteLines(fn.Doc, fn.Pos(), fn.End()))
+		}
+	}
+
+	return f.write(edits, "entity")
+}
+
+// patchInterface edits an interface's methods to match the refined ones,
+// reporting whether anything changed
+func (f *sourceFile) patchInterface(name string, refined []ai.InterfaceMethod) (bool, error) {
+	it, ok := f.typeSpec(name).Type.(*ast.InterfaceType)
+	if !ok {
+		return false, fmt.Errorf("%s is not an interface", name)
+	}
+
+	existing := make(map[string]*ast.Field)
+	for _, m := range it.Methods.List {
+		if len(m.Names) > 0 {
+			existing[strings.ToLower(m.Names[0].Name)] = m
+		}
+	}
+
+	var edits []edit
+	wanted := make(map[string]bool)
+	for _, method := range refined {
+		wanted[strings.ToLower(method.Name)] = true
+
+		current, ok := existing[strings.ToLower(method.Name)]
+		switch {
+		case !ok:
+			at := f.lineStart(it.Methods.Closing)
+			edits = append(edits, edit{at, at, interfaceMethodLines(method)})
+		case compact(f.text(current.Pos(), current.End())) != compact(method.Signature):
+			edits = append(edits, edit{f.offset(current.Pos()), f.offset(current.End()), method.Signature})
+		}
+	}
+	for _, m := range it.Methods.List {
+		if len(m.Names) > 0 && !wanted[strings.ToLower(m.Names[0].Name)] {
+			edits = append(edits, f.deleteLines(m.Doc, m.Pos(), m.End()))
+		}
+	}
+
+	return f.write(edits, "port")
+}
+
+// rewriteValueObject regenerates a value object file, keeping a Validate
+// body that was implemented by hand
+func (f *sourceFile) rewriteValueObject(name string, refined ai.ValueObjectSpec) error {
+	src := valueObjectSource(refined)
+
+	for _, fn := range f.methods(name) {
+		if fn.Name.Name != "Validate" || fn.Body == nil {
+			continue
+		}
+		body := f.text(fn.Body.Lbrace, fn.Body.End())
+		if strings.Contains(body, "TODO: Add validation logic") {
+			break
+		}
+
+		generated, err := parseSource(f.path, []byte(src))
+		if err != nil {
+			return err
+		}
+		for _, gfn := range generated.methods(refined.Name) {
+			if gfn.Name.Name == "Validate" {
+				edits := []edit{{generated.offset(gfn.Body.Lbrace), generated.offset(gfn.Body.End()), body}}
+				src = string(applyEdits(generated.src, edits))
+			}
+		}
+	}
+
+	out, err := fixImports(f.path, []byte(src), "valueobject")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(f.path, out, 0644)
+}
+
+// deleteLines removes whole lines from a node's doc comment to its end
+func (f *sourceFile) deleteLines(doc *ast.CommentGroup, from, to token.Pos) edit {
+	if doc != nil {
+		from = doc.Pos()
+	}
+	return edit{f.lineStart(from), f.lineEnd(to), ""}
+}
+
+// write applies the edits, fixes imports and formats the file. Nothing is
+// written without edits.
+func (f *sourceFile) write(edits []edit, pkg string) (bool, error) {
+	if len(edits) == 0 {
+		return false, nil
+	}
+
+	out, err := fixImports(f.path, applyEdits(f.src, edits), pkg)
+	if err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(f.path, out, 0644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// applyEdits applies non-overlapping edits. Insertions at the same offset
+// keep their order and go before a deletion starting there.
+func applyEdits(src []byte, edits []edit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start < edits[j].start
+		}
+		return edits[i].start == edits[i].end && edits[j].start != edits[j].end
+	})
+
+	out := append([]byte(nil), src...)
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		out = append(out[:e.start], append([]byte(e.text), out[e.end:]...)...)
+	}
+	return out
+}
+
+// fixImports drops imports the file no longer uses and adds the known
+// packages it now needs, then formats it
+func fixImports(filename string, src []byte, pkg string) ([]byte, error) {
+	f, err := parseSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool)
+	ast.Inspect(f.file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+
+	imports := make(map[string]string) // path -> explicit name
+	have := make(map[string]bool)
+	for _, spec := range f.file.Imports {
+		importPath := strings.Trim(spec.Path.Value, `"`)
+		name := path.Base(importPath)
+		explicit := ""
+		if spec.Name != nil {
+			name, explicit = spec.Name.Name, spec.Name.Name
+		}
+		have[name] = true
+		if used[name] || name == "_" || name == "." {
+			imports[importPath] = explicit
+		}
+	}
+	for name, importPath := range knownImports {
+		if used[name] && !have[name] && name != pkg {
+			imports[importPath] = ""
+		}
+	}
+
+	// Replace every import declaration with a single grouped block
+	var edits []edit
+	at := -1
+	for _, decl := range f.file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			if at < 0 {
+				at = f.offset(gen.Pos())
+			}
+			edits = append(edits, edit{f.offset(gen.Pos()), f.lineEnd(gen.End()), ""})
+		}
+	}
+	if at < 0 {
+		at = f.lineEnd(f.file.Name.End())
+	}
+	edits = append(edits, edit{at, at, importBlock(imports)})
+
+	return format.Source(applyEdits(f.src, edits))
+}
+
+// importBlock renders imports with the standard library first
+func importBlock(imports map[string]string) string {
+	if len(imports) == 0 {
+		return ""
+	}
+
+	var std, other []string
+	for importPath, name := range imports {
+		line := "\t" + fmt.Sprintf("%q", importPath)
+		if name != "" {
+			line = "\t" + name + " " + fmt.Sprintf("%q", importPath)
+		}
+		if strings.Contains(strings.Split(importPath, "/")[0], ".") {
+			other = append(other, line)
+		} else {
+			std = append(std, line)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+
+	groups := []string{}
+	if len(std) > 0 {
+		groups = append(groups, strings.Join(std, "\n"))
+	}
+	if len(other) > 0 {
+		groups = append(groups, strings.Join(other, "\n"))
+	}
+	return "import (\n" + strings.Join(groups, "\n\n") + "\n)\n"
+}
+
+// parseSource parses src that has not been written to disk yet
+func parseSource(filename string, src []byte) (*sourceFile, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filename, err)
+	}
+	return &sourceFile{path: filename, src: src, fset: fset, file: file}, nil
+}
+
+// lastReturn returns the position of the final statement of a function
+func lastReturn(fn *ast.FuncDecl) token.Pos {
+	if n := len(fn.Body.List); n > 0 {
+		return fn.Body.List[n-1].Pos()
+	}
+	return fn.Body.Rbrace
+}
+
+func findEntity(entities []ai.EntitySpec, name string) (ai.EntitySpec, bool) {
+	for _, e := range entities {
+		if strings.EqualFold(e.Name, name) {
+			return e, true
+		}
+	}
+	return ai.EntitySpec{}, false
+}
+
+func findValueObject(vos []ai.ValueObjectSpec, name string) (ai.ValueObjectSpec, bool) {
+	for _, vo := range vos {
+		if strings.EqualFold(vo.Name, name) {
+			return vo, true
+		}
+	}
+	return ai.ValueObjectSpec{}, false
+}
+
+// sameValueObject reports whether two value objects have the same fields,
+// types and validation
+func sameValueObject(a, b ai.ValueObjectSpec) bool {
+	if len(a.Fields) != len(b.Fields) || a.Validation != b.Validation {
+		return false
+	}
+	for i := range a.Fields {
+		if !strings.EqualFold(a.Fields[i].Name, b.Fields[i].Name) || compact(a.Fields[i].Type) != compact(b.Fields[i].Type) {
+			return false
+		}
+	}
+	return true
+}
+
+// compact removes whitespace so formatting differences do not count as changes
+func compact(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
+
+// domainTypeName converts a domain name such as "order_item" to OrderItem
+func domainTypeName(domain string) string {
+	parts := strings.FieldsFunc(domain, func(r rune) bool { return r == '_' || r == '-' || r == ' ' })
+	for i, p := range parts {
+		parts[i] = toPascalCase(p)
+	}
+	return strings.Join(parts, "")
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, existing := range list {
+		if existing == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
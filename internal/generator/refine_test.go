@@ -0,0 +1,217 @@
+package generator
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lisvindanu/anaphase-cli/internal/ai"
+)
+
+func customerSpec() *ai.DomainSpec {
+	return &ai.DomainSpec{
+		DomainName: "customer",
+		Entities: []ai.EntitySpec{{
+			Name:            "Customer",
+			IsAggregateRoot: true,
+			Fields: []ai.FieldSpec{
+				{Name: "ID", Type: "uuid.UUID", Description: "Unique identifier"},
+				{Name: "Email", Type: "valueobject.Email", Description: "Contact email", Validation: "must be valid"},
+				{Name: "Nickname", Type: "string", Description: "Display name"},
+			},
+			Methods: []ai.MethodSpec{
+				{Name: "Rename", Signature: "func (c *Customer) Rename(name string) error", Description: "changes the name"},
+			},
+		}},
+		ValueObjects: []ai.ValueObjectSpec{{
+			Name:       "Email",
+			Fields:     []ai.FieldSpec{{Name: "Value", Type: "string"}},
+			Validation: "must contain @",
+		}},
+		RepositoryInterface: ai.RepositorySpec{
+			Name: "CustomerRepository",
+			Methods: []ai.InterfaceMethod{
+				{Name: "Save", Signature: "Save(ctx context.Context, customer *entity.Customer) error", Description: "persists a customer"},
+			},
+		},
+		ServiceInterface: ai.ServiceSpec{
+			Name: "CustomerService",
+			Methods: []ai.InterfaceMethod{
+				{Name: "Register", Signature: "Register(ctx context.Context, email string) (*entity.Customer, error)", Description: "registers a customer"},
+			},
+		},
+	}
+}
+
+func TestLoadDomainSpecRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	spec := customerSpec()
+	if _, err := NewDomainGenerator(spec, dir).Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	loaded, err := LoadDomainSpec(dir, "customer")
+	if err != nil {
+		t.Fatalf("LoadDomainSpec failed: %v", err)
+	}
+
+	if changes := ai.CompareDomainSpecs(spec, loaded); len(changes) != 0 {
+		t.Errorf("Expected the loaded spec to match the generated one, got %+v", changes)
+	}
+	if !loaded.Entities[0].IsAggregateRoot {
+		t.Error("Expected the aggregate root to be recognized")
+	}
+
+	if _, err := LoadDomainSpec(dir, "invoice"); err == nil {
+		t.Error("Expected an error for a domain that does not exist")
+	}
+}
+
+func TestApplyRefinement(t *testing.T) {
+	dir := t.TempDir()
+	current := customerSpec()
+	if _, err := NewDomainGenerator(current, dir).Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// A hand-written body that the refinement must keep
+	entityFile := filepath.Join(dir, "entity", "customer.go")
+	src, _ := os.ReadFile(entityFile)
+	src = []byte(strings.Replace(string(src), "// TODO: Implement business logic", "c.Nickname = name // hand-written", 1))
+	src = []byte(strings.Replace(string(src), "\tNickname string", "\tNickname, Alias string", 1))
+	src = append(src, "\nfunc normalizeEmail(s string) string { return s }\n"...)
+	if err := os.WriteFile(entityFile, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	delta := &ai.DomainDelta{
+		Entities: []ai.EntitySpec{{
+			Name: "Customer",
+			Fields: []ai.FieldSpec{
+				{Name: "Points", Type: "int", Description: "Loyalty points", Validation: "must not be negative"},
+				{Name: "Alias", Type: "string", Description: "Display name"},
+			},
+			Methods: []ai.MethodSpec{
+				{Name: "UpgradeTier", Signature: "func (c *Customer) UpgradeTier() error", Description: "moves the customer up a tier"},
+			},
+		}},
+		ServiceMethods: []ai.InterfaceMethod{
+			{Name: "AwardPoints", Signature: "AwardPoints(ctx context.Context, id uuid.UUID, points int) error", Description: "adds loyalty points"},
+		},
+		Remove: []string{"entities.Customer.fields.Nickname", "repository_interface.methods.Save"},
+	}
+	refined, err := delta.Apply(current)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	changes, err := ApplyRefinement(dir, current, refined)
+	if err != nil {
+		t.Fatalf("ApplyRefinement failed: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Errorf("Expected the entity and both ports to be updated, got %+v", changes)
+	}
+
+	for _, file := range []string{entityFile, filepath.Join(dir, "port", "customer_repository.go"), filepath.Join(dir, "port", "customer_service.go")} {
+		if _, err := parser.ParseFile(token.NewFileSet(), file, nil, 0); err != nil {
+			t.Errorf("Expected %s to parse: %v", file, err)
+		}
+	}
+
+	entity, _ := os.ReadFile(entityFile)
+	for _, want := range []string{"Points int", "Validate Points: must not be negative", "UpgradeTier() error", "c.Nickname = name // hand-written"} {
+		if !strings.Contains(string(entity), want) {
+			t.Errorf("Expected entity to contain %q:\n%s", want, entity)
+		}
+	}
+	if fields := strings.Join(strings.Fields(string(entity)), " "); strings.Contains(fields, "Nickname,") || !strings.Contains(fields, "Alias string // Display name") {
+		t.Errorf("Expected only Nickname to be removed from its declaration:\n%s", entity)
+	}
+	if upgrade, helper := strings.Index(string(entity), "UpgradeTier"), strings.Index(string(entity), "func normalizeEmail"); upgrade > helper {
+		t.Errorf("Expected the new method next to the others, before hand-written code:\n%s", entity)
+	}
+
+	service, _ := os.ReadFile(filepath.Join(dir, "port", "customer_service.go"))
+	if !strings.Contains(string(service), "AwardPoints(ctx context.Context, id uuid.UUID, points int) error") ||
+		!strings.Contains(string(service), `"github.com/google/uuid"`) {
+		t.Errorf("Expected the new service method and its import:\n%s", service)
+	}
+
+	// The files now describe the refined spec
+	loaded, err := LoadDomainSpec(dir, "customer")
+	if err != nil {
+		t.Fatalf("LoadDomainSpec failed: %v", err)
+	}
+	if diff := ai.CompareDomainSpecs(refined, loaded); len(diff) != 0 {
+		t.Errorf("Expected the files to match the refined spec, got %+v", diff)
+	}
+}
+
+func TestApplyRefinementRemovalKeepsOtherCode(t *testing.T) {
+	dir := t.TempDir()
+	current := customerSpec()
+	current.Entities = append(current.Entities, ai.EntitySpec{
+		Name:    "Tag",
+		Fields:  []ai.FieldSpec{{Name: "ID", Type: "uuid.UUID"}, {Name: "Label", Type: "string"}},
+		Methods: []ai.MethodSpec{{Name: "Rename", Signature: "func (t *Tag) Rename(label string) error"}},
+	})
+	if _, err := NewDomainGenerator(current, dir).Generate(); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// Hand-written code next to the Tag entity
+	tagFile := filepath.Join(dir, "entity", "tag.go")
+	src, _ := os.ReadFile(tagFile)
+	src = append(src, "\n// MaxTags is hand-written\nconst MaxTags = 10\n\nfunc labelLength(label string) int { return len(label) }\n"...)
+	if err := os.WriteFile(tagFile, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	delta := &ai.DomainDelta{
+		Entities: []ai.EntitySpec{{Name: "Customer", Fields: []ai.FieldSpec{{Name: "Email", Type: "string"}}}},
+		Remove:   []string{"entities.Tag", "value_objects.Email"},
+	}
+	refined, err := delta.Apply(current)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	changes, err := ApplyRefinement(dir, current, refined)
+	if err != nil {
+		t.Fatalf("ApplyRefinement failed: %v", err)
+	}
+
+	actions := make(map[string]string)
+	for _, change := range changes {
+		actions[filepath.Base(change.Path)] = change.Action
+	}
+	if actions["tag.go"] != "updated" || actions["email.go"] != "removed" {
+		t.Errorf("Expected tag.go to be updated and email.go removed, got %+v", changes)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "valueobject", "email.go")); !os.IsNotExist(err) {
+		t.Errorf("Expected email.go, which held only the value object, to be deleted: %v", err)
+	}
+
+	tag, err := os.ReadFile(tagFile)
+	if err != nil {
+		t.Fatalf("Expected tag.go to be kept: %v", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), tagFile, tag, 0); err != nil {
+		t.Errorf("Expected tag.go to parse: %v", err)
+	}
+	for _, want := range []string{"const MaxTags = 10", "func labelLength(label string) int"} {
+		if !strings.Contains(string(tag), want) {
+			t.Errorf("Expected the hand-written %q to survive:\n%s", want, tag)
+		}
+	}
+	for _, gone := range []string{"type Tag struct", "func NewTag", "ErrTagNotFound", "(t *Tag) Rename", "(e *Tag) Validate", "uuid"} {
+		if strings.Contains(string(tag), gone) {
+			t.Errorf("Expected %q to be removed:\n%s", gone, tag)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// streamPreviewLines is how many trailing lines of streamed text are shown
+const streamPreviewLines = 8
+
+// StreamModel is a bubbletea model that renders streamed text live,
+// together with a spinner, elapsed time and an approximate token count
+type StreamModel struct {
+	spinner   *Spinner
+	next      func() (string, bool, error)
+	start     time.Time
+	text      strings.Builder
+	done      bool
+	cancelled bool
+	err       error
+}
+
+// streamTextMsg carries a piece of streamed text
+type streamTextMsg struct {
+	text string
+}
+
+// streamDoneMsg indicates the stream has finished
+type streamDoneMsg struct {
+	err error
+}
+
+// NewStreamModel creates a new stream model. next blocks until the next
+// piece of text is available and returns more=false once the stream ends.
+func NewStreamModel(message string, next func() (text string, more bool, err error)) *StreamModel {
+	return &StreamModel{
+		spinner: NewSpinner(message),
+		next:    next,
+		start:   time.Now(),
+	}
+}
+
+// Init initializes the model
+func (m *StreamModel) Init() tea.Cmd {
+	return tea.Batch(
+		m.spinner.Init(),
+		m.waitForText(),
+	)
+}
+
+// waitForText reads the next piece of text from the stream
+func (m *StreamModel) waitForText() tea.Cmd {
+	return func() tea.Msg {
+		text, more, err := m.next()
+		if err != nil || !more {
+			return streamDoneMsg{err: err}
+		}
+		return streamTextMsg{text: text}
+	}
+}
+
+// Update updates the model
+func (m *StreamModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			m.cancelled = true
+			m.spinner.Stop()
+			return m, tea.Quit
+		}
+
+	case streamTextMsg:
+		m.text.WriteString(msg.text)
+		return m, m.waitForText()
+
+	case streamDoneMsg:
+		m.done = true
+		m.err = msg.err
+		m.spinner.Stop()
+		return m, tea.Quit
+
+	case tickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+// View renders the model
+func (m *StreamModel) View() string {
+	elapsed := time.Since(m.start).Round(100 * time.Millisecond)
+	stats := fmt.Sprintf("%s · ~%d tokens", elapsed, m.text.Len()/4)
+
+	if m.done || m.cancelled {
+		if m.err != nil {
+			return RenderError(fmt.Sprintf("Failed after %s: %v", elapsed, m.err)) + "\n"
+		}
+		if m.cancelled {
+			return RenderWarning("Cancelled") + "\n"
+		}
+		return RenderSuccess("Received response "+RenderSubtle("("+stats+")")) + "\n"
+	}
+
+	var b strings.Builder
+	b.WriteString(m.spinner.View())
+	b.WriteString(" ")
+	b.WriteString(RenderSubtle(stats))
+	b.WriteString("\n")
+
+	if preview := tailLines(m.text.String(), streamPreviewLines); preview != "" {
+		b.WriteString("\n")
+		for _, line := range strings.Split(preview, "\n") {
+			b.WriteString("  " + RenderSubtle(line) + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+// Err returns the stream error, if any
+func (m *StreamModel) Err() error {
+	return m.err
+}
+
+// Cancelled reports whether the user aborted the stream
+func (m *StreamModel) Cancelled() bool {
+	return m.cancelled
+}
+
+// tailLines returns the last n lines of s
+func tailLines(s string, n int) string {
+	s = strings.TrimRight(s, "\n")
+	lines := strings.Split(s, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}